@@ -7,18 +7,35 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"space-booking/internal/config"
 	"space-booking/internal/server"
 	"syscall"
 	"time"
 )
 
 func main() {
-	// Create a new server instance
-	srv := server.NewServer()
+	// Load and validate the configuration before anything else
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Create a new server instance along with the closer for its resources
+	srv, closeServer, err := server.NewServer(server.WithConfig(cfg))
+	if err != nil {
+		log.Fatalf("Error creating server: %v", err)
+	}
+	// log.Fatalf skips deferred calls, so the fatal paths below close explicitly
+	defer func() {
+		if err := closeServer(); err != nil {
+			log.Printf("Error releasing server resources: %v", err)
+		}
+	}()
 
 	// Create a listener on the desired address
 	listener, err := net.Listen("tcp", srv.Addr)
 	if err != nil {
+		closeServer()
 		log.Fatalf("Error creating listener: %v", err)
 	}
 
@@ -44,6 +61,7 @@ func main() {
 	select {
 	case err := <-errChan:
 		// Server encountered an unexpected error
+		closeServer()
 		log.Fatalf("Server error: %v", err)
 	case sig := <-stop:
 		// Received an interrupt signal, shut down gracefully
@@ -55,6 +73,7 @@ func main() {
 
 		// Attempt a graceful shutdown
 		if err := srv.Shutdown(ctx); err != nil {
+			closeServer()
 			log.Fatalf("Could not gracefully shut down the server: %v", err)
 		}
 
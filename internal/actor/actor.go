@@ -0,0 +1,36 @@
+package actor
+
+import "context"
+
+// Anonymous is the ID of callers that did not identify themselves.
+const Anonymous = "anonymous"
+
+// Actor is who a change is made by. It travels with the request context
+// down to the database, where it is written to the audit log.
+type Actor struct {
+	// ID names the caller, e.g. "admin", "client:agency-42" or
+	// "system:reconciler".
+	ID string `json:"id"`
+	// RequestID identifies the HTTP request the change was made in, if any.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// System returns the actor for changes made by a background job.
+func System(job string) Actor {
+	return Actor{ID: "system:" + job}
+}
+
+type contextKey struct{}
+
+// With returns a copy of ctx carrying a.
+func With(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, contextKey{}, a)
+}
+
+// From returns the actor carried by ctx, or an anonymous one.
+func From(ctx context.Context) Actor {
+	if a, ok := ctx.Value(contextKey{}).(Actor); ok {
+		return a
+	}
+	return Actor{ID: Anonymous}
+}
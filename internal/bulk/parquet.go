@@ -0,0 +1,100 @@
+package bulk
+
+import (
+	"io"
+	"time"
+
+	"space-booking/internal/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroup is how many bookings go into a row group of a Parquet
+// file, which is held in memory until it is full.
+const parquetRowGroup = 10000
+
+// parquetRow is a booking as a row of a Parquet file, with the columns of
+// ExportColumns. Dates are days and times milliseconds since 1970-01-01
+// UTC, prices are in cents; the zero value of an optional column is written
+// as null.
+type parquetRow struct {
+	ID               int64  `parquet:"id"`
+	Code             string `parquet:"code"`
+	Status           string `parquet:"status"`
+	FirstName        string `parquet:"first_name"`
+	LastName         string `parquet:"last_name"`
+	Email            string `parquet:"email"`
+	Gender           string `parquet:"gender"`
+	Birthday         int32  `parquet:"birthday,date"`
+	LaunchpadID      string `parquet:"launchpad_id"`
+	DestinationID    int64  `parquet:"destination_id"`
+	LaunchDate       int32  `parquet:"launch_date,date"`
+	CreatedAt        int64  `parquet:"created_at,timestamp(millisecond)"`
+	ConfirmedAt      int64  `parquet:"confirmed_at,optional,timestamp(millisecond)"`
+	CheckedInAt      int64  `parquet:"checked_in_at,optional,timestamp(millisecond)"`
+	BoardedAt        int64  `parquet:"boarded_at,optional,timestamp(millisecond)"`
+	CancelledAt      int64  `parquet:"cancelled_at,optional,timestamp(millisecond)"`
+	DisruptedAt      int64  `parquet:"disrupted_at,optional,timestamp(millisecond)"`
+	RebookedAt       int64  `parquet:"rebooked_at,optional,timestamp(millisecond)"`
+	FlownAt          int64  `parquet:"flown_at,optional,timestamp(millisecond)"`
+	ExpiredAt        int64  `parquet:"expired_at,optional,timestamp(millisecond)"`
+	DisruptionReason string `parquet:"disruption_reason,optional"`
+	DisruptedBy      string `parquet:"disrupted_by,optional"`
+	RebookedFrom     int64  `parquet:"rebooked_from,optional"`
+	PriceCents       int64  `parquet:"price_cents,optional"`
+	Currency         string `parquet:"currency,optional"`
+	PromoCode        string `parquet:"promo_code,optional"`
+	DiscountCents    int64  `parquet:"discount_cents,optional"`
+}
+
+type parquetWriter struct {
+	w *parquet.GenericWriter[parquetRow]
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{w: parquet.NewGenericWriter[parquetRow](w,
+		parquet.MaxRowsPerRowGroup(parquetRowGroup), parquet.Compression(&parquet.Snappy))}
+}
+
+func (pw *parquetWriter) Write(b *models.Booking) error {
+	row := parquetRow{
+		ID: int64(b.ID), Code: b.Code, Status: string(b.Status), FirstName: b.FirstName, LastName: b.LastName,
+		Email: b.Email, Gender: b.Gender, Birthday: days(b.Birthday), LaunchpadID: b.LaunchpadID,
+		DestinationID: b.DestinationID, LaunchDate: days(b.LaunchDate), CreatedAt: b.CreatedAt.UnixMilli(),
+		ConfirmedAt: millis(b.ConfirmedAt), CheckedInAt: millis(b.CheckedInAt), BoardedAt: millis(b.BoardedAt),
+		CancelledAt: millis(b.CancelledAt), DisruptedAt: millis(b.DisruptedAt), RebookedAt: millis(b.RebookedAt),
+		FlownAt: millis(b.FlownAt), ExpiredAt: millis(b.ExpiredAt), DisruptionReason: b.DisruptionReason,
+		DisruptedBy: b.DisruptedBy, PriceCents: b.Price, Currency: b.Currency, PromoCode: b.PromoCode,
+		DiscountCents: b.Discount,
+	}
+	if b.RebookedFrom != nil {
+		row.RebookedFrom = int64(*b.RebookedFrom)
+	}
+	_, err := pw.w.Write([]parquetRow{row})
+	return err
+}
+
+// Flush writes nothing: a row group can only be written whole, and is
+// once parquetRowGroup bookings are buffered.
+func (pw *parquetWriter) Flush() error {
+	return nil
+}
+
+// Close writes the last row group and the footer.
+func (pw *parquetWriter) Close() error {
+	return pw.w.Close()
+}
+
+// days returns the calendar day of t as days since 1970-01-01.
+func days(t time.Time) int32 {
+	y, m, d := t.Date()
+	return int32(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// millis returns t as milliseconds since 1970-01-01 UTC, and 0 for nil.
+func millis(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
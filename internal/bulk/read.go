@@ -0,0 +1,257 @@
+// Package bulk reads and writes files of bookings exchanged with agencies.
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"space-booking/internal/models"
+)
+
+// Format is a file format bookings are exchanged in.
+type Format string
+
+const (
+	CSV    Format = "csv"
+	NDJSON Format = "ndjson"
+	// Parquet is only written, for exports.
+	Parquet Format = "parquet"
+)
+
+// FormatOf returns the format of a media type such as a Content-Type
+// header.
+func FormatOf(mediaType string) (Format, bool) {
+	t, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return "", false
+	}
+	switch t {
+	case "text/csv":
+		return CSV, true
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return NDJSON, true
+	}
+	return "", false
+}
+
+// ImportColumns are the columns of an imported CSV file, in any order.
+var ImportColumns = []string{
+	"first_name", "last_name", "email", "gender", "birthday", "launchpad_id", "destination_id", "launch_date",
+}
+
+// Row is a booking read from a file.
+type Row struct {
+	// Line is the line of the file the row starts on.
+	Line    int
+	Booking models.Booking
+	// Err says why the row could not be read. Its message is safe to
+	// return to the client.
+	Err error
+}
+
+// FileError reports a file that cannot be read at all. Its message is safe
+// to return to the client.
+type FileError struct {
+	Line int
+	Msg  string
+}
+
+func (e *FileError) Error() string {
+	if e.Line == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Read reads the bookings of a file, at most max rows. Rows that are not
+// well-formed are returned with their Err set; a *FileError is returned
+// when the file as a whole cannot be read.
+func Read(r io.Reader, format Format, max int) ([]Row, error) {
+	switch format {
+	case CSV:
+		return readCSV(r, max)
+	case NDJSON:
+		return readNDJSON(r, max)
+	}
+	return nil, fmt.Errorf("bulk: unknown format %q", format)
+}
+
+func readCSV(r io.Reader, max int) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &FileError{Msg: "the file is empty"}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+		if !slices.Contains(ImportColumns, name) {
+			return nil, &FileError{Line: 1, Msg: fmt.Sprintf("unknown column %q", name)}
+		}
+		if _, ok := index[name]; ok {
+			return nil, &FileError{Line: 1, Msg: fmt.Sprintf("column %q appears twice", name)}
+		}
+		index[name] = i
+	}
+	for _, name := range ImportColumns {
+		if _, ok := index[name]; !ok {
+			return nil, &FileError{Line: 1, Msg: fmt.Sprintf("column %q is missing", name)}
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, csvError(err)
+		}
+		if len(rows) == max {
+			return nil, tooManyRows(max)
+		}
+		line, _ := cr.FieldPos(0)
+		row := Row{Line: line}
+		if err != nil {
+			row.Err = fmt.Errorf("expected %d fields, got %d", len(header), len(record))
+		} else {
+			field := func(name string) string { return strings.TrimSpace(record[index[name]]) }
+			row.Booking, row.Err = parseRecord(importRecord{
+				FirstName:     field("first_name"),
+				LastName:      field("last_name"),
+				Email:         field("email"),
+				Gender:        field("gender"),
+				Birthday:      field("birthday"),
+				LaunchpadID:   field("launchpad_id"),
+				DestinationID: field("destination_id"),
+				LaunchDate:    field("launch_date"),
+			})
+		}
+		rows = append(rows, row)
+	}
+}
+
+func csvError(err error) error {
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return &FileError{Line: perr.Line, Msg: perr.Err.Error()}
+	}
+	return err
+}
+
+func tooManyRows(max int) error {
+	return &FileError{Msg: fmt.Sprintf("the file has more than %d rows", max)}
+}
+
+// maxLine bounds a line of an NDJSON file.
+const maxLine = 64 << 10
+
+func readNDJSON(r io.Reader, max int) ([]Row, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 4096), maxLine)
+
+	var rows []Row
+	line := 0
+	for sc.Scan() {
+		line++
+		data := bytes.TrimSpace(sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(rows) == max {
+			return nil, tooManyRows(max)
+		}
+		row := Row{Line: line}
+		var rec ndjsonRecord
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec); err != nil {
+			row.Err = fmt.Errorf("invalid JSON: %v", err)
+		} else {
+			row.Booking, row.Err = parseRecord(importRecord{
+				FirstName:     rec.FirstName,
+				LastName:      rec.LastName,
+				Email:         rec.Email,
+				Gender:        rec.Gender,
+				Birthday:      rec.Birthday,
+				LaunchpadID:   rec.LaunchpadID,
+				DestinationID: string(rec.DestinationID),
+				LaunchDate:    rec.LaunchDate,
+			})
+		}
+		rows = append(rows, row)
+	}
+	if errors.Is(sc.Err(), bufio.ErrTooLong) {
+		return nil, &FileError{Line: line + 1, Msg: fmt.Sprintf("a line is longer than %d bytes", maxLine)}
+	}
+	return rows, sc.Err()
+}
+
+// ndjsonRecord is a line of an NDJSON file, spelled like a booking.
+type ndjsonRecord struct {
+	FirstName     string      `json:"first_name"`
+	LastName      string      `json:"last_name"`
+	Email         string      `json:"email"`
+	Gender        string      `json:"gender"`
+	Birthday      string      `json:"birthday"`
+	LaunchpadID   string      `json:"launchpad_id"`
+	DestinationID json.Number `json:"destination_id"`
+	LaunchDate    string      `json:"launch_date"`
+}
+
+// importRecord is a row as read, before its fields are parsed.
+type importRecord struct {
+	FirstName, LastName, Email, Gender string
+	Birthday, LaunchpadID              string
+	DestinationID, LaunchDate          string
+}
+
+func parseRecord(rec importRecord) (models.Booking, error) {
+	b := models.Booking{
+		FirstName:   strings.TrimSpace(rec.FirstName),
+		LastName:    strings.TrimSpace(rec.LastName),
+		Email:       strings.TrimSpace(rec.Email),
+		Gender:      strings.TrimSpace(rec.Gender),
+		LaunchpadID: strings.TrimSpace(rec.LaunchpadID),
+	}
+	var err error
+	if b.Birthday, err = parseDate("birthday", rec.Birthday); err != nil {
+		return b, err
+	}
+	if b.LaunchDate, err = parseDate("launch_date", rec.LaunchDate); err != nil {
+		return b, err
+	}
+	if b.DestinationID, err = strconv.ParseInt(strings.TrimSpace(rec.DestinationID), 10, 64); err != nil {
+		return b, fmt.Errorf("destination_id %q is not a number", rec.DestinationID)
+	}
+	return b, nil
+}
+
+// parseDate accepts a calendar date or an RFC 3339 time, as bookings are
+// written in JSON.
+func parseDate(name, v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s %q is not a date, expected YYYY-MM-DD", name, v)
+}
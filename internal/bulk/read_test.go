@@ -0,0 +1,85 @@
+package bulk
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCSV(t *testing.T) {
+	file := "\uFEFFLaunch_Date, launchpad_id,destination_id,first_name,last_name,email,gender,birthday\n" +
+		"2049-12-24,pad_a,1,Ada,\"Lovelace, Countess\",ada@example.com,female,1990-01-01\n" +
+		"2049-12-24,pad_a,1,Alan\n" +
+		"24/12/2049,pad_a,1,Grace,Hopper,grace@example.com,female,1990-01-01\n"
+
+	rows, err := Read(strings.NewReader(file), CSV, 10)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "Lovelace, Countess", rows[0].Booking.LastName)
+	assert.Equal(t, time.Date(2049, time.December, 24, 0, 0, 0, 0, time.UTC), rows[0].Booking.LaunchDate)
+	assert.Equal(t, int64(1), rows[0].Booking.DestinationID)
+
+	assert.EqualError(t, rows[1].Err, "expected 8 fields, got 4")
+	assert.EqualError(t, rows[2].Err, `launch_date "24/12/2049" is not a date, expected YYYY-MM-DD`)
+	assert.Equal(t, 4, rows[2].Line)
+}
+
+func TestReadNDJSON(t *testing.T) {
+	file := `{"first_name": "Ada", "last_name": "Lovelace", "email": "ada@example.com", "gender": "female", "birthday": "1990-01-01T00:00:00Z", "launchpad_id": "pad_a", "destination_id": 1, "launch_date": "2049-12-24"}
+
+{"first_name": "Alan", "status": "flown"}
+not json
+`
+	rows, err := Read(strings.NewReader(file), NDJSON, 10)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, "pad_a", rows[0].Booking.LaunchpadID)
+	assert.Equal(t, time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), rows[0].Booking.Birthday)
+	assert.Equal(t, 3, rows[1].Line)
+	assert.ErrorContains(t, rows[1].Err, `unknown field "status"`)
+	assert.ErrorContains(t, rows[2].Err, "invalid JSON")
+}
+
+func TestReadFileErrors(t *testing.T) {
+	header := strings.Join(ImportColumns, ",") + "\n"
+	row := "Ada,Lovelace,ada@example.com,female,1990-01-01,pad_a,1,2049-12-24\n"
+	tests := []struct {
+		name   string
+		format Format
+		file   string
+		want   string
+	}{
+		{"empty", CSV, "", "the file is empty"},
+		{"unknown column", CSV, header[:len(header)-1] + ",seat\n", `line 1: unknown column "seat"`},
+		{"duplicate column", CSV, header[:len(header)-1] + ",email\n", `line 1: column "email" appears twice`},
+		{"broken quotes", CSV, header + `"Ada,Lovelace` + "\n", `line 2: extraneous or missing " in quoted-field`},
+		{"too many rows", CSV, header + row + row + row, "the file has more than 2 rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.file), tt.format, 2)
+			var ferr *FileError
+			require.ErrorAs(t, err, &ferr)
+			assert.Equal(t, tt.want, ferr.Error())
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	f, ok := FormatOf("text/csv; charset=utf-8")
+	assert.True(t, ok)
+	assert.Equal(t, CSV, f)
+	f, ok = FormatOf("application/x-ndjson")
+	assert.True(t, ok)
+	assert.Equal(t, NDJSON, f)
+	_, ok = FormatOf("application/json")
+	assert.False(t, ok)
+}
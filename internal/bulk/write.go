@@ -0,0 +1,123 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"space-booking/internal/models"
+)
+
+// ExportColumns are the columns of an exported CSV file, in order.
+var ExportColumns = []string{
+	"id", "code", "status", "first_name", "last_name", "email", "gender", "birthday", "launchpad_id",
+	"destination_id", "launch_date", "created_at", "confirmed_at", "checked_in_at", "boarded_at",
+	"cancelled_at", "disrupted_at", "rebooked_at", "flown_at", "expired_at", "disruption_reason",
+	"disrupted_by", "rebooked_from", "price_cents", "currency", "promo_code", "discount_cents",
+}
+
+// Writer writes bookings one at a time, so a file of any length is written
+// in constant memory.
+type Writer interface {
+	Write(booking *models.Booking) error
+	// Flush writes out whatever is buffered.
+	Flush() error
+	// Close ends the file; the Writer is not used after.
+	Close() error
+}
+
+// NewWriter returns a Writer of the format. A CSV file starts with a header
+// of ExportColumns; an NDJSON file has one booking per line, spelled as in
+// the JSON API; a Parquet file has the columns of ExportColumns, typed.
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case CSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case NDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case Parquet:
+		return newParquetWriter(w), nil
+	}
+	return nil, fmt.Errorf("bulk: cannot write %q", format)
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (cw *csvWriter) Write(b *models.Booking) error {
+	if !cw.headerWritten {
+		if err := cw.w.Write(ExportColumns); err != nil {
+			return err
+		}
+		cw.headerWritten = true
+	}
+	rebookedFrom := ""
+	if b.RebookedFrom != nil {
+		rebookedFrom = strconv.Itoa(*b.RebookedFrom)
+	}
+	// Bookings made before fares were introduced have no price.
+	price := ""
+	if b.Price != 0 {
+		price = strconv.FormatInt(b.Price, 10)
+	}
+	discount := ""
+	if b.Discount != 0 {
+		discount = strconv.FormatInt(b.Discount, 10)
+	}
+	return cw.w.Write([]string{
+		strconv.Itoa(b.ID), b.Code, string(b.Status), b.FirstName, b.LastName, b.Email, b.Gender,
+		formatDate(b.Birthday), b.LaunchpadID, strconv.FormatInt(b.DestinationID, 10), formatDate(b.LaunchDate),
+		b.CreatedAt.UTC().Format(time.RFC3339), formatTime(b.ConfirmedAt), formatTime(b.CheckedInAt),
+		formatTime(b.BoardedAt), formatTime(b.CancelledAt), formatTime(b.DisruptedAt), formatTime(b.RebookedAt),
+		formatTime(b.FlownAt), formatTime(b.ExpiredAt), b.DisruptionReason, b.DisruptedBy, rebookedFrom, price, b.Currency,
+		b.PromoCode, discount,
+	})
+}
+
+// Flush writes the header of a file without bookings too.
+func (cw *csvWriter) Flush() error {
+	if !cw.headerWritten {
+		if err := cw.w.Write(ExportColumns); err != nil {
+			return err
+		}
+		cw.headerWritten = true
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	return cw.Flush()
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (nw *ndjsonWriter) Write(b *models.Booking) error {
+	return nw.enc.Encode(b)
+}
+
+func (nw *ndjsonWriter) Flush() error {
+	return nil
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nil
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
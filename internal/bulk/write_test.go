@@ -0,0 +1,93 @@
+package bulk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"space-booking/internal/models"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCSV(t *testing.T) {
+	confirmed := time.Date(2049, time.December, 1, 9, 30, 0, 0, time.UTC)
+	from := 3
+	b := &models.Booking{
+		ID: 7, Code: "K7QX2MWP9D", Status: models.StatusConfirmed, FirstName: "Ada", LastName: "Lovelace, Countess",
+		Email: "ada@example.com", Gender: "female", Birthday: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+		LaunchpadID: "pad_a", DestinationID: 1, LaunchDate: time.Date(2049, time.December, 24, 0, 0, 0, 0, time.UTC),
+		CreatedAt: confirmed, ConfirmedAt: &confirmed, RebookedFrom: &from, Price: 100000000, Currency: "USD",
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, CSV)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(b))
+	require.NoError(t, w.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, strings.Join(ExportColumns, ","), lines[0])
+	assert.Equal(t, `7,K7QX2MWP9D,confirmed,Ada,"Lovelace, Countess",ada@example.com,female,1990-01-01,pad_a,1,2049-12-24,`+
+		`2049-12-01T09:30:00Z,2049-12-01T09:30:00Z,,,,,,,,,,3,100000000,USD,,`, lines[1])
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, CSV)
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	assert.Equal(t, strings.Join(ExportColumns, ",")+"\n", buf.String())
+
+	buf.Reset()
+	w, err = NewWriter(&buf, NDJSON)
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	assert.Empty(t, buf.String())
+}
+
+func TestWriteParquet(t *testing.T) {
+	confirmed := time.Date(2049, time.December, 1, 9, 30, 0, 0, time.UTC)
+	from := 3
+	bookings := []*models.Booking{
+		{
+			ID: 7, Code: "K7QX2MWP9D", Status: models.StatusConfirmed, FirstName: "Ada", LastName: "Lovelace",
+			Birthday: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), LaunchpadID: "pad_a", DestinationID: 1,
+			LaunchDate: time.Date(2049, time.December, 24, 0, 0, 0, 0, time.UTC), CreatedAt: confirmed,
+			ConfirmedAt: &confirmed, RebookedFrom: &from, Price: 100000000, Currency: "USD",
+		},
+		{ID: 8, Code: "M4RB8ZT2LC", Status: models.StatusPending, CreatedAt: confirmed},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Parquet)
+	require.NoError(t, err)
+	for _, b := range bookings {
+		require.NoError(t, w.Write(b))
+	}
+	require.NoError(t, w.Close())
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	confirmedAt, ok := f.Schema().Lookup("confirmed_at")
+	require.True(t, ok)
+	assert.Equal(t, "TIMESTAMP(isAdjustedToUTC=true,unit=MILLIS)", confirmedAt.Node.Type().LogicalType().String())
+	assert.Equal(t, int64(1), f.Metadata().RowGroups[0].Columns[confirmedAt.ColumnIndex].MetaData.Statistics.NullCount)
+
+	rows, err := parquet.Read[parquetRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, int64(7), rows[0].ID)
+	assert.Equal(t, "Lovelace", rows[0].LastName)
+	assert.Equal(t, int32(29212), rows[0].LaunchDate, "Expected 2049-12-24 in days since 1970-01-01")
+	assert.Equal(t, confirmed.UnixMilli(), rows[0].ConfirmedAt)
+	assert.Equal(t, int64(3), rows[0].RebookedFrom)
+	assert.Equal(t, int64(100000000), rows[0].PriceCents)
+	assert.Zero(t, rows[1].ConfirmedAt)
+	assert.Zero(t, rows[1].PriceCents)
+	assert.Equal(t, "pending", rows[1].Status)
+}
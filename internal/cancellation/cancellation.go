@@ -0,0 +1,78 @@
+// Package cancellation decides what cancelling a booking refunds.
+package cancellation
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+)
+
+// OperatorReason is the reason of the refunds of the bookings cancelled by
+// an operator.
+const OperatorReason = "Cancelled by the operator."
+
+// Tier refunds Share of the fare of the bookings cancelled From days or
+// more before their launch day.
+type Tier struct {
+	From  int
+	Share float64
+}
+
+// Policy is the refund policy of cancellations. Passengers are refunded by
+// how far ahead of the launch they cancel, along the tiers. Bookings
+// cancelled by an operator, and bookings whose flight was disrupted, are
+// refunded in full.
+type Policy struct {
+	Tiers []Tier
+}
+
+// Terms returns what cancelling the booking at now refunds. byOperator
+// tells an operator's cancellation from the passenger's own.
+func (p Policy) Terms(booking *models.Booking, now time.Time, byOperator bool) models.RefundTerms {
+	switch {
+	case booking.Status == models.StatusDisrupted:
+		return models.RefundTerms{
+			Share:       1,
+			Reason:      "Flight was disrupted: " + booking.DisruptionReason,
+			DisruptedBy: booking.DisruptedBy,
+		}
+	case byOperator:
+		return models.RefundTerms{Share: 1, Reason: OperatorReason}
+	}
+	days := DaysUntilLaunch(booking.LaunchDate, now)
+	return models.RefundTerms{Share: p.share(days), Reason: reason(days)}
+}
+
+// reason says when a passenger cancelled.
+func reason(days int) string {
+	switch {
+	case days < 0:
+		return "Cancelled after the launch day."
+	case days == 0:
+		return "Cancelled on the launch day."
+	case days == 1:
+		return "Cancelled 1 day before launch."
+	}
+	return fmt.Sprintf("Cancelled %d days before launch.", days)
+}
+
+// share returns the share of the tier with the highest threshold not
+// above days, or nothing when there is none.
+func (p Policy) share(days int) float64 {
+	share, from := 0.0, math.MinInt
+	for _, t := range p.Tiers {
+		if t.From <= days && t.From > from {
+			share, from = t.Share, t.From
+		}
+	}
+	return share
+}
+
+// DaysUntilLaunch counts the calendar days from now to the launch day, in
+// UTC; it is negative once the launch day passed.
+func DaysUntilLaunch(launchDate, now time.Time) int {
+	return int(clock.Day(launchDate).Sub(clock.Day(now)).Hours() / 24)
+}
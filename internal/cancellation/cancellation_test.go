@@ -0,0 +1,53 @@
+package cancellation
+
+import (
+	"testing"
+	"time"
+
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerms(t *testing.T) {
+	policy := Policy{Tiers: []Tier{{From: 0, Share: 0}, {From: 8, Share: 0.5}, {From: 31, Share: 1}}}
+	launch := time.Date(2050, time.January, 31, 0, 0, 0, 0, time.UTC)
+	booking := func(status models.BookingStatus) *models.Booking {
+		return &models.Booking{ID: 7, Status: status, LaunchDate: launch}
+	}
+
+	tests := []struct {
+		name       string
+		booking    *models.Booking
+		now        time.Time
+		byOperator bool
+		want       models.RefundTerms
+	}{
+		{"more than 30 days ahead", booking(models.StatusConfirmed), time.Date(2049, time.December, 31, 23, 0, 0, 0, time.UTC), false,
+			models.RefundTerms{Share: 1, Reason: "Cancelled 31 days before launch."}},
+		{"30 days ahead", booking(models.StatusConfirmed), time.Date(2050, time.January, 1, 0, 0, 0, 0, time.UTC), false,
+			models.RefundTerms{Share: 0.5, Reason: "Cancelled 30 days before launch."}},
+		{"a week ahead", booking(models.StatusConfirmed), time.Date(2050, time.January, 24, 12, 0, 0, 0, time.UTC), false,
+			models.RefundTerms{Share: 0, Reason: "Cancelled 7 days before launch."}},
+		{"after the launch", booking(models.StatusCheckedIn), time.Date(2050, time.February, 1, 0, 0, 0, 0, time.UTC), false,
+			models.RefundTerms{Share: 0, Reason: "Cancelled after the launch day."}},
+		{"the day before", booking(models.StatusConfirmed), time.Date(2050, time.January, 30, 23, 0, 0, 0, time.UTC), false,
+			models.RefundTerms{Share: 0, Reason: "Cancelled 1 day before launch."}},
+		{"by the operator", booking(models.StatusConfirmed), time.Date(2050, time.January, 30, 0, 0, 0, 0, time.UTC), true,
+			models.RefundTerms{Share: 1, Reason: OperatorReason}},
+		{"disrupted", &models.Booking{Status: models.StatusDisrupted, LaunchDate: launch, DisruptedBy: "spacex",
+			DisruptionReason: "Launchpad pad_a is taken"}, time.Date(2050, time.January, 30, 0, 0, 0, 0, time.UTC), false,
+			models.RefundTerms{Share: 1, Reason: "Flight was disrupted: Launchpad pad_a is taken", DisruptedBy: "spacex"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Terms(tt.booking, tt.now, tt.byOperator))
+		})
+	}
+}
+
+func TestTermsWithoutTiers(t *testing.T) {
+	booking := &models.Booking{Status: models.StatusConfirmed, LaunchDate: time.Date(2050, time.January, 31, 0, 0, 0, 0, time.UTC)}
+	terms := Policy{}.Terms(booking, time.Date(2049, time.December, 1, 0, 0, 0, 0, time.UTC), false)
+	assert.Equal(t, 0.0, terms.Share)
+}
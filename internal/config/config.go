@@ -0,0 +1,916 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the application configuration.
+type Config struct {
+	// Env is the deployment environment, e.g. "development" or "production".
+	Env string `yaml:"env" toml:"env"`
+	// Port is the TCP port the HTTP server listens on.
+	Port int `yaml:"port" toml:"port"`
+	// AdminToken is the bearer token for the /admin endpoints, which are
+	// disabled while it is empty.
+	AdminToken string `yaml:"admin_token" toml:"admin_token"`
+
+	Database Database `yaml:"database" toml:"database"`
+	SpaceX   SpaceX   `yaml:"spacex" toml:"spacex"`
+	Booking  Booking  `yaml:"booking" toml:"booking"`
+
+	Conflicts Conflicts `yaml:"conflicts" toml:"conflicts"`
+	Reconcile Reconcile `yaml:"reconcile" toml:"reconcile"`
+	Webhooks  Webhooks  `yaml:"webhooks" toml:"webhooks"`
+	Events    Events    `yaml:"events" toml:"events"`
+	Mail      Mail      `yaml:"mail" toml:"mail"`
+	Tickets   Tickets   `yaml:"tickets" toml:"tickets"`
+	CheckIn   CheckIn   `yaml:"checkin" toml:"checkin"`
+	Manifests Manifests `yaml:"manifests" toml:"manifests"`
+	Pricing   Pricing   `yaml:"pricing" toml:"pricing"`
+	Payments  Payments  `yaml:"payments" toml:"payments"`
+
+	Cancellation Cancellation `yaml:"cancellation" toml:"cancellation"`
+	Waitlist     Waitlist     `yaml:"waitlist" toml:"waitlist"`
+	Holds        Holds        `yaml:"holds" toml:"holds"`
+}
+
+// Database holds the PostgreSQL connection settings.
+type Database struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     string `yaml:"port" toml:"port"`
+	Name     string `yaml:"name" toml:"name"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+	Schema   string `yaml:"schema" toml:"schema"`
+
+	// DSN overrides the individual settings above when set.
+	DSN string `yaml:"dsn" toml:"dsn"`
+}
+
+// Failure policies for bookings while the SpaceX API is unreachable.
+const (
+	// FailOpen accepts bookings without checking for SpaceX launches.
+	FailOpen = "open"
+	// FailClosed rejects bookings until SpaceX can be checked again.
+	FailClosed = "closed"
+)
+
+// SpaceX holds the settings for the SpaceX API.
+type SpaceX struct {
+	// APIURL is the base URL of the API, e.g. https://api.spacexdata.com.
+	APIURL string `yaml:"api_url" toml:"api_url"`
+	// Timeout bounds a single request.
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+	// Retries is how many times a failed request is retried.
+	Retries int `yaml:"retries" toml:"retries"`
+	// BreakerThreshold is how many failed calls in a row open the circuit
+	// breaker; zero disables it.
+	BreakerThreshold int `yaml:"breaker_threshold" toml:"breaker_threshold"`
+	// BreakerCooldown is how long the open breaker rejects calls.
+	BreakerCooldown time.Duration `yaml:"breaker_cooldown" toml:"breaker_cooldown"`
+	// FailurePolicy is FailOpen or FailClosed.
+	FailurePolicy string `yaml:"failure_policy" toml:"failure_policy"`
+	// CacheTTL is how long launch query results are reused; zero disables
+	// the cache.
+	CacheTTL time.Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+}
+
+// Conflicts holds the extra sources of launchpad conflicts next to SpaceX
+// and the blackouts kept in the database.
+type Conflicts struct {
+	// Files lists JSON or CSV files of launchpad closures.
+	Files []string `yaml:"files" toml:"files"`
+}
+
+// Reconcile holds the settings of the background check of future bookings.
+type Reconcile struct {
+	// Interval is the time between two checks; zero disables them.
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+}
+
+// Webhooks holds the settings of the webhook dispatcher.
+type Webhooks struct {
+	// Interval is the time between two dispatcher runs; zero disables
+	// webhook deliveries.
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+	// Timeout bounds a single delivery attempt.
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+	// MaxAttempts is how many attempts a delivery gets before it fails.
+	MaxAttempts int `yaml:"max_attempts" toml:"max_attempts"`
+}
+
+// Events holds the settings of the /events stream.
+type Events struct {
+	// PollInterval is how often the outbox is checked for new events;
+	// zero disables polling, leaving only Listen.
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	// Listen wakes the streams through PostgreSQL LISTEN/NOTIFY, which
+	// also carries the events written by other replicas at once.
+	Listen bool `yaml:"listen" toml:"listen"`
+	// Heartbeat is the time between two keep-alive comments on an idle
+	// stream.
+	Heartbeat time.Duration `yaml:"heartbeat" toml:"heartbeat"`
+}
+
+// Mailers sending the notifications.
+const (
+	// MailerLog writes the emails to the log.
+	MailerLog = "log"
+	// MailerFile writes the emails as .eml files into Mail.Dir.
+	MailerFile = "file"
+	// MailerSMTP sends the emails through Mail.SMTP.
+	MailerSMTP = "smtp"
+)
+
+// Mail holds the settings of the email notifications.
+type Mail struct {
+	// Mailer is MailerLog, MailerFile or MailerSMTP.
+	Mailer string `yaml:"mailer" toml:"mailer"`
+	// From is the sender address, e.g. "SpaceTrouble <bookings@example.com>".
+	From string `yaml:"from" toml:"from"`
+	// Dir is where MailerFile writes.
+	Dir  string `yaml:"dir" toml:"dir"`
+	SMTP SMTP   `yaml:"smtp" toml:"smtp"`
+	// Interval is the time between two sender runs; zero disables emails.
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+	// MaxAttempts is how many attempts an email gets before it fails.
+	MaxAttempts int `yaml:"max_attempts" toml:"max_attempts"`
+}
+
+// SMTP holds the connection settings of the mail server.
+type SMTP struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+// Tickets holds the settings of the ticket documents.
+type Tickets struct {
+	// SigningKey is the base64 encoded 32 byte seed of the Ed25519 key
+	// signing the QR codes. A random key is used while it is empty, which
+	// invalidates the printed tickets at every restart.
+	SigningKey string `yaml:"signing_key" toml:"signing_key"`
+}
+
+// CheckIn holds the check-in window, measured from the start of the
+// launch day in UTC.
+type CheckIn struct {
+	// OpensBefore is how long before the launch day check-in opens.
+	OpensBefore time.Duration `yaml:"opens_before" toml:"opens_before"`
+	// ClosesAfter is how far into the launch day check-in closes.
+	ClosesAfter time.Duration `yaml:"closes_after" toml:"closes_after"`
+}
+
+// Manifests holds the settings of the manifests handed to range control.
+type Manifests struct {
+	// Lead is how long before the launch day a flight's manifest is
+	// published.
+	Lead time.Duration `yaml:"lead" toml:"lead"`
+	// Interval is the time between two checks for due manifests; zero
+	// disables publishing.
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+}
+
+// Pricing holds the fare rules. A fare is the base price of the
+// destination times the factors of the advance, load and age tiers that
+// apply.
+type Pricing struct {
+	// Currency is the ISO 4217 code fares are quoted in.
+	Currency string `yaml:"currency" toml:"currency"`
+	// Seats is how many passengers a flight, a launchpad on a launch day,
+	// takes.
+	Seats int `yaml:"seats" toml:"seats"`
+	// LoadSurcharge is added to the load factor in proportion to the share
+	// of seats sold: the last seat costs 1+LoadSurcharge times the first.
+	LoadSurcharge float64 `yaml:"load_surcharge" toml:"load_surcharge"`
+	// Advance are the factors by days until launch; the tier with the
+	// highest From not above the days applies.
+	Advance []Tier `yaml:"advance" toml:"advance"`
+	// AgeBands are the factors by age on the launch day; the tier with the
+	// highest From not above the age applies.
+	AgeBands []Tier `yaml:"age_bands" toml:"age_bands"`
+	// QuoteTTL is how long a quote can be booked.
+	QuoteTTL time.Duration `yaml:"quote_ttl" toml:"quote_ttl"`
+	// QuoteSigningKey is the base64 encoded key of at least 32 bytes the
+	// quotes are signed with. A random key is used while it is empty, which
+	// invalidates the open quotes at every restart.
+	QuoteSigningKey string `yaml:"quote_signing_key" toml:"quote_signing_key"`
+}
+
+// Payment providers.
+const (
+	// PaymentFake is the in-memory provider for development and tests.
+	PaymentFake = "fake"
+)
+
+// Payments holds the settings of the payment provider.
+type Payments struct {
+	// Provider is the payment provider; only PaymentFake for now.
+	Provider string `yaml:"provider" toml:"provider"`
+	// WebhookSecret verifies the events the provider posts. A random
+	// secret is used while it is empty, which only the fake provider knows.
+	WebhookSecret string `yaml:"webhook_secret" toml:"webhook_secret"`
+	// PendingTTL is how long a booking waits for its payment before it
+	// expires and its seat is released.
+	PendingTTL time.Duration `yaml:"pending_ttl" toml:"pending_ttl"`
+	// Interval is the time between two runs of the expiry, capture and
+	// refund jobs; zero disables them.
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+	// RefundMaxAttempts is how many attempts a refund gets before it fails.
+	RefundMaxAttempts int `yaml:"refund_max_attempts" toml:"refund_max_attempts"`
+}
+
+// Cancellation holds the refund policy of cancellations.
+type Cancellation struct {
+	// Refunds are the shares of the fare refunded to passengers by days
+	// before the launch day; the tier with the highest From not above the
+	// days applies. Operators' cancellations and disrupted bookings are
+	// refunded in full.
+	Refunds []RefundTier `yaml:"refunds" toml:"refunds"`
+}
+
+// Holds holds the settings of the seat holds taken during checkout.
+type Holds struct {
+	// TTL is how long a seat hold reserves its seats.
+	TTL time.Duration `yaml:"ttl" toml:"ttl"`
+	// MaxSeats is the most seats a single hold may reserve.
+	MaxSeats int `yaml:"max_seats" toml:"max_seats"`
+	// Interval is the time between two sweeps of expired holds; zero
+	// disables it. Expired holds stop counting against capacity either
+	// way, the sweep only closes them.
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+}
+
+// Waitlist holds the settings of the waitlist of sold out and blocked
+// flights.
+type Waitlist struct {
+	// Hold is how long a seat offered to a waiting passenger is held for
+	// them to pay for it.
+	Hold time.Duration `yaml:"hold" toml:"hold"`
+	// Interval is the time between two runs of the promotion job; zero
+	// disables it.
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+}
+
+// RefundTier is a share of the fare refunded from a number of days before
+// the launch day on.
+type RefundTier struct {
+	From  int     `yaml:"from" toml:"from"`
+	Share float64 `yaml:"share" toml:"share"`
+}
+
+// Tier is a factor that applies from a threshold on.
+type Tier struct {
+	From   int     `yaml:"from" toml:"from"`
+	Factor float64 `yaml:"factor" toml:"factor"`
+}
+
+// Booking holds the business rules for accepting bookings.
+type Booking struct {
+	// HorizonDays is how many days ahead a launch date may be booked.
+	HorizonDays int `yaml:"horizon_days" toml:"horizon_days"`
+}
+
+// Default returns the configuration used before any file or environment
+// variable is applied.
+func Default() *Config {
+	return &Config{
+		Env:  "development",
+		Port: 8080,
+		Database: Database{
+			Port:   "5432",
+			Schema: "public",
+		},
+		SpaceX: SpaceX{
+			Timeout:          5 * time.Second,
+			Retries:          2,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+			FailurePolicy:    FailClosed,
+			CacheTTL:         time.Minute,
+		},
+		Booking: Booking{
+			HorizonDays: 365,
+		},
+		Reconcile: Reconcile{
+			Interval: 15 * time.Minute,
+		},
+		Webhooks: Webhooks{
+			Interval:    5 * time.Second,
+			Timeout:     10 * time.Second,
+			MaxAttempts: 8,
+		},
+		Events: Events{
+			PollInterval: time.Second,
+			Heartbeat:    15 * time.Second,
+		},
+		CheckIn: CheckIn{
+			OpensBefore: 48 * time.Hour,
+			ClosesAfter: 6 * time.Hour,
+		},
+		Manifests: Manifests{
+			Lead:     24 * time.Hour,
+			Interval: 5 * time.Minute,
+		},
+		Pricing: Pricing{
+			Currency:      "USD",
+			Seats:         50,
+			LoadSurcharge: 0.5,
+			Advance: []Tier{
+				{From: 0, Factor: 1.25},
+				{From: 14, Factor: 1},
+				{From: 60, Factor: 0.95},
+				{From: 180, Factor: 0.85},
+			},
+			AgeBands: []Tier{
+				{From: 0, Factor: 0.75},
+				{From: 18, Factor: 1},
+				{From: 65, Factor: 0.9},
+			},
+			QuoteTTL: 15 * time.Minute,
+		},
+		Payments: Payments{
+			Provider:          PaymentFake,
+			PendingTTL:        15 * time.Minute,
+			Interval:          time.Minute,
+			RefundMaxAttempts: 5,
+		},
+		Cancellation: Cancellation{
+			// Full refund more than 30 days ahead, half more than 7.
+			Refunds: []RefundTier{
+				{From: 0, Share: 0},
+				{From: 8, Share: 0.5},
+				{From: 31, Share: 1},
+			},
+		},
+		Waitlist: Waitlist{
+			Hold:     24 * time.Hour,
+			Interval: time.Minute,
+		},
+		Holds: Holds{
+			TTL:      10 * time.Minute,
+			MaxSeats: 10,
+			Interval: time.Minute,
+		},
+		Mail: Mail{
+			Mailer:      MailerLog,
+			From:        "SpaceTrouble <bookings@spacetrouble.example>",
+			Dir:         "mail",
+			SMTP:        SMTP{Port: 587},
+			Interval:    10 * time.Second,
+			MaxAttempts: 5,
+		},
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, an optional YAML or TOML file and the environment. The
+// environment is seeded from ".env.<APP_ENV>" and ".env" when those files
+// exist.
+//
+// The file is taken from CONFIG_FILE, or from "configs/<APP_ENV>.yaml" or
+// else "configs/<APP_ENV>.toml" when one exists. The result is validated
+// before it is returned.
+func Load() (*Config, error) {
+	env := os.Getenv("APP_ENV")
+	if err := loadDotEnv(env); err != nil {
+		return nil, err
+	}
+	// The .env files may have set APP_ENV themselves.
+	env = os.Getenv("APP_ENV")
+
+	cfg := Default()
+
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" && env != "" {
+		for _, ext := range []string{".yaml", ".toml"} {
+			candidate := filepath.Join("configs", env+ext)
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadDotEnv loads the environment specific and the shared .env files.
+// Variables that are already set are never overwritten, so the environment
+// specific file wins over the shared one.
+func loadDotEnv(env string) error {
+	files := []string{".env"}
+	if env != "" {
+		files = append([]string{".env." + env}, files...)
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			continue
+		}
+		if err := godotenv.Load(f); err != nil {
+			return fmt.Errorf("load %s: %w", f, err)
+		}
+	}
+	return nil
+}
+
+// loadFile reads the YAML or TOML file at path over the current values,
+// telling them apart by the extension. Other extensions are refused rather
+// than misread.
+func (c *Config) loadFile(path string) error {
+	var unmarshal func([]byte, any) error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	case ".toml":
+		unmarshal = toml.Unmarshal
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q", path, ext)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	if err := unmarshal(data, c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides the current values with the ones set in the environment.
+func (c *Config) applyEnv() error {
+	setString(&c.Env, "APP_ENV")
+	if err := setInt(&c.Port, "PORT"); err != nil {
+		return err
+	}
+	setString(&c.AdminToken, "ADMIN_TOKEN")
+
+	setString(&c.Database.Host, "DB_HOST")
+	setString(&c.Database.Port, "DB_PORT")
+	setString(&c.Database.Name, "DB_DATABASE")
+	setString(&c.Database.Username, "DB_USERNAME")
+	setString(&c.Database.Password, "DB_PASSWORD")
+	setString(&c.Database.Schema, "DB_SCHEMA")
+	setString(&c.Database.DSN, "DB_DSN")
+
+	setString(&c.SpaceX.APIURL, "SPACEXAPIURL")
+	setString(&c.SpaceX.FailurePolicy, "SPACEX_FAILURE_POLICY")
+	if err := setDuration(&c.SpaceX.Timeout, "SPACEX_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := setInt(&c.SpaceX.Retries, "SPACEX_RETRIES"); err != nil {
+		return err
+	}
+	if err := setInt(&c.SpaceX.BreakerThreshold, "SPACEX_BREAKER_THRESHOLD"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.SpaceX.BreakerCooldown, "SPACEX_BREAKER_COOLDOWN"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.SpaceX.CacheTTL, "SPACEX_CACHE_TTL"); err != nil {
+		return err
+	}
+
+	if err := setInt(&c.Booking.HorizonDays, "BOOKING_HORIZON_DAYS"); err != nil {
+		return err
+	}
+
+	setList(&c.Conflicts.Files, "CONFLICT_FILES")
+	if err := setDuration(&c.Reconcile.Interval, "RECONCILE_INTERVAL"); err != nil {
+		return err
+	}
+
+	if err := setDuration(&c.Webhooks.Interval, "WEBHOOK_INTERVAL"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Webhooks.Timeout, "WEBHOOK_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Webhooks.MaxAttempts, "WEBHOOK_MAX_ATTEMPTS"); err != nil {
+		return err
+	}
+
+	if err := setDuration(&c.Events.PollInterval, "EVENTS_POLL_INTERVAL"); err != nil {
+		return err
+	}
+	if err := setBool(&c.Events.Listen, "EVENTS_LISTEN"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Events.Heartbeat, "EVENTS_HEARTBEAT"); err != nil {
+		return err
+	}
+
+	setString(&c.Mail.Mailer, "MAILER")
+	setString(&c.Mail.From, "MAIL_FROM")
+	setString(&c.Mail.Dir, "MAIL_DIR")
+	setString(&c.Mail.SMTP.Host, "SMTP_HOST")
+	if err := setInt(&c.Mail.SMTP.Port, "SMTP_PORT"); err != nil {
+		return err
+	}
+	setString(&c.Mail.SMTP.Username, "SMTP_USERNAME")
+	setString(&c.Mail.SMTP.Password, "SMTP_PASSWORD")
+	if err := setDuration(&c.Mail.Interval, "MAIL_INTERVAL"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Mail.MaxAttempts, "MAIL_MAX_ATTEMPTS"); err != nil {
+		return err
+	}
+
+	setString(&c.Tickets.SigningKey, "TICKET_SIGNING_KEY")
+
+	if err := setDuration(&c.CheckIn.OpensBefore, "CHECKIN_OPENS_BEFORE"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.CheckIn.ClosesAfter, "CHECKIN_CLOSES_AFTER"); err != nil {
+		return err
+	}
+
+	if err := setDuration(&c.Manifests.Lead, "MANIFEST_LEAD"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Manifests.Interval, "MANIFEST_INTERVAL"); err != nil {
+		return err
+	}
+
+	setString(&c.Pricing.Currency, "PRICING_CURRENCY")
+	if err := setInt(&c.Pricing.Seats, "FLIGHT_SEATS"); err != nil {
+		return err
+	}
+	if err := setFloat(&c.Pricing.LoadSurcharge, "PRICING_LOAD_SURCHARGE"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Pricing.QuoteTTL, "QUOTE_TTL"); err != nil {
+		return err
+	}
+	setString(&c.Pricing.QuoteSigningKey, "QUOTE_SIGNING_KEY")
+
+	setString(&c.Payments.Provider, "PAYMENT_PROVIDER")
+	setString(&c.Payments.WebhookSecret, "PAYMENT_WEBHOOK_SECRET")
+	if err := setDuration(&c.Payments.PendingTTL, "PAYMENT_PENDING_TTL"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Payments.Interval, "PAYMENT_INTERVAL"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Payments.RefundMaxAttempts, "REFUND_MAX_ATTEMPTS"); err != nil {
+		return err
+	}
+
+	if err := setDuration(&c.Waitlist.Hold, "WAITLIST_HOLD"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Waitlist.Interval, "WAITLIST_INTERVAL"); err != nil {
+		return err
+	}
+
+	if err := setDuration(&c.Holds.TTL, "HOLD_TTL"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Holds.MaxSeats, "HOLD_MAX_SEATS"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Holds.Interval, "HOLD_INTERVAL"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func setString(dst *string, key string) {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		*dst = v
+	}
+}
+
+// setList reads a comma separated list.
+func setList(dst *[]string, key string) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*dst = list
+}
+
+func setInt(dst *int, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a number", key, v)
+	}
+	*dst = n
+	return nil
+}
+
+func setFloat(dst *float64, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a number", key, v)
+	}
+	*dst = f
+	return nil
+}
+
+func setBool(dst *bool, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a boolean", key, v)
+	}
+	*dst = b
+	return nil
+}
+
+func setDuration(dst *time.Duration, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a duration", key, v)
+	}
+	*dst = d
+	return nil
+}
+
+// Validate reports every missing or malformed value at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port %d is out of range", c.Port))
+	}
+
+	if c.Database.DSN == "" {
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database host is required"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database name is required"))
+		}
+		if c.Database.Username == "" {
+			errs = append(errs, errors.New("database username is required"))
+		}
+		if p, err := strconv.Atoi(c.Database.Port); err != nil || p < 1 || p > 65535 {
+			errs = append(errs, fmt.Errorf("database port %q is invalid", c.Database.Port))
+		}
+	} else if _, err := url.Parse(c.Database.DSN); err != nil {
+		errs = append(errs, fmt.Errorf("database dsn is invalid: %w", err))
+	}
+
+	if c.SpaceX.APIURL == "" {
+		errs = append(errs, errors.New("spacex api url is required"))
+	} else if u, err := url.Parse(c.SpaceX.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("spacex api url %q is not an http(s) URL", c.SpaceX.APIURL))
+	}
+
+	if c.SpaceX.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("spacex timeout %s must be positive", c.SpaceX.Timeout))
+	}
+	if c.SpaceX.Retries < 0 {
+		errs = append(errs, fmt.Errorf("spacex retries %d must not be negative", c.SpaceX.Retries))
+	}
+	if c.SpaceX.BreakerThreshold < 0 {
+		errs = append(errs, fmt.Errorf("spacex breaker threshold %d must not be negative", c.SpaceX.BreakerThreshold))
+	}
+	if c.SpaceX.BreakerThreshold > 0 && c.SpaceX.BreakerCooldown <= 0 {
+		errs = append(errs, fmt.Errorf("spacex breaker cooldown %s must be positive", c.SpaceX.BreakerCooldown))
+	}
+	if c.SpaceX.FailurePolicy != FailOpen && c.SpaceX.FailurePolicy != FailClosed {
+		errs = append(errs, fmt.Errorf("spacex failure policy %q must be %q or %q", c.SpaceX.FailurePolicy, FailOpen, FailClosed))
+	}
+
+	if c.Reconcile.Interval < 0 {
+		errs = append(errs, fmt.Errorf("reconcile interval %s must not be negative", c.Reconcile.Interval))
+	}
+
+	if c.Webhooks.Interval < 0 {
+		errs = append(errs, fmt.Errorf("webhook interval %s must not be negative", c.Webhooks.Interval))
+	}
+	if c.Webhooks.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("webhook timeout %s must be positive", c.Webhooks.Timeout))
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("webhook max attempts %d must be positive", c.Webhooks.MaxAttempts))
+	}
+
+	if c.Events.PollInterval < 0 {
+		errs = append(errs, fmt.Errorf("events poll interval %s must not be negative", c.Events.PollInterval))
+	}
+	if c.Events.Heartbeat <= 0 {
+		errs = append(errs, fmt.Errorf("events heartbeat %s must be positive", c.Events.Heartbeat))
+	}
+
+	switch c.Mail.Mailer {
+	case MailerLog:
+	case MailerFile:
+		if c.Mail.Dir == "" {
+			errs = append(errs, errors.New("mail dir is required for the file mailer"))
+		}
+	case MailerSMTP:
+		if c.Mail.SMTP.Host == "" {
+			errs = append(errs, errors.New("smtp host is required for the smtp mailer"))
+		}
+		if c.Mail.SMTP.Port < 1 || c.Mail.SMTP.Port > 65535 {
+			errs = append(errs, fmt.Errorf("smtp port %d is out of range", c.Mail.SMTP.Port))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mailer %q must be %q, %q or %q", c.Mail.Mailer, MailerLog, MailerFile, MailerSMTP))
+	}
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		errs = append(errs, fmt.Errorf("mail from %q is not an email address", c.Mail.From))
+	}
+	if c.Mail.Interval < 0 {
+		errs = append(errs, fmt.Errorf("mail interval %s must not be negative", c.Mail.Interval))
+	}
+	if c.Mail.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("mail max attempts %d must be positive", c.Mail.MaxAttempts))
+	}
+
+	if c.Tickets.SigningKey != "" {
+		if seed, err := base64.StdEncoding.DecodeString(c.Tickets.SigningKey); err != nil || len(seed) != ed25519.SeedSize {
+			errs = append(errs, fmt.Errorf("ticket signing key must be %d bytes in base64", ed25519.SeedSize))
+		}
+	}
+
+	if c.CheckIn.OpensBefore < 0 {
+		errs = append(errs, fmt.Errorf("check-in opens before %s must not be negative", c.CheckIn.OpensBefore))
+	}
+	if c.CheckIn.ClosesAfter < 0 || c.CheckIn.ClosesAfter > 24*time.Hour {
+		errs = append(errs, fmt.Errorf("check-in closes after %s must be within the launch day", c.CheckIn.ClosesAfter))
+	}
+	if -c.CheckIn.OpensBefore >= c.CheckIn.ClosesAfter {
+		errs = append(errs, errors.New("check-in window is empty"))
+	}
+
+	if c.Manifests.Lead < 0 {
+		errs = append(errs, fmt.Errorf("manifest lead %s must not be negative", c.Manifests.Lead))
+	}
+	if c.Manifests.Interval < 0 {
+		errs = append(errs, fmt.Errorf("manifest interval %s must not be negative", c.Manifests.Interval))
+	}
+
+	if !currencyCode.MatchString(c.Pricing.Currency) {
+		errs = append(errs, fmt.Errorf("pricing currency %q is not an ISO 4217 code", c.Pricing.Currency))
+	}
+	if c.Pricing.Seats < 1 {
+		errs = append(errs, fmt.Errorf("flight seats %d must be positive", c.Pricing.Seats))
+	}
+	if c.Pricing.LoadSurcharge < 0 {
+		errs = append(errs, fmt.Errorf("pricing load surcharge %g must not be negative", c.Pricing.LoadSurcharge))
+	}
+	errs = append(errs, validateTiers("pricing advance", c.Pricing.Advance)...)
+	errs = append(errs, validateTiers("pricing age bands", c.Pricing.AgeBands)...)
+	if c.Pricing.QuoteTTL <= 0 {
+		errs = append(errs, fmt.Errorf("quote ttl %s must be positive", c.Pricing.QuoteTTL))
+	}
+	if c.Pricing.QuoteSigningKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(c.Pricing.QuoteSigningKey); err != nil || len(key) < 32 {
+			errs = append(errs, errors.New("quote signing key must be at least 32 bytes in base64"))
+		}
+	}
+
+	if c.Payments.Provider != PaymentFake {
+		errs = append(errs, fmt.Errorf("payment provider %q must be %q", c.Payments.Provider, PaymentFake))
+	}
+	if c.Payments.PendingTTL <= 0 {
+		errs = append(errs, fmt.Errorf("payment pending ttl %s must be positive", c.Payments.PendingTTL))
+	}
+	if c.Payments.Interval < 0 {
+		errs = append(errs, fmt.Errorf("payment interval %s must not be negative", c.Payments.Interval))
+	}
+	if c.Payments.RefundMaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("refund max attempts %d must be positive", c.Payments.RefundMaxAttempts))
+	}
+
+	froms := make([]int, len(c.Cancellation.Refunds))
+	for i, t := range c.Cancellation.Refunds {
+		froms[i] = t.From
+		if t.Share < 0 || t.Share > 1 {
+			errs = append(errs, fmt.Errorf("cancellation refunds: tier from %d has share %g, which must be within 0 and 1", t.From, t.Share))
+		}
+	}
+	errs = append(errs, validateThresholds("cancellation refunds", froms)...)
+
+	if c.Waitlist.Hold <= 0 {
+		errs = append(errs, fmt.Errorf("waitlist hold %s must be positive", c.Waitlist.Hold))
+	}
+	if c.Waitlist.Interval < 0 {
+		errs = append(errs, fmt.Errorf("waitlist interval %s must not be negative", c.Waitlist.Interval))
+	}
+	if c.Holds.TTL <= 0 {
+		errs = append(errs, fmt.Errorf("hold TTL %s must be positive", c.Holds.TTL))
+	}
+	if c.Holds.MaxSeats < 1 {
+		errs = append(errs, fmt.Errorf("hold max seats %d must be at least 1", c.Holds.MaxSeats))
+	}
+	if c.Holds.Interval < 0 {
+		errs = append(errs, fmt.Errorf("hold interval %s must not be negative", c.Holds.Interval))
+	}
+
+	if c.Booking.HorizonDays < 1 {
+		errs = append(errs, fmt.Errorf("booking horizon of %d days must be positive", c.Booking.HorizonDays))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// currencyCode matches an ISO 4217 currency code.
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// validateTiers requires a tier from zero, positive factors and distinct
+// thresholds.
+func validateTiers(name string, tiers []Tier) []error {
+	var errs []error
+	froms := make([]int, len(tiers))
+	for i, t := range tiers {
+		froms[i] = t.From
+		if t.Factor <= 0 {
+			errs = append(errs, fmt.Errorf("%s: tier from %d has factor %g, which must be positive", name, t.From, t.Factor))
+		}
+	}
+	return append(errs, validateThresholds(name, froms)...)
+}
+
+// validateThresholds requires tiers from distinct thresholds that are not
+// negative, one of them zero.
+func validateThresholds(name string, froms []int) []error {
+	var errs []error
+	seen := make(map[int]bool)
+	for _, from := range froms {
+		if from < 0 {
+			errs = append(errs, fmt.Errorf("%s: tier from %d must not be negative", name, from))
+		}
+		if seen[from] {
+			errs = append(errs, fmt.Errorf("%s: two tiers from %d", name, from))
+		}
+		seen[from] = true
+	}
+	if !seen[0] {
+		errs = append(errs, fmt.Errorf("%s: a tier from 0 is required", name))
+	}
+	return errs
+}
+
+// Addr returns the address the HTTP server listens on.
+func (c *Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Port)
+}
+
+// ConnString returns the PostgreSQL connection string, preferring the DSN
+// override when it is set.
+func (d Database) ConnString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+	return fmt.Sprintf(
+		"postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s",
+		d.Username, d.Password, d.Host, d.Port, d.Name, d.Schema,
+	)
+}
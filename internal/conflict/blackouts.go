@@ -0,0 +1,49 @@
+package conflict
+
+import (
+	"context"
+	"time"
+
+	"space-booking/internal/models"
+)
+
+// BlackoutStore reads the launchpad blackouts managed in the database.
+type BlackoutStore interface {
+	GetBlackouts(ctx context.Context, launchpadID string, from, to time.Time) ([]models.Blackout, error)
+}
+
+// Blackouts reports the manual launchpad blackouts kept in the database.
+type Blackouts struct {
+	store BlackoutStore
+}
+
+// NewBlackouts returns a provider backed by store.
+func NewBlackouts(store BlackoutStore) *Blackouts {
+	return &Blackouts{store: store}
+}
+
+// Name implements Provider.
+func (p *Blackouts) Name() string {
+	return "blackouts"
+}
+
+// Conflicts implements Provider.
+func (p *Blackouts) Conflicts(ctx context.Context, launchpadID string, from, to time.Time) ([]Conflict, error) {
+	blackouts, err := p.store.GetBlackouts(ctx, launchpadID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []Conflict
+	for _, b := range blackouts {
+		days(b.StartsOn, b.EndsOn, from, to, func(day time.Time) {
+			conflicts = append(conflicts, Conflict{
+				Provider:    p.Name(),
+				LaunchpadID: launchpadID,
+				Date:        day,
+				Reason:      b.Reason,
+			})
+		})
+	}
+	return conflicts, nil
+}
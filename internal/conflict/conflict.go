@@ -0,0 +1,173 @@
+package conflict
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"space-booking/internal/clock"
+)
+
+// Conflict is a reason a launchpad cannot be used on a calendar day.
+type Conflict struct {
+	// Provider is the name of the provider that reported the conflict.
+	Provider    string    `json:"provider"`
+	LaunchpadID string    `json:"launchpad_id"`
+	Date        time.Time `json:"date"`
+	Reason      string    `json:"reason"`
+}
+
+// Provider is a source of launchpad conflicts, such as a competitor's
+// launch schedule or a list of range closures.
+type Provider interface {
+	// Name identifies the provider in reported conflicts.
+	Name() string
+	// Conflicts returns the conflicts on the launchpad for every calendar
+	// day from from to to, both included.
+	Conflicts(ctx context.Context, launchpadID string, from, to time.Time) ([]Conflict, error)
+}
+
+// Aggregator combines several providers into one. It is itself a Provider.
+type Aggregator struct {
+	providers []Provider
+}
+
+// NewAggregator returns an Aggregator over the given providers.
+func NewAggregator(providers ...Provider) *Aggregator {
+	return &Aggregator{providers: providers}
+}
+
+// Name implements Provider.
+func (a *Aggregator) Name() string {
+	return "aggregate"
+}
+
+// Conflicts queries all providers concurrently and returns their conflicts
+// sorted by date. It fails if any provider fails.
+func (a *Aggregator) Conflicts(ctx context.Context, launchpadID string, from, to time.Time) ([]Conflict, error) {
+	results := make([][]Conflict, len(a.providers))
+	errs := make([]error, len(a.providers))
+
+	var wg sync.WaitGroup
+	for i, p := range a.providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = p.Conflicts(ctx, launchpadID, from, to)
+		}()
+	}
+	wg.Wait()
+
+	var conflicts []Conflict
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("conflict provider %s: %w", a.providers[i].Name(), err)
+		}
+		conflicts = append(conflicts, results[i]...)
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].Date.Before(conflicts[j].Date)
+	})
+	return conflicts, nil
+}
+
+// Check returns the first conflict on the launchpad on date, or nil when
+// the launchpad is free.
+func (a *Aggregator) Check(ctx context.Context, launchpadID string, date time.Time) (*Conflict, error) {
+	day := clock.Day(date)
+	conflicts, err := a.Conflicts(ctx, launchpadID, day, day)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+	return &conflicts[0], nil
+}
+
+// Checker tells whether a launchpad is free on a day. Aggregator and
+// Snapshot are Checkers.
+type Checker interface {
+	// Check returns the first conflict on the launchpad on date, or nil
+	// when the launchpad is free.
+	Check(ctx context.Context, launchpadID string, date time.Time) (*Conflict, error)
+}
+
+// Snapshot holds the conflicts of some launchpads over ranges of days,
+// read at once. It answers Check for those days from memory.
+type Snapshot struct {
+	// ranges are the first and last day read of every launchpad.
+	ranges    map[string][2]time.Time
+	conflicts map[string]map[time.Time]Conflict
+}
+
+// Snapshot reads the conflicts on every launchpad in dates over the days
+// from its earliest to its latest date, with one query per launchpad
+// however many dates it has. It fails like Conflicts.
+func (a *Aggregator) Snapshot(ctx context.Context, dates map[string][]time.Time) (*Snapshot, error) {
+	s := &Snapshot{
+		ranges:    make(map[string][2]time.Time),
+		conflicts: make(map[string]map[time.Time]Conflict),
+	}
+	for launchpadID, days := range dates {
+		if len(days) == 0 {
+			continue
+		}
+		from, to := clock.Day(days[0]), clock.Day(days[0])
+		for _, d := range days[1:] {
+			d = clock.Day(d)
+			if d.Before(from) {
+				from = d
+			}
+			if d.After(to) {
+				to = d
+			}
+		}
+		conflicts, err := a.Conflicts(ctx, launchpadID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		byDay := make(map[time.Time]Conflict)
+		for _, c := range conflicts {
+			day := clock.Day(c.Date)
+			if _, ok := byDay[day]; !ok {
+				byDay[day] = c
+			}
+		}
+		s.ranges[launchpadID] = [2]time.Time{from, to}
+		s.conflicts[launchpadID] = byDay
+	}
+	return s, nil
+}
+
+// Check implements Checker. It fails for a day that was not read.
+func (s *Snapshot) Check(_ context.Context, launchpadID string, date time.Time) (*Conflict, error) {
+	day := clock.Day(date)
+	r, ok := s.ranges[launchpadID]
+	if !ok || day.Before(r[0]) || day.After(r[1]) {
+		return nil, fmt.Errorf("conflict: launchpad %s on %s is not in the snapshot", launchpadID, day.Format("2006-01-02"))
+	}
+	c, ok := s.conflicts[launchpadID][day]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// days calls fn for every calendar day in the overlap of [start, end] and
+// [from, to], all ends included.
+func days(start, end, from, to time.Time, fn func(day time.Time)) {
+	start, end = clock.Day(start), clock.Day(end)
+	from, to = clock.Day(from), clock.Day(to)
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		fn(d)
+	}
+}
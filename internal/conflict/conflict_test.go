@@ -0,0 +1,175 @@
+package conflict
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"space-booking/internal/models"
+	"space-booking/internal/spacex"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var christmas = time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+
+// spaceXServer serves launches from the query endpoint.
+func spaceXServer(t *testing.T, launches []map[string]interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"docs": launches, "hasNextPage": false})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSpaceXConflict(t *testing.T) {
+	server := spaceXServer(t, []map[string]interface{}{
+		{"id": "launch_1", "name": "Test Launch", "date_local": "2049-12-25T00:00:00Z", "launchpad": "test_launchpad"},
+		{"id": "launch_2", "name": "Another Launch", "date_local": "2049-12-26T00:00:00Z", "launchpad": "test_launchpad"},
+		{"id": "launch_3", "name": "Invalid Date Launch", "date_local": "invalid-date-format", "launchpad": "test_launchpad"},
+	})
+	p := NewSpaceX(spacex.New(server.URL), false, nil)
+
+	conflicts, err := p.Conflicts(context.Background(), "test_launchpad", christmas, christmas)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1, "Expected only the launch on the day, skipping the invalid date")
+	assert.Equal(t, "spacex", conflicts[0].Provider)
+	assert.Equal(t, christmas, conflicts[0].Date)
+	assert.Equal(t, `SpaceX launch "Test Launch"`, conflicts[0].Reason)
+}
+
+func TestSpaceXFailurePolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<html>Bad Gateway</html>", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	// Fail open accepts the launchpad unchecked
+	p := NewSpaceX(spacex.New(server.URL, spacex.WithRetries(0)), true, nil)
+	conflicts, err := p.Conflicts(context.Background(), "test_launchpad", christmas, christmas)
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	// Fail closed reports SpaceX as unavailable
+	p = NewSpaceX(spacex.New(server.URL, spacex.WithRetries(0)), false, nil)
+	_, err = p.Conflicts(context.Background(), "test_launchpad", christmas, christmas)
+	assert.ErrorIs(t, err, spacex.ErrUnavailable)
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	csvPath := filepath.Join(dir, "range-safety.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte(
+		"launchpad_id,starts_on,ends_on,reason\n"+
+			"test_launchpad,2049-12-24,2049-12-26,Range closed\n"+
+			"other_launchpad,2049-12-25,,Weather\n"), 0o600))
+
+	jsonPath := filepath.Join(dir, "blue-origin.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(
+		`[{"launchpad_id": "test_launchpad", "starts_on": "2049-12-25", "reason": "Competitor launch"}]`), 0o600))
+
+	csvFile, err := LoadFile(csvPath)
+	require.NoError(t, err)
+	assert.Equal(t, "range-safety", csvFile.Name())
+
+	conflicts, err := csvFile.Conflicts(context.Background(), "test_launchpad", christmas, christmas.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	require.Len(t, conflicts, 2, "Expected the closure clipped to the requested days")
+	assert.Equal(t, christmas, conflicts[0].Date)
+	assert.Equal(t, christmas.AddDate(0, 0, 1), conflicts[1].Date)
+
+	jsonFile, err := LoadFile(jsonPath)
+	require.NoError(t, err)
+	conflicts, err = jsonFile.Conflicts(context.Background(), "test_launchpad", christmas, christmas)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "Competitor launch", conflicts[0].Reason)
+}
+
+func TestLoadFileRejectsBadDates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.csv")
+	require.NoError(t, os.WriteFile(path, []byte("launchpad_id,starts_on,ends_on\npad,2049-12-26,2049-12-24\n"), 0o600))
+
+	_, err := LoadFile(path)
+	assert.ErrorContains(t, err, "ends_on is before starts_on")
+}
+
+type blackoutStore []models.Blackout
+
+func (s blackoutStore) GetBlackouts(_ context.Context, launchpadID string, from, to time.Time) ([]models.Blackout, error) {
+	return s, nil
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (failingProvider) Conflicts(context.Context, string, time.Time, time.Time) ([]Conflict, error) {
+	return nil, errors.New("boom")
+}
+
+func TestAggregatorCheck(t *testing.T) {
+	blackouts := NewBlackouts(blackoutStore{
+		{LaunchpadID: "test_launchpad", StartsOn: christmas.AddDate(0, 0, -1), EndsOn: christmas, Reason: "Maintenance"},
+	})
+	server := spaceXServer(t, nil)
+	a := NewAggregator(NewSpaceX(spacex.New(server.URL), false, nil), blackouts)
+
+	c, err := a.Check(context.Background(), "test_launchpad", christmas)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, "blackouts", c.Provider, "Expected the provider that blocked the date")
+	assert.Equal(t, "Maintenance", c.Reason)
+
+	c, err = a.Check(context.Background(), "test_launchpad", christmas.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	_, err = NewAggregator(blackouts, failingProvider{}).Check(context.Background(), "test_launchpad", christmas)
+	assert.ErrorContains(t, err, "conflict provider failing: boom")
+}
+
+// countingProvider counts the queries made to blackouts.
+type countingProvider struct {
+	*Blackouts
+	queries int
+}
+
+func (p *countingProvider) Conflicts(ctx context.Context, launchpadID string, from, to time.Time) ([]Conflict, error) {
+	p.queries++
+	return p.Blackouts.Conflicts(ctx, launchpadID, from, to)
+}
+
+func TestAggregatorSnapshot(t *testing.T) {
+	p := &countingProvider{Blackouts: NewBlackouts(blackoutStore{
+		{LaunchpadID: "test_launchpad", StartsOn: christmas, EndsOn: christmas, Reason: "Maintenance"},
+	})}
+	a := NewAggregator(p)
+
+	s, err := a.Snapshot(context.Background(), map[string][]time.Time{
+		"test_launchpad": {christmas.AddDate(0, 0, 3), christmas.Add(9 * time.Hour), christmas.AddDate(0, 0, -2)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.queries, "Expected one query per launchpad")
+
+	c, err := s.Check(context.Background(), "test_launchpad", christmas.Add(15*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, "Maintenance", c.Reason)
+
+	c, err = s.Check(context.Background(), "test_launchpad", christmas.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	_, err = s.Check(context.Background(), "test_launchpad", christmas.AddDate(0, 0, 4))
+	assert.ErrorContains(t, err, "not in the snapshot")
+	assert.Equal(t, 1, p.queries)
+}
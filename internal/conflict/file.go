@@ -0,0 +1,148 @@
+package conflict
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// closure is a range of days a launchpad is closed, as read from a file.
+type closure struct {
+	LaunchpadID string `json:"launchpad_id"`
+	StartsOn    string `json:"starts_on"`
+	EndsOn      string `json:"ends_on"`
+	Reason      string `json:"reason"`
+
+	start, end time.Time
+}
+
+// File reports the closures listed in a local JSON or CSV file, such as
+// another competitor's schedule or range-safety closures.
+//
+// A JSON file holds an array of objects and a CSV file has a header row,
+// both with the fields launchpad_id, starts_on, ends_on and reason. Dates
+// are written as 2006-01-02 and ends_on defaults to starts_on.
+type File struct {
+	name     string
+	closures []closure
+}
+
+// LoadFile reads the closures from path. The provider is named after the
+// file, without its extension.
+func LoadFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var closures []closure
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.NewDecoder(f).Decode(&closures)
+	case ".csv":
+		closures, err = readCSV(f)
+	default:
+		return nil, fmt.Errorf("conflict file %s: unsupported format %q", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("conflict file %s: %w", path, err)
+	}
+
+	for i := range closures {
+		if err := closures[i].parse(); err != nil {
+			return nil, fmt.Errorf("conflict file %s: entry %d: %w", path, i+1, err)
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &File{name: name, closures: closures}, nil
+}
+
+func readCSV(r io.Reader) ([]closure, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"launchpad_id", "starts_on"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	closures := make([]closure, 0, len(records)-1)
+	for _, record := range records[1:] {
+		closures = append(closures, closure{
+			LaunchpadID: field(record, "launchpad_id"),
+			StartsOn:    field(record, "starts_on"),
+			EndsOn:      field(record, "ends_on"),
+			Reason:      field(record, "reason"),
+		})
+	}
+	return closures, nil
+}
+
+func (c *closure) parse() error {
+	if c.LaunchpadID == "" {
+		return errors.New("launchpad_id is required")
+	}
+	var err error
+	if c.start, err = time.Parse(time.DateOnly, c.StartsOn); err != nil {
+		return fmt.Errorf("starts_on: %w", err)
+	}
+	c.end = c.start
+	if c.EndsOn != "" {
+		if c.end, err = time.Parse(time.DateOnly, c.EndsOn); err != nil {
+			return fmt.Errorf("ends_on: %w", err)
+		}
+	}
+	if c.end.Before(c.start) {
+		return errors.New("ends_on is before starts_on")
+	}
+	return nil
+}
+
+// Name implements Provider.
+func (p *File) Name() string {
+	return p.name
+}
+
+// Conflicts implements Provider.
+func (p *File) Conflicts(_ context.Context, launchpadID string, from, to time.Time) ([]Conflict, error) {
+	var conflicts []Conflict
+	for _, c := range p.closures {
+		if c.LaunchpadID != launchpadID {
+			continue
+		}
+		days(c.start, c.end, from, to, func(day time.Time) {
+			conflicts = append(conflicts, Conflict{
+				Provider:    p.name,
+				LaunchpadID: launchpadID,
+				Date:        day,
+				Reason:      c.Reason,
+			})
+		})
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,79 @@
+package conflict
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/spacex"
+)
+
+// SpaceX reports the days SpaceX launches from a launchpad.
+type SpaceX struct {
+	client *spacex.Client
+	// failOpen reports no conflicts while SpaceX is unreachable instead of
+	// failing.
+	failOpen bool
+	logger   *log.Logger
+}
+
+// NewSpaceX returns a provider backed by the SpaceX API. While the API is
+// unreachable it fails with an error wrapping spacex.ErrUnavailable, or,
+// when failOpen is set, logs and reports no conflicts.
+func NewSpaceX(client *spacex.Client, failOpen bool, logger *log.Logger) *SpaceX {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &SpaceX{client: client, failOpen: failOpen, logger: logger}
+}
+
+// Name implements Provider.
+func (p *SpaceX) Name() string {
+	return "spacex"
+}
+
+// Conflicts implements Provider. A launch occupies its launchpad on the
+// calendar day of its local launch time.
+func (p *SpaceX) Conflicts(ctx context.Context, launchpadID string, from, to time.Time) ([]Conflict, error) {
+	// Launch sites are at most a day away from UTC, so the launches whose
+	// local date is in range all fall within a day either side of it.
+	from, to = clock.Day(from), clock.Day(to)
+	launches, err := p.client.QueryLaunches(ctx, spacex.LaunchQuery{
+		LaunchpadID: launchpadID,
+		From:        from.AddDate(0, 0, -1),
+		To:          to.AddDate(0, 0, 2),
+	})
+	if err != nil {
+		if errors.Is(err, spacex.ErrUnavailable) && p.failOpen {
+			p.logger.Printf("SpaceX unavailable, accepting launchpad %s unchecked: %v", launchpadID, err)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var conflicts []Conflict
+	for _, launch := range launches {
+		dateLocal, err := launch.LocalDate()
+		if err != nil {
+			p.logger.Printf("Error parsing dateLocal for launch %s: %v", launch.ID, err)
+			continue // Skip this launch due to invalid date
+		}
+		if launch.Launchpad != launchpadID {
+			continue
+		}
+		day := clock.Day(dateLocal)
+		if day.Before(from) || day.After(to) {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{
+			Provider:    p.Name(),
+			LaunchpadID: launchpadID,
+			Date:        day,
+			Reason:      fmt.Sprintf("SpaceX launch %q", launch.Name),
+		})
+	}
+	return conflicts, nil
+}
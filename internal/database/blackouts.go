@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"space-booking/internal/models"
+	"time"
+)
+
+// blackoutColumns are the columns scanned by scanBlackout, in order.
+const blackoutColumns = `id, launchpad_id, starts_on, ends_on, reason`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBlackout(row scanner) (models.Blackout, error) {
+	var b models.Blackout
+	err := row.Scan(&b.ID, &b.LaunchpadID, &b.StartsOn, &b.EndsOn, &b.Reason)
+	return b, err
+}
+
+func scanBlackouts(rows *sql.Rows) ([]models.Blackout, error) {
+	defer rows.Close()
+
+	var blackouts []models.Blackout
+	for rows.Next() {
+		b, err := scanBlackout(rows)
+		if err != nil {
+			return nil, err
+		}
+		blackouts = append(blackouts, b)
+	}
+	return blackouts, rows.Err()
+}
+
+func (s *service) GetBlackouts(ctx context.Context, launchpadID string, from, to time.Time) ([]models.Blackout, error) {
+	query := `
+		SELECT ` + blackoutColumns + `
+		FROM launchpad_blackouts
+		WHERE launchpad_id = $1 AND starts_on <= $3 AND ends_on >= $2
+		ORDER BY starts_on, id
+	`
+	rows, err := s.db.QueryContext(ctx, query, launchpadID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlackouts(rows)
+}
+
+func (s *service) ListBlackouts(ctx context.Context, launchpadID string) ([]models.Blackout, error) {
+	query := `
+		SELECT ` + blackoutColumns + `
+		FROM launchpad_blackouts
+		WHERE $1 = '' OR launchpad_id = $1
+		ORDER BY starts_on, id
+	`
+	rows, err := s.db.QueryContext(ctx, query, launchpadID)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlackouts(rows)
+}
+
+func (s *service) GetBlackout(ctx context.Context, id int) (*models.Blackout, error) {
+	query := `
+		SELECT ` + blackoutColumns + `
+		FROM launchpad_blackouts
+		WHERE id = $1
+	`
+	b, err := scanBlackout(s.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (s *service) CreateBlackout(ctx context.Context, blackout *models.Blackout) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO launchpad_blackouts (launchpad_id, starts_on, ends_on, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		blackout.LaunchpadID,
+		blackout.StartsOn,
+		blackout.EndsOn,
+		blackout.Reason,
+	).Scan(&blackout.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.publishBlackout(ctx, tx, models.BlackoutCreated, blackout); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *service) UpdateBlackout(ctx context.Context, blackout *models.Blackout) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE launchpad_blackouts
+		SET launchpad_id = $2, starts_on = $3, ends_on = $4, reason = $5
+		WHERE id = $1
+	`
+	res, err := tx.ExecContext(
+		ctx,
+		query,
+		blackout.ID,
+		blackout.LaunchpadID,
+		blackout.StartsOn,
+		blackout.EndsOn,
+		blackout.Reason,
+	)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(res); err != nil {
+		return err
+	}
+	if err := s.publishBlackout(ctx, tx, models.BlackoutUpdated, blackout); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *service) DeleteBlackout(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM launchpad_blackouts
+		WHERE id = $1
+		RETURNING ` + blackoutColumns
+	blackout, err := scanBlackout(tx.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.publishBlackout(ctx, tx, models.BlackoutDeleted, &blackout); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// publishBlackout announces a schedule change through the outbox.
+func (s *service) publishBlackout(ctx context.Context, tx *sql.Tx, messageType string, blackout *models.Blackout) error {
+	payload, err := json.Marshal(blackout)
+	if err != nil {
+		return err
+	}
+	return publish(ctx, tx, messageType, 0, payload, s.clock.Now())
+}
+
+// expectAffected returns ErrNotFound when res touched no rows.
+func expectAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
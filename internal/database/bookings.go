@@ -0,0 +1,533 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"space-booking/internal/models"
+	"strings"
+	"time"
+)
+
+// bookingColumns are the columns scanned by scanBooking, in order.
+const bookingColumns = `id, first_name, last_name, gender, birthday, launchpad_id, destination_id, launch_date,
+	status, created_at, confirmed_at, checked_in_at, boarded_at, cancelled_at, disrupted_at, rebooked_at, flown_at,
+	COALESCE(disruption_reason, ''), rebooked_from, COALESCE(email, ''), code, COALESCE(price_cents, 0),
+	COALESCE(currency, ''), expired_at, COALESCE(disrupted_by, ''), COALESCE(promo_code, ''),
+	COALESCE(discount_cents, 0), hold_until, hold_id`
+
+// activeStatuses matches the statuses in models.ActiveStatuses.
+const activeStatuses = `('pending', 'confirmed', 'checked_in', 'boarded')`
+
+func scanBooking(row scanner) (models.Booking, error) {
+	var booking models.Booking
+	err := row.Scan(bookingFields(&booking)...)
+	return booking, err
+}
+
+// bookingFields returns the destinations of bookingColumns in booking.
+func bookingFields(booking *models.Booking) []any {
+	return []any{
+		&booking.ID,
+		&booking.FirstName,
+		&booking.LastName,
+		&booking.Gender,
+		&booking.Birthday,
+		&booking.LaunchpadID,
+		&booking.DestinationID,
+		&booking.LaunchDate,
+		&booking.Status,
+		&booking.CreatedAt,
+		&booking.ConfirmedAt,
+		&booking.CheckedInAt,
+		&booking.BoardedAt,
+		&booking.CancelledAt,
+		&booking.DisruptedAt,
+		&booking.RebookedAt,
+		&booking.FlownAt,
+		&booking.DisruptionReason,
+		&booking.RebookedFrom,
+		&booking.Email,
+		&booking.Code,
+		&booking.Price,
+		&booking.Currency,
+		&booking.ExpiredAt,
+		&booking.DisruptedBy,
+		&booking.PromoCode,
+		&booking.Discount,
+		&booking.HoldUntil,
+		&booking.HoldID,
+	}
+}
+
+// scanBookings reads and closes rows selected with bookingColumns.
+func scanBookings(rows *sql.Rows) ([]models.Booking, error) {
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, rows.Err()
+}
+
+func (s *service) CreateBooking(ctx context.Context, booking *models.Booking, capacity int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A held seat was counted against the capacity when it was held.
+	now := s.clock.Now()
+	if booking.HoldToken != "" {
+		err = takeHeldSeat(ctx, tx, booking, now)
+	} else {
+		err = claimSeats(ctx, tx, booking.LaunchpadID, booking.LaunchDate, 1, capacity, now)
+	}
+	if err != nil {
+		return err
+	}
+	if err := insertBooking(ctx, tx, booking, now); err != nil {
+		return err
+	}
+	if booking.PromoCode != "" {
+		if err := redeemPromo(ctx, tx, booking, now); err != nil {
+			return err
+		}
+	}
+	if err := recordEvent(ctx, tx, models.EventCreated, nil, booking, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *service) CreateBookings(ctx context.Context, bookings []*models.Booking, capacity int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The flights are locked in order, so that concurrent imports cannot
+	// deadlock.
+	now := s.clock.Now()
+	flights := slices.Clone(bookings)
+	slices.SortFunc(flights, func(a, b *models.Booking) int {
+		return cmp.Or(strings.Compare(a.LaunchpadID, b.LaunchpadID), a.LaunchDate.Compare(b.LaunchDate))
+	})
+	for i := 0; i < len(flights); {
+		j := i + 1
+		for j < len(flights) && flights[j].LaunchpadID == flights[i].LaunchpadID && flights[j].LaunchDate.Equal(flights[i].LaunchDate) {
+			j++
+		}
+		if err := claimSeats(ctx, tx, flights[i].LaunchpadID, flights[i].LaunchDate, j-i, capacity, now); err != nil {
+			return err
+		}
+		i = j
+	}
+
+	for _, booking := range bookings {
+		if err := insertBooking(ctx, tx, booking, now); err != nil {
+			return err
+		}
+		if err := recordEvent(ctx, tx, models.EventCreated, nil, booking, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertBooking stores booking, confirmed unless its status says otherwise,
+// and fills in the generated fields. A confirmed booking with an email
+// address gets its confirmation queued.
+func insertBooking(ctx context.Context, tx *sql.Tx, booking *models.Booking, now time.Time) error {
+	if booking.Status == "" {
+		booking.Status = models.StatusConfirmed
+	}
+	code, err := models.NewBookingCode()
+	if err != nil {
+		return err
+	}
+	var confirmedAt *time.Time
+	if booking.Status == models.StatusConfirmed {
+		confirmedAt = &now
+	}
+
+	query := `
+		INSERT INTO bookings (first_name, last_name, gender, birthday, launchpad_id, destination_id, launch_date,
+			status, created_at, confirmed_at, rebooked_from, email, code, price_cents, currency, promo_code, discount_cents,
+			hold_until, hold_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, NULLIF($14, 0), NULLIF($15, ''),
+			NULLIF($16, ''), NULLIF($17, 0), $18, $19)
+		RETURNING id
+	`
+	var id int
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		booking.FirstName,
+		booking.LastName,
+		booking.Gender,
+		booking.Birthday,
+		booking.LaunchpadID,
+		booking.DestinationID,
+		booking.LaunchDate,
+		booking.Status,
+		now,
+		confirmedAt,
+		booking.RebookedFrom,
+		booking.Email,
+		code,
+		booking.Price,
+		booking.Currency,
+		booking.PromoCode,
+		booking.Discount,
+		booking.HoldUntil,
+		booking.HoldID,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+	booking.ID = id
+	booking.Code = code
+	booking.CreatedAt = now
+	booking.ConfirmedAt = confirmedAt
+	if booking.Status == models.StatusConfirmed {
+		return queueNotification(ctx, tx, models.NotificationConfirmation, booking, now)
+	}
+	return nil
+}
+
+// bound returns t as a query argument, NULL when it is zero.
+func bound(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// bookingWhere returns the WHERE clause selecting the bookings matching
+// filter and its arguments.
+func bookingWhere(filter models.BookingFilter) (string, []any) {
+	where := `
+		WHERE (cardinality($1::text[]) = 0 OR status = ANY($1))
+		AND ($2 = '' OR launchpad_id = $2)
+		AND ($3 = 0 OR destination_id = $3)
+		AND ($4::date IS NULL OR launch_date >= $4)
+		AND ($5::date IS NULL OR launch_date <= $5)
+		AND ($6::timestamptz IS NULL OR created_at >= $6)
+		AND ($7::timestamptz IS NULL OR created_at < $7)
+	`
+	statuses := make([]string, len(filter.Statuses))
+	for i, status := range filter.Statuses {
+		statuses[i] = string(status)
+	}
+	return where, []any{
+		statuses,
+		filter.LaunchpadID,
+		filter.DestinationID,
+		bound(filter.LaunchFrom),
+		bound(filter.LaunchTo),
+		bound(filter.CreatedFrom),
+		bound(filter.CreatedTo),
+	}
+}
+
+func (s *service) GetBookings(ctx context.Context, filter models.BookingFilter) ([]models.Booking, error) {
+	where, args := bookingWhere(filter)
+	query := `SELECT ` + bookingColumns + ` FROM bookings` + where + `ORDER BY id`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanBookings(rows)
+}
+
+// exportBatch is the number of rows fetched from the export cursor at once.
+const exportBatch = 1000
+
+func (s *service) ExportBookings(ctx context.Context, filter models.BookingFilter, fn func(*models.Booking) error) error {
+	// The cursor lives as long as the transaction, which sees one snapshot
+	// of the table however long the export takes.
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, args := bookingWhere(filter)
+	declare := `DECLARE export_bookings NO SCROLL CURSOR FOR SELECT ` + bookingColumns + ` FROM bookings` + where + `ORDER BY id`
+	if _, err := tx.ExecContext(ctx, declare, args...); err != nil {
+		return err
+	}
+	for {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(`FETCH FORWARD %d FROM export_bookings`, exportBatch))
+		if err != nil {
+			return err
+		}
+		n := 0
+		for rows.Next() {
+			booking, err := scanBooking(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			n++
+			if err := fn(&booking); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n < exportBatch {
+			return tx.Commit()
+		}
+	}
+}
+
+func (s *service) GetBooking(ctx context.Context, id int) (*models.Booking, error) {
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings
+		WHERE id = $1
+	`
+	booking, err := scanBooking(s.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+func (s *service) GetBookingByCode(ctx context.Context, code string) (*models.Booking, error) {
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings
+		WHERE code = $1
+	`
+	booking, err := scanBooking(s.db.QueryRowContext(ctx, query, models.NormalizeBookingCode(code)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+func (s *service) UpdateBookingStatus(ctx context.Context, id int, status models.BookingStatus, reason string) (*models.Booking, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockBooking(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	after, err := changeStatus(ctx, tx, before, status, reason, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// changeStatus moves the locked booking to status, logs the change and
+// queues the confirmation of a confirmed booking.
+func changeStatus(ctx context.Context, tx *sql.Tx, before *models.Booking, status models.BookingStatus, reason string, now time.Time) (*models.Booking, error) {
+	after, err := setStatus(ctx, tx, before, status, reason, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordEvent(ctx, tx, models.StatusEvent(status), before, after, now); err != nil {
+		return nil, err
+	}
+	if status == models.StatusConfirmed {
+		if err := queueNotification(ctx, tx, models.NotificationConfirmation, after, now); err != nil {
+			return nil, err
+		}
+	}
+	return after, nil
+}
+
+func (s *service) DisruptBooking(ctx context.Context, id int, reason, source string) (*models.Booking, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockBooking(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	after, err := setStatus(ctx, tx, before, models.StatusDisrupted, reason, now)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE bookings SET disrupted_by = $2 WHERE id = $1`, id, source); err != nil {
+		return nil, err
+	}
+	after.DisruptedBy = source
+	if err := recordEvent(ctx, tx, models.EventDisrupted, before, after, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+func (s *service) RebookBooking(ctx context.Context, id int, replacement *models.Booking, capacity int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockBooking(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	after, err := setStatus(ctx, tx, before, models.StatusRebooked, "", now)
+	if err != nil {
+		return err
+	}
+	if err := recordEvent(ctx, tx, models.EventRebooked, before, after, now); err != nil {
+		return err
+	}
+
+	// The old seat is free by now, should the new flight be the same.
+	if err := claimSeats(ctx, tx, replacement.LaunchpadID, replacement.LaunchDate, 1, capacity, now); err != nil {
+		return err
+	}
+	replacement.Status = models.StatusConfirmed
+	replacement.RebookedFrom = &id
+	if err := insertBooking(ctx, tx, replacement, now); err != nil {
+		return err
+	}
+	if err := recordEvent(ctx, tx, models.EventCreated, nil, replacement, now); err != nil {
+		return err
+	}
+
+	// The fare paid for the old flight pays for the new one, and is
+	// refunded from it.
+	_, err = tx.ExecContext(ctx, `UPDATE payments SET booking_id = $2 WHERE booking_id = $1`, id, replacement.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockBooking reads the booking and locks it until tx ends.
+func lockBooking(ctx context.Context, tx *sql.Tx, id int) (*models.Booking, error) {
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings
+		WHERE id = $1
+		FOR UPDATE
+	`
+	booking, err := scanBooking(tx.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// setStatus moves the locked booking to status if the state machine allows
+// it, and returns the updated booking.
+func setStatus(ctx context.Context, tx *sql.Tx, booking *models.Booking, status models.BookingStatus, reason string, now time.Time) (*models.Booking, error) {
+	if !booking.Status.CanTransitionTo(status) {
+		return nil, &models.TransitionError{From: booking.Status, To: status}
+	}
+	column, err := statusTimeColumn(status)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE bookings
+		SET status = $2, ` + column + ` = $3,
+			disruption_reason = CASE WHEN $2 = 'disrupted' THEN $4 ELSE disruption_reason END
+		WHERE id = $1
+		RETURNING ` + bookingColumns
+	updated, err := scanBooking(tx.QueryRowContext(ctx, query, booking.ID, status, now, reason))
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// statusTimeColumn returns the column recording when a booking entered
+// status.
+func statusTimeColumn(status models.BookingStatus) (string, error) {
+	switch status {
+	case models.StatusConfirmed:
+		return "confirmed_at", nil
+	case models.StatusCheckedIn:
+		return "checked_in_at", nil
+	case models.StatusBoarded:
+		return "boarded_at", nil
+	case models.StatusCancelled:
+		return "cancelled_at", nil
+	case models.StatusDisrupted:
+		return "disrupted_at", nil
+	case models.StatusRebooked:
+		return "rebooked_at", nil
+	case models.StatusFlown:
+		return "flown_at", nil
+	case models.StatusExpired:
+		return "expired_at", nil
+	}
+	return "", fmt.Errorf("bookings cannot move to status %q", status)
+}
+
+func (s *service) GetBookingsOnLaunchpad(ctx context.Context, launchpadID string, from, to time.Time) ([]models.Booking, error) {
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings
+		WHERE launchpad_id = $1 AND launch_date BETWEEN $2 AND $3 AND status IN ` + activeStatuses + `
+		ORDER BY launch_date, id
+	`
+	rows, err := s.db.QueryContext(ctx, query, launchpadID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanBookings(rows)
+}
+
+func (s *service) GetUpcomingBookings(ctx context.Context, from time.Time) ([]models.Booking, error) {
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings
+		WHERE launch_date >= $1 AND status IN ` + activeStatuses + `
+		ORDER BY launchpad_id, launch_date, id
+	`
+	rows, err := s.db.QueryContext(ctx, query, from)
+	if err != nil {
+		return nil, err
+	}
+	return scanBookings(rows)
+}
@@ -3,12 +3,11 @@ package database
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
+	"space-booking/internal/clock"
+	"space-booking/internal/config"
 	"space-booking/internal/models"
 	"strconv"
 	"time"
@@ -19,9 +18,6 @@ import (
 	// Migration libraries
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-
-	// Environment variables
-	_ "github.com/joho/godotenv/autoload"
 )
 
 // Service represents a service that interacts with a database.
@@ -34,45 +30,284 @@ type Service interface {
 	// It returns an error if the connection cannot be closed.
 	Close() error
 
-	CreateBooking(booking *models.Booking) error
-	GetAllBookings() ([]models.Booking, error)
-	CheckLaunchpadAvailability(launchpadID string, launchDate time.Time) (bool, error)
-	CheckDestinationSchedule(destinationID int64, launchpadID string, launchDate time.Time) (bool, error)
+	// CreateBooking stores a new booking in its initial status, confirmed
+	// unless booking.Status says otherwise. Its promo code, if any, is
+	// redeemed with it; a *models.PromoError is returned when the code
+	// cannot be redeemed. A booking with a HoldToken takes a seat of that hold,
+	// or gets a *models.HoldError; any other returns ErrNoSeats when the
+	// bookings and holds of its flight leave no seat of capacity.
+	CreateBooking(ctx context.Context, booking *models.Booking, capacity int) error
+	// CreateBookings stores several new bookings like CreateBooking, all or
+	// none of them.
+	CreateBookings(ctx context.Context, bookings []*models.Booking, capacity int) error
+	// GetBookings returns the bookings matching filter.
+	GetBookings(ctx context.Context, filter models.BookingFilter) ([]models.Booking, error)
+	// ExportBookings calls fn with every booking matching filter, in the
+	// order of their IDs, reading them from a cursor in batches rather than
+	// all at once. It stops at the first error fn returns.
+	ExportBookings(ctx context.Context, filter models.BookingFilter, fn func(*models.Booking) error) error
+	// GetBooking returns ErrNotFound when the booking does not exist.
+	GetBooking(ctx context.Context, id int) (*models.Booking, error)
+	// GetBookingByCode finds a booking by its public code, in any case. It
+	// returns ErrNotFound when no booking has the code.
+	GetBookingByCode(ctx context.Context, code string) (*models.Booking, error)
+	// UpdateBookingStatus moves a booking to a new status and records when
+	// it did. The reason is kept for disruptions. It returns ErrNotFound for
+	// an unknown booking and a *models.TransitionError for a change the
+	// state machine does not allow.
+	UpdateBookingStatus(ctx context.Context, id int, status models.BookingStatus, reason string) (*models.Booking, error)
+	// CancelBooking cancels a booking like UpdateBookingStatus, gives up the
+	// payments it still waits for and queues the refund of every captured
+	// payment, less what was already refunded of it, on the terms decided
+	// for the booking as it was before the cancellation.
+	CancelBooking(ctx context.Context, id int, terms func(*models.Booking) models.RefundTerms) (*models.Booking, error)
+	// DisruptBooking moves a booking to disrupted like UpdateBookingStatus,
+	// keeping the reason and the source of the disruption.
+	DisruptBooking(ctx context.Context, id int, reason, source string) (*models.Booking, error)
+	// RebookBooking moves a booking to rebooked and stores its confirmed
+	// replacement in one transaction, moving the payments over to it. It
+	// fails like UpdateBookingStatus, or with ErrNoSeats when the new
+	// flight has no seat left of capacity.
+	RebookBooking(ctx context.Context, id int, replacement *models.Booking, capacity int) error
+	// ExpirePendingBookings moves up to a batch of the bookings still
+	// pending that were created before the given time, or whose hold from
+	// the waitlist ended, to expired, giving up their payments and promo
+	// codes, and returns how many it expired.
+	ExpirePendingBookings(ctx context.Context, before time.Time) (int, error)
+
+	// CreatePayment stores a new pending payment of a booking.
+	CreatePayment(ctx context.Context, payment *models.Payment) error
+	// GetPayments returns the payments of a booking with their refunds,
+	// oldest first.
+	GetPayments(ctx context.Context, bookingID int) ([]models.Payment, error)
+	// StartCapture marks an authorized payment capturing before its
+	// capture is sent to the provider, so that a capture whose outcome is
+	// lost is retried from retryAt, and returns it. A payment that is
+	// capturing already is returned as it is. It returns ErrNotFound for
+	// an unknown payment, and ErrPaymentClosed for a captured or failed
+	// one or when the booking is no longer pending, in which case the
+	// payment fails without a capture.
+	StartCapture(ctx context.Context, id int64, providerRef string, retryAt time.Time) (*models.Payment, error)
+	// ConfirmPayment marks a capturing payment captured and confirms its
+	// booking. Should the booking have been cancelled meanwhile, the
+	// payment is refunded in full instead and ErrPaymentClosed returned. A
+	// payment captured before is left as it is. It returns ErrNotFound for
+	// an unknown payment, and ErrPaymentClosed for one that is not
+	// capturing or was refunded.
+	ConfirmPayment(ctx context.Context, id int64) (*models.Booking, error)
+	// FailPayment marks a pending payment failed and cancels its booking
+	// while that is pending, giving up its promo code. A payment failed
+	// before is left as it is. It returns ErrNotFound for an unknown
+	// payment and ErrPaymentClosed for a capturing or captured one.
+	FailPayment(ctx context.Context, id int64, providerRef, reason string) (*models.Booking, error)
+	// ClaimCaptures returns up to limit capturing payments that are due to
+	// be tried again, and keeps them from being claimed again for lease.
+	ClaimCaptures(ctx context.Context, limit int, lease time.Duration) ([]models.Payment, error)
+	// RecordCaptureAttempt stores the outcome of a failed capture of a
+	// capturing payment: its status, attempts, next attempt and failure
+	// reason.
+	RecordCaptureAttempt(ctx context.Context, payment *models.Payment) error
+	// ClaimRefunds returns up to limit pending refunds that are due, with
+	// their payment, and keeps them from being claimed again for lease.
+	ClaimRefunds(ctx context.Context, limit int, lease time.Duration) ([]models.DueRefund, error)
+	// RecordRefundAttempt stores the outcome of an attempt: the status,
+	// attempts, next attempt, last error and provider ID of the refund.
+	RecordRefundAttempt(ctx context.Context, refund *models.Refund) error
+	// GetBookingEvents returns the audit log of a booking, oldest first.
+	// Every change made through this service is logged with the actor
+	// carried by its context.
+	GetBookingEvents(ctx context.Context, bookingID int) ([]models.BookingEvent, error)
+	CheckDestinationSchedule(ctx context.Context, destinationID int64, launchpadID string, launchDate time.Time) (bool, error)
+	// GetDestinationIDs returns the IDs of all destinations in rotation order.
+	GetDestinationIDs(ctx context.Context) ([]int64, error)
+	// GetDestinationName returns ErrNotFound when the destination does not
+	// exist.
+	GetDestinationName(ctx context.Context, id int64) (string, error)
+	// GetDestination returns ErrNotFound when the destination does not
+	// exist.
+	GetDestination(ctx context.Context, id int64) (*models.Destination, error)
+
+	// GetUpcomingBookings returns the active bookings launching on or
+	// after from.
+	GetUpcomingBookings(ctx context.Context, from time.Time) ([]models.Booking, error)
+
+	// GetBookingsOnLaunchpad returns the active bookings launching from the
+	// launchpad on the days from from to to, both included.
+	GetBookingsOnLaunchpad(ctx context.Context, launchpadID string, from, to time.Time) ([]models.Booking, error)
+
+	// GetBlackouts returns the blackouts on the launchpad that overlap the
+	// days from from to to, both included.
+	GetBlackouts(ctx context.Context, launchpadID string, from, to time.Time) ([]models.Blackout, error)
+	// ListBlackouts returns all blackouts, or those of one launchpad when
+	// launchpadID is not empty.
+	ListBlackouts(ctx context.Context, launchpadID string) ([]models.Blackout, error)
+	// GetBlackout returns ErrNotFound when the blackout does not exist.
+	GetBlackout(ctx context.Context, id int) (*models.Blackout, error)
+	CreateBlackout(ctx context.Context, blackout *models.Blackout) error
+	// UpdateBlackout returns ErrNotFound when the blackout does not exist.
+	UpdateBlackout(ctx context.Context, blackout *models.Blackout) error
+	// DeleteBlackout returns ErrNotFound when the blackout does not exist.
+	DeleteBlackout(ctx context.Context, id int) error
+
+	ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error)
+	// GetWebhook returns ErrNotFound when the subscription does not exist.
+	GetWebhook(ctx context.Context, id int) (*models.WebhookSubscription, error)
+	CreateWebhook(ctx context.Context, webhook *models.WebhookSubscription) error
+	// UpdateWebhook changes the URL, event types and active flag, never the
+	// secret. It returns ErrNotFound when the subscription does not exist.
+	UpdateWebhook(ctx context.Context, webhook *models.WebhookSubscription) error
+	// DeleteWebhook removes the subscription and its deliveries. It returns
+	// ErrNotFound when the subscription does not exist.
+	DeleteWebhook(ctx context.Context, id int) error
+	// ListWebhookDeliveries returns the latest deliveries to a subscription,
+	// newest first.
+	ListWebhookDeliveries(ctx context.Context, subscriptionID int, limit int) ([]models.WebhookDelivery, error)
+
+	ListPromoCodes(ctx context.Context) ([]models.PromoCode, error)
+	// GetPromoCode returns ErrNotFound when the promo code does not exist.
+	GetPromoCode(ctx context.Context, id int) (*models.PromoCode, error)
+	// GetPromoCodeByCode finds a promo code by its code, in any case. It
+	// returns ErrNotFound when no promo code has the code.
+	GetPromoCodeByCode(ctx context.Context, code string) (*models.PromoCode, error)
+	// CreatePromoCode returns ErrPromoCodeTaken when the code exists.
+	CreatePromoCode(ctx context.Context, code *models.PromoCode) error
+	// UpdatePromoCode changes everything but the code and its redemptions.
+	// It returns ErrNotFound when the promo code does not exist.
+	UpdatePromoCode(ctx context.Context, code *models.PromoCode) error
+	// DeletePromoCode returns ErrNotFound when the promo code does not
+	// exist and ErrPromoCodeRedeemed when it was ever redeemed.
+	DeletePromoCode(ctx context.Context, id int) error
+	// ListPromoRedemptions returns the redemptions of a promo code, oldest
+	// first, released ones included.
+	ListPromoRedemptions(ctx context.Context, promoCodeID int) ([]models.PromoRedemption, error)
+	// GetPromoReport returns the redemptions, discounts and revenue of
+	// every promo code, counting the redemptions made from from until to.
+	// A zero time leaves that end open.
+	GetPromoReport(ctx context.Context, from, to time.Time) ([]models.PromoReport, error)
+
+	// JoinWaitlist adds the passenger to the end of the waitlist of the
+	// flight and fills in the entry with its position. It returns
+	// ErrAlreadyWaiting when the passenger is waiting for the flight.
+	JoinWaitlist(ctx context.Context, entry *models.WaitlistEntry) error
+	// GetWaitlistEntry returns ErrNotFound when the entry does not exist.
+	GetWaitlistEntry(ctx context.Context, id int) (*models.WaitlistEntry, error)
+	// GetWaitlistEntryByCode finds a waitlist entry by its public code, in
+	// any case. It returns ErrNotFound when no entry has the code.
+	GetWaitlistEntryByCode(ctx context.Context, code string) (*models.WaitlistEntry, error)
+	// ListWaitlist returns the waitlist entries of every status, or those
+	// of one launchpad or launch date when given, in the order they joined.
+	ListWaitlist(ctx context.Context, launchpadID string, launchDate time.Time) ([]models.WaitlistEntry, error)
+	// LeaveWaitlist withdraws a waiting entry. It returns ErrNotFound for
+	// an unknown entry and ErrNotWaiting, with the entry, for one that is
+	// no longer waiting.
+	LeaveWaitlist(ctx context.Context, id int) (*models.WaitlistEntry, error)
+	// GetWaitlistFlights returns the flights launching on or after from
+	// that passengers are waiting for.
+	GetWaitlistFlights(ctx context.Context, from time.Time) ([]models.WaitlistFlight, error)
+	// ExpireWaitlist closes the entries still waiting for flights that
+	// launched before the given day, and returns how many it closed.
+	ExpireWaitlist(ctx context.Context, before time.Time) (int, error)
+	// PromoteWaitlist offers the seats left of seats on the flight to its
+	// waiting entries, first come first served: each gets a pending booking
+	// held until holdUntil, priced by price with the seats sold before it,
+	// and an email. It returns the promoted entries.
+	PromoteWaitlist(ctx context.Context, flight models.WaitlistFlight, seats int, holdUntil time.Time, price func(booking *models.Booking, sold int) error) ([]models.WaitlistEntry, error)
+
+	// CreateHold reserves hold.Seats seats on the flight until
+	// hold.ExpiresAt and fills in the generated fields. It returns
+	// ErrNoSeats when the bookings and holds of the flight leave fewer
+	// than that of capacity.
+	CreateHold(ctx context.Context, hold *models.SeatHold, capacity int) error
+	// GetHold finds a hold by its token, in any case. It returns
+	// ErrNotFound when no hold has the token.
+	GetHold(ctx context.Context, token string) (*models.SeatHold, error)
+	// ReleaseHold gives up the seats left of the active hold with the
+	// token. It returns ErrNotFound for an unknown token and ErrHoldClosed,
+	// with the hold, for one that is no longer active.
+	ReleaseHold(ctx context.Context, token string) (*models.SeatHold, error)
+	// CountHeldSeats returns the seats held and not yet booked on the
+	// flight.
+	CountHeldSeats(ctx context.Context, launchpadID string, launchDate time.Time) (int, error)
+	// ExpireHolds closes the active holds that ran out, and returns how
+	// many it closed.
+	ExpireHolds(ctx context.Context) (int, error)
+
+	// FanOutOutbox turns up to limit unpublished outbox messages into one
+	// pending delivery per matching active subscription, and returns how
+	// many messages it published.
+	FanOutOutbox(ctx context.Context, limit int) (int, error)
+	// ClaimWebhookDeliveries returns up to limit pending deliveries that are
+	// due, and keeps them from being claimed again for lease.
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error)
+	// RecordWebhookAttempt stores the outcome of an attempt: the status,
+	// attempts, next attempt and last response of the delivery.
+	RecordWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery) error
+
+	// ClaimNotifications returns up to limit pending notifications that are
+	// due, with their booking, and keeps them from being claimed again for
+	// lease.
+	ClaimNotifications(ctx context.Context, limit int, lease time.Duration) ([]models.DueNotification, error)
+	// RecordNotificationAttempt stores the outcome of an attempt: the
+	// status, attempts, next attempt and last error of the notification.
+	RecordNotificationAttempt(ctx context.Context, notification *models.Notification) error
+
+	// PublishManifest writes the manifest to the outbox unless one was
+	// already published for its launchpad and launch date, and reports
+	// whether it did.
+	PublishManifest(ctx context.Context, manifest *models.Manifest) (bool, error)
+
+	// GetOutboxMessages returns the outbox messages matching filter in
+	// stream order, see models.OutboxPosition. Messages of transactions
+	// still running, or younger than one, are held back. The outbox is the
+	// persisted log of the event stream.
+	GetOutboxMessages(ctx context.Context, filter models.OutboxFilter) ([]models.OutboxMessage, error)
+	// LatestOutboxPosition returns the position of the newest outbox
+	// message GetOutboxMessages returns, or the zero position.
+	LatestOutboxPosition(ctx context.Context) (models.OutboxPosition, error)
+	// ListenOutbox calls notify whenever messages are written to the
+	// outbox, by any replica, until ctx is done or the connection fails.
+	ListenOutbox(ctx context.Context, notify func()) error
 }
 
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("database: not found")
+
 type service struct {
-	db *sql.DB
+	db    *sql.DB
+	name  string
+	clock clock.Clock
 }
 
-var (
-	database     = os.Getenv("DB_DATABASE")
-	password     = os.Getenv("DB_PASSWORD")
-	username     = os.Getenv("DB_USERNAME")
-	port         = os.Getenv("DB_PORT")
-	host         = os.Getenv("DB_HOST")
-	schema       = os.Getenv("DB_SCHEMA")
-	SpaceXAPIURL = os.Getenv("SPACEXAPIURL")
-	dbInstance   *service
-)
+// Option configures a service built by New.
+type Option func(*service)
 
-func New() Service {
-	// Reuse Connection
-	if dbInstance != nil {
-		return dbInstance
+// WithClock sets the clock used to timestamp records. It defaults to the
+// system clock.
+func WithClock(c clock.Clock) Option {
+	return func(s *service) {
+		if c != nil {
+			s.clock = c
+		}
 	}
-	connStr := fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s",
-		username, password, host, port, database, schema,
-	)
-	db, err := sql.Open("pgx", connStr)
+}
+
+// New opens a connection pool for the configured database. Every call
+// returns an independent service that must be closed by the caller.
+func New(cfg *config.Config, opts ...Option) (Service, error) {
+	db, err := sql.Open("pgx", cfg.Database.ConnString())
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	dbInstance = &service{
-		db: db,
+	s := &service{
+		db:    db,
+		name:  cfg.Database.Name,
+		clock: clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
-	return dbInstance
+	return s, nil
 }
 
 // Health checks the health of the database connection by pinging the database.
@@ -131,117 +366,36 @@ func (s *service) Health() map[string]string {
 // If the connection is successfully closed, it returns nil.
 // If an error occurs while closing the connection, it returns the error.
 func (s *service) Close() error {
-	log.Printf("Disconnected from database: %s", database)
+	log.Printf("Disconnected from database: %s", s.name)
 	return s.db.Close()
 }
 
-func (s *service) CreateBooking(booking *models.Booking) error {
-	query := `
-		INSERT INTO bookings (first_name, last_name, gender, birthday, launchpad_id, destination_id, launch_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id
-	`
-	var id int
-	err := s.db.QueryRow(
-		query,
-		booking.FirstName,
-		booking.LastName,
-		booking.Gender,
-		booking.Birthday,
-		booking.LaunchpadID,
-		booking.DestinationID,
-		booking.LaunchDate,
-	).Scan(&id)
-	if err != nil {
-		return err
-	}
-	booking.ID = id
-	return nil
-}
-
-func (s *service) GetAllBookings() ([]models.Booking, error) {
-	query := `
-		SELECT id, first_name, last_name, gender, birthday, launchpad_id, destination_id, launch_date
-		FROM bookings
-	`
-	rows, err := s.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var bookings []models.Booking
-	for rows.Next() {
-		var booking models.Booking
-		err := rows.Scan(
-			&booking.ID,
-			&booking.FirstName,
-			&booking.LastName,
-			&booking.Gender,
-			&booking.Birthday,
-			&booking.LaunchpadID,
-			&booking.DestinationID,
-			&booking.LaunchDate,
-		)
-		if err != nil {
-			return nil, err
-		}
-		bookings = append(bookings, booking)
-	}
-	return bookings, nil
-}
-
-func (s *service) CheckLaunchpadAvailability(launchpadID string, launchDate time.Time) (bool, error) {
-	var launches []struct {
-		Launchpad string `json:"launchpad"`
-		Name      string `json:"name"`
-		DateLocal string `json:"date_local"`
-		ID        string `json:"id"`
-	}
-	resp, err := http.Get(SpaceXAPIURL)
+func (s *service) CheckDestinationSchedule(ctx context.Context, destinationID int64, launchpadID string, launchDate time.Time) (bool, error) {
+	// Get list of destinations
+	destinationIDs, err := s.GetDestinationIDs(ctx)
 	if err != nil {
 		return false, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	expectedDestinationID, err := ExpectedDestination(destinationIDs, launchDate)
 	if err != nil {
 		return false, err
 	}
 
-	err = json.Unmarshal(body, &launches)
-	if err != nil {
-		return false, err
-	}
-	// Log the launches for debugging
-	log.Printf("Received %d launches", len(launches))
-	for i, launch := range launches {
-		log.Printf("Launch %d: %+v", i, launch)
-	}
-
-	for _, launch := range launches {
-		dateLocal, err := time.Parse(time.RFC3339, launch.DateLocal)
-		if err != nil {
-			log.Printf("Error parsing dateLocal for launch %s: %v", launch.ID, err)
-			continue // Skip this launch due to invalid date
-		}
+	log.Printf("destinationID: %d, expectedDestinationID: %d", destinationID, expectedDestinationID)
 
-		if launch.Launchpad == launchpadID && dateLocal.Format("2006-01-02") == launchDate.Format("2006-01-02") {
-			// Launchpad is not available on this date
-			return false, nil
-		}
+	if destinationID != expectedDestinationID {
+		return false, nil
 	}
 
-	// No conflicting launches found
 	return true, nil
 }
 
-func (s *service) CheckDestinationSchedule(destinationID int64, launchpadID string, launchDate time.Time) (bool, error) {
-	// Get list of destinations
-	rows, err := s.db.Query(`SELECT id FROM destinations ORDER BY id`)
+func (s *service) GetDestinationIDs(ctx context.Context) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM destinations ORDER BY id`)
 	if err != nil {
 		log.Printf("Error executing query: %v", err)
-		return false, err
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -251,32 +405,49 @@ func (s *service) CheckDestinationSchedule(destinationID int64, launchpadID stri
 		err := rows.Scan(&id)
 		if err != nil {
 			log.Printf("Error scanning row: %v", err)
-			return false, err
+			return nil, err
 		}
 		destinationIDs = append(destinationIDs, id)
 	}
 
 	if err := rows.Err(); err != nil {
 		log.Printf("Row iteration error: %v", err)
-		return false, err
+		return nil, err
 	}
 
-	log.Printf("Destination IDs: %v", destinationIDs)
+	return destinationIDs, nil
+}
 
-	if len(destinationIDs) == 0 {
-		return false, fmt.Errorf("no destinations available")
+func (s *service) GetDestination(ctx context.Context, id int64) (*models.Destination, error) {
+	d := models.Destination{ID: id}
+	err := s.db.QueryRowContext(ctx, `SELECT name, base_price_cents FROM destinations WHERE id = $1`, id).Scan(&d.Name, &d.BasePrice)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
 
-	weekday := (int(launchDate.Weekday()) + 6) % 7 // Monday=1, ..., Sunday=7
-	index := weekday % len(destinationIDs)
-	expectedDestinationID := destinationIDs[index]
-
-	log.Printf("Weekday: %d, Index: %d, Expected Destination ID: %d", weekday, index, expectedDestinationID)
-	log.Printf("destinationID: %d, expectedDestinationID: %d", destinationID, expectedDestinationID)
+func (s *service) GetDestinationName(ctx context.Context, id int64) (string, error) {
+	var name string
+	err := s.db.QueryRowContext(ctx, `SELECT name FROM destinations WHERE id = $1`, id).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return name, err
+}
 
-	if destinationID != expectedDestinationID {
-		return false, nil
+// ExpectedDestination returns the destination flown to on launchDate. The
+// destinations, ordered by ID, rotate through the days of the week starting
+// on Monday, the same for every launchpad.
+func ExpectedDestination(destinationIDs []int64, launchDate time.Time) (int64, error) {
+	if len(destinationIDs) == 0 {
+		return 0, fmt.Errorf("no destinations available")
 	}
 
-	return true, nil
+	weekday := (int(launchDate.Weekday()) + 6) % 7 // Monday=0, ..., Sunday=6
+	index := weekday % len(destinationIDs)
+	return destinationIDs[index], nil
 }
@@ -1,9 +1,11 @@
 package database
 
 import (
-	"encoding/json"
-	"net/http"
-	"net/http/httptest"
+	"context"
+	"database/sql/driver"
+	"space-booking/internal/actor"
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
 	"testing"
 	"time"
 
@@ -12,40 +14,6 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// TestCheckLaunchpadAvailability tests the CheckLaunchpadAvailability function
-func TestCheckLaunchpadAvailability(t *testing.T) {
-	// Mock SpaceX API response
-	mockSpaceXResponse := []map[string]interface{}{
-		{
-			"id":         "launch_1",
-			"name":       "Test Launch",
-			"date_local": "2049-12-25T00:00:00Z", // Use UTC time
-			"launchpad":  "test_launchpad",
-		},
-	}
-
-	// Setup mock server
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(mockSpaceXResponse)
-	}))
-	defer server.Close()
-
-	// Override the SpaceX API URL with the mock server URL
-	SpaceXAPIURL = server.URL
-
-	// Initialize the database service
-	s := &service{}
-
-	// Test data
-	launchpadID := "test_launchpad"
-	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
-
-	// Call the method
-	isAvailable, err := s.CheckLaunchpadAvailability(launchpadID, launchDate)
-	assert.NoError(t, err)
-	assert.False(t, isAvailable, "Expected launchpad to be unavailable due to conflicting launch")
-}
-
 // TestCheckDestinationSchedule tests the CheckDestinationSchedule function
 func TestCheckDestinationSchedule(t *testing.T) {
 	// Create a sqlmock database connection
@@ -78,7 +46,7 @@ func TestCheckDestinationSchedule(t *testing.T) {
 	require.NoError(t, err)
 
 	// Call the method to check the destination schedule
-	isValid, err := s.CheckDestinationSchedule(destinationID, launchpadID, launchDate)
+	isValid, err := s.CheckDestinationSchedule(context.Background(), destinationID, launchpadID, launchDate)
 	assert.NoError(t, err)
 	assert.True(t, isValid, "Expected destination schedule to be valid")
 	// Ensure all expectations were met
@@ -86,74 +54,416 @@ func TestCheckDestinationSchedule(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-// Additional test for when the launchpad is available
-func TestCheckLaunchpadAvailability_NoConflict(t *testing.T) {
-	// Mock SpaceX API response with no conflicting launches
-	mockSpaceXResponse := []map[string]interface{}{
-		{
-			"id":         "launch_2",
-			"name":       "Another Launch",
-			"date_local": "2049-12-26T00:00:00Z", // Different date
-			"launchpad":  "test_launchpad",
-		},
-	}
+// TestGetBlackouts tests the GetBlackouts function
+func TestGetBlackouts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
 
-	// Setup mock server
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(mockSpaceXResponse)
-	}))
-	defer server.Close()
+	s := &service{db: db}
 
-	// Override the SpaceX API URL with the mock server URL
-	SpaceXAPIURL = server.URL
+	from := time.Date(2049, time.December, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2049, time.December, 31, 0, 0, 0, 0, time.UTC)
+	startsOn := time.Date(2049, time.December, 24, 0, 0, 0, 0, time.UTC)
+	endsOn := time.Date(2049, time.December, 26, 0, 0, 0, 0, time.UTC)
 
-	// Initialize the database service
-	s := &service{}
+	mock.ExpectQuery("SELECT id, launchpad_id, starts_on, ends_on, reason FROM launchpad_blackouts").
+		WithArgs("test_launchpad", from, to).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "launchpad_id", "starts_on", "ends_on", "reason"}).
+				AddRow(1, "test_launchpad", startsOn, endsOn, "Maintenance"),
+		)
 
-	// Test data
-	launchpadID := "test_launchpad"
+	blackouts, err := s.GetBlackouts(context.Background(), "test_launchpad", from, to)
+	require.NoError(t, err)
+	require.Len(t, blackouts, 1)
+	assert.Equal(t, "Maintenance", blackouts[0].Reason)
+	assert.Equal(t, endsOn, blackouts[0].EndsOn)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// bookingRows returns rows of bookingColumns holding one booking.
+func bookingRows(id int, status models.BookingStatus) *sqlmock.Rows {
 	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2049, time.December, 1, 9, 30, 0, 0, time.UTC)
+	return sqlmock.NewRows([]string{
+		"id", "first_name", "last_name", "gender", "birthday", "launchpad_id", "destination_id", "launch_date",
+		"status", "created_at", "confirmed_at", "checked_in_at", "boarded_at", "cancelled_at", "disrupted_at", "rebooked_at", "flown_at",
+		"disruption_reason", "rebooked_from", "email", "code", "price_cents", "currency",
+		"expired_at", "disrupted_by", "promo_code", "discount_cents", "hold_until", "hold_id",
+	}).AddRow(
+		id, "Test", "User", "Non-binary", time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), "test_launchpad", int64(6), launchDate,
+		string(status), createdAt, createdAt, nil, nil, nil, nil, nil, nil,
+		"", nil, "", "K7QX2MWP9D", int64(120000000), "USD",
+		nil, "", "", int64(0), nil, nil,
+	)
+}
 
-	// Call the method
-	isAvailable, err := s.CheckLaunchpadAvailability(launchpadID, launchDate)
-	assert.NoError(t, err)
-	assert.True(t, isAvailable, "Expected launchpad to be available since there is no conflicting launch")
-}
-
-// Additional test for invalid dates in the SpaceX API response
-func TestCheckLaunchpadAvailability_InvalidDate(t *testing.T) {
-	// Save the original value of spaceXAPIURL
-	origURL := SpaceXAPIURL
-	defer func() { SpaceXAPIURL = origURL }()
-
-	// Mock SpaceX API response with invalid date
-	mockSpaceXResponse := []map[string]interface{}{
-		{
-			"id":         "launch_3",
-			"name":       "Invalid Date Launch",
-			"date_local": "invalid-date-format",
-			"launchpad":  "test_launchpad",
-		},
+// TestUpdateBookingStatusRejectsTransition tests that a final status is kept
+func TestUpdateBookingStatusRejectsTransition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := &service{db: db, clock: clock.NewFake(time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC))}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM bookings WHERE id = \\$1 FOR UPDATE").
+		WithArgs(7).
+		WillReturnRows(bookingRows(7, models.StatusFlown))
+	mock.ExpectRollback()
+
+	_, err = s.UpdateBookingStatus(context.Background(), 7, models.StatusCancelled, "")
+	var terr *models.TransitionError
+	require.ErrorAs(t, err, &terr)
+	assert.Equal(t, models.StatusFlown, terr.From)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestUpdateBookingStatusRecordsEvent tests that the change and its actor
+// are logged and published in the same transaction
+func TestUpdateBookingStatusRecordsEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC)
+	s := &service{db: db, clock: clock.NewFake(now)}
+	ctx := actor.With(context.Background(), actor.Actor{ID: "client:agency-42", RequestID: "req-1"})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM bookings WHERE id = \\$1 FOR UPDATE").
+		WithArgs(7).
+		WillReturnRows(bookingRows(7, models.StatusDisrupted))
+	mock.ExpectQuery("UPDATE bookings SET status = \\$2, cancelled_at = \\$3").
+		WithArgs(7, models.StatusCancelled, now, "").
+		WillReturnRows(bookingRows(7, models.StatusCancelled))
+	mock.ExpectExec("INSERT INTO booking_events").
+		WithArgs(7, models.EventCancelled, "client:agency-42", "req-1", sqlmock.AnyArg(), sqlmock.AnyArg(), now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO outbox").
+		WithArgs("booking.cancelled", 7, sqlmock.AnyArg(), now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(42)))
+	mock.ExpectExec("SELECT pg_notify").
+		WithArgs(OutboxChannel, "42").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	booking, err := s.UpdateBookingStatus(ctx, 7, models.StatusCancelled, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, booking.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCancelBookingQueuesRefund tests that the refund is queued with the
+// cancellation, less what was refunded before
+func TestCancelBookingQueuesRefund(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC)
+	s := &service{db: db, clock: clock.NewFake(now)}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM bookings WHERE id = \\$1 FOR UPDATE").
+		WithArgs(7).
+		WillReturnRows(bookingRows(7, models.StatusDisrupted))
+	mock.ExpectQuery("UPDATE bookings SET status = \\$2, cancelled_at = \\$3").
+		WithArgs(7, models.StatusCancelled, now, "").
+		WillReturnRows(bookingRows(7, models.StatusCancelled))
+	mock.ExpectExec("INSERT INTO booking_events").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO outbox").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(42)))
+	mock.ExpectExec("SELECT pg_notify").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE payments SET status = \\$2").
+		WithArgs(7, models.PaymentFailed, sqlmock.AnyArg(), now, models.PaymentPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM payments p LEFT JOIN refunds r").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amount_cents", "refunded"}).
+			AddRow(int64(3), int64(120000000), int64(20000000)))
+	mock.ExpectExec("INSERT INTO refunds").
+		WithArgs(int64(3), int64(100000000), "Flight was disrupted.", "spacex", now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	booking, err := s.CancelBooking(context.Background(), 7, func(b *models.Booking) models.RefundTerms {
+		assert.Equal(t, models.StatusDisrupted, b.Status, "Expected the terms of the booking before it was cancelled")
+		return models.RefundTerms{Share: 1, Reason: "Flight was disrupted.", DisruptedBy: "spacex"}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, booking.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// paymentRows returns payment 3 of booking 7 as read by paymentColumns.
+func paymentRows(status string, now time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "booking_id", "provider", "provider_ref", "amount_cents", "currency", "status",
+		"failure_reason", "created_at", "updated_at", "captured_at", "capture_attempts", "next_capture_at",
+	}).AddRow(int64(3), 7, "fake", "pay_1", int64(120000000), "USD", status, "", now, now, nil, 0, nil)
+}
+
+// expectLockPayment expects payment 3 and booking 7 to be locked.
+func expectLockPayment(mock sqlmock.Sqlmock, booking models.BookingStatus, payment string, now time.Time) {
+	mock.ExpectQuery("SELECT booking_id FROM payments WHERE id = \\$1").
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"booking_id"}).AddRow(7))
+	mock.ExpectQuery("FROM bookings WHERE id = \\$1 FOR UPDATE").
+		WithArgs(7).
+		WillReturnRows(bookingRows(7, booking))
+	mock.ExpectQuery("FROM payments p WHERE p.id = \\$1 FOR UPDATE").
+		WithArgs(int64(3)).
+		WillReturnRows(paymentRows(payment, now))
+}
+
+// TestStartCaptureCommitsFirst tests that a payment is marked capturing,
+// and the transaction committed, before the capture is sent
+func TestStartCaptureCommitsFirst(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC)
+	s := &service{db: db, clock: clock.NewFake(now)}
+	retryAt := now.Add(30 * time.Second)
+
+	mock.ExpectBegin()
+	expectLockPayment(mock, models.StatusPending, models.PaymentPending, now)
+	mock.ExpectQuery("UPDATE payments p SET status = \\$2").
+		WithArgs(int64(3), models.PaymentCapturing, "pay_1", retryAt, now).
+		WillReturnRows(paymentRows(models.PaymentCapturing, now))
+	mock.ExpectCommit()
+
+	p, err := s.StartCapture(context.Background(), 3, "pay_1", retryAt)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentCapturing, p.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestConfirmPaymentRefundsCancelledBooking tests that money taken for a
+// booking cancelled during the capture is recorded and given back
+func TestConfirmPaymentRefundsCancelledBooking(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC)
+	s := &service{db: db, clock: clock.NewFake(now)}
+
+	mock.ExpectBegin()
+	expectLockPayment(mock, models.StatusCancelled, models.PaymentCapturing, now)
+	mock.ExpectExec("UPDATE payments SET status = \\$2, next_capture_at = NULL").
+		WithArgs(int64(3), models.PaymentCaptured, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO refunds").
+		WithArgs(int64(3), int64(120000000), uncapturedReason, "", now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	_, err = s.ConfirmPayment(context.Background(), 3)
+	assert.ErrorIs(t, err, ErrPaymentClosed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// arrayConverter passes string slices through as pgx does.
+type arrayConverter struct{}
+
+func (arrayConverter) ConvertValue(v any) (driver.Value, error) {
+	if s, ok := v.([]string); ok {
+		return s, nil
 	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
 
-	// Setup mock server
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(mockSpaceXResponse)
-	}))
-	defer server.Close()
+// TestExportBookings tests that bookings are read from a cursor until a
+// batch comes back short
+func TestExportBookings(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	require.NoError(t, err)
+	defer db.Close()
 
-	// Override the SpaceX API URL with the mock server URL
-	SpaceXAPIURL = server.URL
+	s := &service{db: db, clock: clock.NewFake(time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC))}
 
-	// Initialize the database service
-	s := &service{}
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE export_bookings NO SCROLL CURSOR FOR SELECT .* FROM bookings").
+		WithArgs([]string{"confirmed"}, "test_launchpad", int64(0), nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FETCH FORWARD 1000 FROM export_bookings").
+		WillReturnRows(bookingRows(7, models.StatusConfirmed))
+	mock.ExpectCommit()
 
-	// Test data
-	launchpadID := "test_launchpad"
+	var ids []int
+	filter := models.BookingFilter{Statuses: []models.BookingStatus{models.StatusConfirmed}, LaunchpadID: "test_launchpad"}
+	err = s.ExportBookings(context.Background(), filter, func(b *models.Booking) error {
+		ids = append(ids, b.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{7}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectSeatsTaken expects the flight of a booking to be locked and its
+// seats counted.
+func expectSeatsTaken(mock sqlmock.Sqlmock, taken int) {
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs("test_launchpad", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\(SELECT COUNT\\(\\*\\) FROM bookings").
+		WithArgs("test_launchpad", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"taken"}).AddRow(taken))
+}
+
+// TestCreateBookingChecksCapacity tests that a booking is refused once the
+// bookings and holds of its flight fill it
+func TestCreateBookingChecksCapacity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := &service{db: db, clock: clock.NewFake(time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC))}
+	booking := func() *models.Booking {
+		return &models.Booking{
+			FirstName: "Test", LastName: "User", LaunchpadID: "test_launchpad", DestinationID: 6,
+			LaunchDate: time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	mock.ExpectBegin()
+	expectSeatsTaken(mock, 50)
+	mock.ExpectRollback()
+	assert.ErrorIs(t, s.CreateBooking(context.Background(), booking(), 50), ErrNoSeats)
+
+	// Two bookings on a flight with one seat left are refused together.
+	mock.ExpectBegin()
+	expectSeatsTaken(mock, 49)
+	mock.ExpectRollback()
+	err = s.CreateBookings(context.Background(), []*models.Booking{booking(), booking()}, 50)
+	assert.ErrorIs(t, err, ErrNoSeats)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateBookingRedeemsPromo tests that a promo code is redeemed with
+// the booking, and that one used up rolls the booking back
+func TestCreateBookingRedeemsPromo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC)
+	s := &service{db: db, clock: clock.NewFake(now)}
+	promoRows := func(redemptions int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"id", "code", "kind", "value", "currency", "valid_from", "valid_until",
+			"destination_ids", "max_redemptions", "redemptions", "active", "created_at",
+		}).AddRow(4, "MARS10", models.PromoPercent, int64(10), "", nil, nil, []byte("[6]"), 2, redemptions, true, now)
+	}
+	booking := func() *models.Booking {
+		return &models.Booking{
+			FirstName: "Test", LastName: "User", Birthday: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+			LaunchpadID: "test_launchpad", DestinationID: 6, Status: models.StatusPending,
+			Price: 108000000, Currency: "USD", PromoCode: "MARS10", Discount: 12000000,
+		}
+	}
+
+	mock.ExpectBegin()
+	expectSeatsTaken(mock, 10)
+	mock.ExpectQuery("INSERT INTO bookings").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("FROM promo_codes WHERE code = \\$1 FOR UPDATE").
+		WithArgs("MARS10").
+		WillReturnRows(promoRows(1))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(4, "test|user|1990-01-01").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("INSERT INTO promo_redemptions").
+		WithArgs(4, 7, "test|user|1990-01-01", int64(12000000), "USD", now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE promo_codes SET redemptions = redemptions \\+ 1").
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO booking_events").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO outbox").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(42)))
+	mock.ExpectExec("SELECT pg_notify").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	require.NoError(t, s.CreateBooking(context.Background(), booking(), 50))
+
+	mock.ExpectBegin()
+	expectSeatsTaken(mock, 11)
+	mock.ExpectQuery("INSERT INTO bookings").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectQuery("FROM promo_codes WHERE code = \\$1 FOR UPDATE").
+		WithArgs("MARS10").
+		WillReturnRows(promoRows(2))
+	mock.ExpectRollback()
+	err = s.CreateBooking(context.Background(), booking(), 50)
+	var promoErr *models.PromoError
+	require.ErrorAs(t, err, &promoErr)
+	assert.Equal(t, "has been used up", promoErr.Reason)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJoinWaitlistAlreadyWaiting(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := &service{db: db, clock: clock.NewFake(time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC))}
+	mock.ExpectQuery("INSERT INTO waitlist_entries .* ON CONFLICT DO NOTHING").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err = s.JoinWaitlist(context.Background(), &models.WaitlistEntry{FirstName: "Test", LastName: "User"})
+	assert.ErrorIs(t, err, ErrAlreadyWaiting)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateBookingTakesHeldSeat(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2049, time.December, 2, 0, 0, 0, 0, time.UTC)
 	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	s := &service{db: db, clock: clock.NewFake(now)}
+	holdRows := func(expiresAt time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"id", "token", "launchpad_id", "destination_id", "launch_date", "seats", "booked", "status", "expires_at",
+			"created_at", "closed_at",
+		}).AddRow(5, "H7MQ2XK9RT", "test_launchpad", int64(6), launchDate, 2, 1, models.HoldActive, expiresAt, now, nil)
+	}
+	booking := func() *models.Booking {
+		return &models.Booking{
+			FirstName: "Test", LastName: "User", Birthday: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+			LaunchpadID: "test_launchpad", DestinationID: 6, LaunchDate: launchDate, Status: models.StatusPending,
+			Price: 120000000, Currency: "USD", HoldToken: "h7mq2xk9rt",
+		}
+	}
 
-	// Call the method
-	isAvailable, err := s.CheckLaunchpadAvailability(launchpadID, launchDate)
-	assert.NoError(t, err)
-	assert.True(t, isAvailable, "Expected launchpad to be available since the launch date is invalid")
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM seat_holds WHERE token = \\$1 FOR UPDATE").
+		WithArgs("H7MQ2XK9RT").
+		WillReturnRows(holdRows(now.Add(time.Minute)))
+	mock.ExpectExec("UPDATE seat_holds SET booked = booked \\+ 1").
+		WithArgs(5, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO bookings").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec("INSERT INTO booking_events").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO outbox").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(42)))
+	mock.ExpectExec("SELECT pg_notify").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	held := booking()
+	require.NoError(t, s.CreateBooking(context.Background(), held, 50))
+	require.NotNil(t, held.HoldID)
+	assert.Equal(t, 5, *held.HoldID)
+
+	// An expired hold books nothing.
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM seat_holds WHERE token = \\$1 FOR UPDATE").
+		WithArgs("H7MQ2XK9RT").
+		WillReturnRows(holdRows(now))
+	mock.ExpectRollback()
+	err = s.CreateBooking(context.Background(), booking(), 50)
+	var holdErr *models.HoldError
+	require.ErrorAs(t, err, &holdErr)
+	assert.Equal(t, "Seat hold has expired.", holdErr.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"space-booking/internal/actor"
+	"space-booking/internal/models"
+	"time"
+)
+
+// recordEvent appends a change of the booking to the audit log and to the
+// outbox, within the transaction making the change. The actor is taken
+// from ctx.
+func recordEvent(ctx context.Context, tx *sql.Tx, eventType models.BookingEventType, before, after *models.Booking, now time.Time) error {
+	var bookingID int
+	var beforeJSON, afterJSON []byte
+	var err error
+	if before != nil {
+		bookingID = before.ID
+		if beforeJSON, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		bookingID = after.ID
+		if afterJSON, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+
+	a := actor.From(ctx)
+	query := `
+		INSERT INTO booking_events (booking_id, type, actor, request_id, before, after, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+	`
+	_, err = tx.ExecContext(ctx, query, bookingID, eventType, a.ID, a.RequestID, nullJSON(beforeJSON), nullJSON(afterJSON), now)
+	if err != nil {
+		return err
+	}
+
+	payload := afterJSON
+	if payload == nil {
+		payload = beforeJSON
+	}
+	return publish(ctx, tx, models.OutboxType(eventType), bookingID, payload, now)
+}
+
+// nullJSON stores an absent document as NULL rather than an empty string,
+// which is not valid JSON.
+func nullJSON(doc []byte) any {
+	if doc == nil {
+		return nil
+	}
+	return string(doc)
+}
+
+func (s *service) GetBookingEvents(ctx context.Context, bookingID int) ([]models.BookingEvent, error) {
+	query := `
+		SELECT id, booking_id, type, actor, COALESCE(request_id, ''), before, after, created_at
+		FROM booking_events
+		WHERE booking_id = $1
+		ORDER BY id
+	`
+	rows, err := s.db.QueryContext(ctx, query, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.BookingEvent
+	for rows.Next() {
+		var e models.BookingEvent
+		var before, after []byte
+		err := rows.Scan(&e.ID, &e.BookingID, &e.Type, &e.Actor, &e.RequestID, &before, &after, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Before, e.After = before, after
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"space-booking/internal/models"
+	"time"
+)
+
+var (
+	// ErrNoSeats is returned when a flight has fewer free seats than a
+	// hold or the bookings being stored ask for.
+	ErrNoSeats = errors.New("database: not enough free seats")
+	// ErrHoldClosed is returned when releasing a hold that is no longer
+	// active.
+	ErrHoldClosed = errors.New("database: seat hold is closed")
+)
+
+// holdColumns are the columns scanned by scanHold, in order.
+const holdColumns = `id, token, launchpad_id, destination_id, launch_date, seats, booked, status, expires_at, created_at,
+	closed_at`
+
+func scanHold(row scanner) (*models.SeatHold, error) {
+	var h models.SeatHold
+	err := row.Scan(&h.ID, &h.Token, &h.LaunchpadID, &h.DestinationID, &h.LaunchDate, &h.Seats, &h.Booked, &h.Status,
+		&h.ExpiresAt, &h.CreatedAt, &h.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// lockFlight serializes the transactions that take seats on a flight
+// against its capacity until tx ends.
+func lockFlight(ctx context.Context, tx *sql.Tx, launchpadID string, launchDate time.Time) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1), $2::date - DATE '2000-01-01')`,
+		launchpadID, launchDate)
+	return err
+}
+
+// seatsTaken counts the seats of a flight taken at now: those of its
+// active bookings and those still held.
+func seatsTaken(ctx context.Context, tx *sql.Tx, launchpadID string, launchDate, now time.Time) (int, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM bookings
+				WHERE launchpad_id = $1 AND launch_date = $2 AND status IN ` + activeStatuses + `)
+			+ (SELECT COALESCE(SUM(seats - booked), 0) FROM seat_holds
+				WHERE launchpad_id = $1 AND launch_date = $2 AND status = 'active' AND expires_at > $3)
+	`
+	var taken int
+	err := tx.QueryRowContext(ctx, query, launchpadID, launchDate, now).Scan(&taken)
+	return taken, err
+}
+
+// claimSeats locks the flight until tx ends and returns ErrNoSeats unless
+// seats more fit in its capacity at now.
+func claimSeats(ctx context.Context, tx *sql.Tx, launchpadID string, launchDate time.Time, seats, capacity int, now time.Time) error {
+	if err := lockFlight(ctx, tx, launchpadID, launchDate); err != nil {
+		return err
+	}
+	taken, err := seatsTaken(ctx, tx, launchpadID, launchDate, now)
+	if err != nil {
+		return err
+	}
+	if taken+seats > capacity {
+		return ErrNoSeats
+	}
+	return nil
+}
+
+func (s *service) CreateHold(ctx context.Context, hold *models.SeatHold, capacity int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := s.clock.Now()
+	if err := claimSeats(ctx, tx, hold.LaunchpadID, hold.LaunchDate, hold.Seats, capacity, now); err != nil {
+		return err
+	}
+
+	token, err := models.NewBookingCode()
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO seat_holds (token, launchpad_id, destination_id, launch_date, seats, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	var id int
+	err = tx.QueryRowContext(ctx, query, token, hold.LaunchpadID, hold.DestinationID, hold.LaunchDate, hold.Seats,
+		hold.ExpiresAt, now).Scan(&id)
+	if err != nil {
+		return err
+	}
+	hold.ID = id
+	hold.Token = token
+	hold.Booked = 0
+	hold.Status = models.HoldActive
+	hold.CreatedAt = now
+	hold.ClosedAt = nil
+	return tx.Commit()
+}
+
+func (s *service) GetHold(ctx context.Context, token string) (*models.SeatHold, error) {
+	query := `SELECT ` + holdColumns + ` FROM seat_holds WHERE token = $1`
+	hold, err := scanHold(s.db.QueryRowContext(ctx, query, models.NormalizeBookingCode(token)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return hold, err
+}
+
+func (s *service) ReleaseHold(ctx context.Context, token string) (*models.SeatHold, error) {
+	query := `
+		UPDATE seat_holds
+		SET status = 'released', closed_at = $2
+		WHERE token = $1 AND status = 'active'
+	`
+	res, err := s.db.ExecContext(ctx, query, models.NormalizeBookingCode(token), s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	hold, err := s.GetHold(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return hold, ErrHoldClosed
+	}
+	return hold, nil
+}
+
+func (s *service) CountHeldSeats(ctx context.Context, launchpadID string, launchDate time.Time) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(seats - booked), 0)
+		FROM seat_holds
+		WHERE launchpad_id = $1 AND launch_date = $2 AND status = 'active' AND expires_at > $3
+	`
+	var held int
+	err := s.db.QueryRowContext(ctx, query, launchpadID, launchDate, s.clock.Now()).Scan(&held)
+	return held, err
+}
+
+func (s *service) ExpireHolds(ctx context.Context) (int, error) {
+	now := s.clock.Now()
+	query := `
+		UPDATE seat_holds
+		SET status = 'expired', closed_at = $1
+		WHERE status = 'active' AND expires_at <= $1
+	`
+	res, err := s.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// takeHeldSeat books a seat of the hold named by the token of booking in
+// tx, sets the booking's HoldID and converts the hold once all its seats
+// are booked. It returns a *models.HoldError when no hold has the token or
+// the hold has no seat for the booking.
+func takeHeldSeat(ctx context.Context, tx *sql.Tx, booking *models.Booking, now time.Time) error {
+	query := `SELECT ` + holdColumns + ` FROM seat_holds WHERE token = $1 FOR UPDATE`
+	hold, err := scanHold(tx.QueryRowContext(ctx, query, models.NormalizeBookingCode(booking.HoldToken)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.HoldError{Reason: "does not exist"}
+	}
+	if err != nil {
+		return err
+	}
+	if err := hold.Check(booking, now); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE seat_holds
+		SET booked = booked + 1,
+			status = CASE WHEN booked + 1 = seats THEN 'converted' ELSE status END,
+			closed_at = CASE WHEN booked + 1 = seats THEN $2 ELSE closed_at END
+		WHERE id = $1
+	`
+	res, err := tx.ExecContext(ctx, query, hold.ID, now)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(res); err != nil {
+		return err
+	}
+	booking.HoldID = &hold.ID
+	return nil
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"space-booking/internal/models"
+)
+
+func (s *service) PublishManifest(ctx context.Context, manifest *models.Manifest) (bool, error) {
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// A concurrent publisher of the same flight waits here until this
+	// transaction ends, and then inserts nothing.
+	now := s.clock.Now()
+	query := `
+		INSERT INTO manifest_publications (launchpad_id, launch_date, published_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+	res, err := tx.ExecContext(ctx, query, manifest.LaunchpadID, manifest.LaunchDate, now)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := publish(ctx, tx, models.ManifestPublished, 0, payload, now); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"space-booking/internal/models"
+	"time"
+)
+
+// notificationColumns are the columns scanned by notificationFields, in
+// order.
+const notificationColumns = `n.id, n.booking_id, n.kind, n.recipient, n.status, n.attempts, n.next_attempt_at,
+	COALESCE(n.last_error, ''), n.sent_at, n.created_at`
+
+func notificationFields(n *models.Notification) []any {
+	return []any{
+		&n.ID, &n.BookingID, &n.Kind, &n.Recipient, &n.Status, &n.Attempts, &n.NextAttemptAt,
+		&n.LastError, &n.SentAt, &n.CreatedAt,
+	}
+}
+
+// queueNotification queues an email of the given kind to the passenger,
+// within the transaction changing the booking. Bookings without an email
+// address are skipped.
+func queueNotification(ctx context.Context, tx *sql.Tx, kind string, booking *models.Booking, now time.Time) error {
+	if booking.Email == "" {
+		return nil
+	}
+	query := `
+		INSERT INTO notification_jobs (booking_id, kind, recipient, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $4)
+	`
+	_, err := tx.ExecContext(ctx, query, booking.ID, kind, booking.Email, now)
+	return err
+}
+
+func (s *service) ClaimNotifications(ctx context.Context, limit int, lease time.Duration) ([]models.DueNotification, error) {
+	now := s.clock.Now()
+	query := `
+		WITH claimed AS (
+			UPDATE notification_jobs n
+			SET next_attempt_at = $2
+			FROM (
+				SELECT id
+				FROM notification_jobs
+				WHERE status = 'pending' AND next_attempt_at <= $1
+				ORDER BY next_attempt_at, id
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			) due
+			WHERE n.id = due.id
+			RETURNING n.*
+		)
+		SELECT ` + notificationColumns + `, COALESCE(d.name, ''), b.*
+		FROM claimed n
+		JOIN (SELECT ` + bookingColumns + ` FROM bookings) b ON b.id = n.booking_id
+		LEFT JOIN destinations d ON d.id = b.destination_id
+		ORDER BY n.next_attempt_at, n.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []models.DueNotification
+	for rows.Next() {
+		var n models.DueNotification
+		fields := append(notificationFields(&n.Notification), &n.DestinationName)
+		fields = append(fields, bookingFields(&n.Booking)...)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		due = append(due, n)
+	}
+	return due, rows.Err()
+}
+
+func (s *service) RecordNotificationAttempt(ctx context.Context, notification *models.Notification) error {
+	query := `
+		UPDATE notification_jobs
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = NULLIF($5, ''), sent_at = $6
+		WHERE id = $1
+	`
+	res, err := s.db.ExecContext(
+		ctx,
+		query,
+		notification.ID,
+		notification.Status,
+		notification.Attempts,
+		notification.NextAttemptAt,
+		notification.LastError,
+		notification.SentAt,
+	)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"space-booking/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// OutboxChannel is the channel notified with the ID of every message
+// written to the outbox, once its transaction commits.
+const OutboxChannel = "outbox"
+
+// publish writes a message to the outbox within tx and notifies the
+// listeners when tx commits. A bookingID of zero marks a message that is
+// not about a single booking.
+func publish(ctx context.Context, tx *sql.Tx, messageType string, bookingID int, payload []byte, now time.Time) error {
+	query := `
+		INSERT INTO outbox (type, booking_id, payload, created_at)
+		VALUES ($1, NULLIF($2, 0), $3, $4)
+		RETURNING id
+	`
+	var id int64
+	if err := tx.QueryRowContext(ctx, query, messageType, bookingID, string(payload), now).Scan(&id); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, OutboxChannel, fmt.Sprint(id))
+	return err
+}
+
+func (s *service) GetOutboxMessages(ctx context.Context, filter models.OutboxFilter) ([]models.OutboxMessage, error) {
+	// Only the messages of transactions older than every one still running
+	// are returned, by position: those that commit later all come after.
+	// A long transaction thus holds back every stream until it ends, which
+	// is why none is kept open across a call to another service.
+	// A passenger sees the messages about their bookings, and the schedule
+	// changes; manifests list other passengers and are kept from them.
+	query := `
+		SELECT o.id, o.xid::text::bigint, o.type, COALESCE(o.booking_id, 0), o.payload, o.created_at
+		FROM outbox o
+		WHERE (o.xid, o.id) > ($1::text::xid8, $2)
+			AND o.xid < pg_snapshot_xmin(pg_current_snapshot())
+			AND (cardinality($3::text[]) = 0 OR o.type = ANY($3))
+			AND (NOT $4 OR (o.booking_id IS NULL AND o.type LIKE 'schedule.%') OR o.booking_id = ANY($5))
+		ORDER BY o.xid, o.id
+		LIMIT $6
+	`
+	types := filter.Types
+	if types == nil {
+		types = []string{}
+	}
+	bookings := make([]int64, len(filter.Bookings))
+	for i, id := range filter.Bookings {
+		bookings[i] = int64(id)
+	}
+	rows, err := s.db.QueryContext(ctx, query, fmt.Sprint(filter.After.XID), filter.After.ID, types,
+		filter.Bookings != nil, bookings, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var m models.OutboxMessage
+		var payload []byte
+		if err := rows.Scan(&m.ID, &m.XID, &m.Type, &m.BookingID, &payload, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.Payload = payload
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (s *service) LatestOutboxPosition(ctx context.Context) (models.OutboxPosition, error) {
+	query := `
+		SELECT o.xid::text::bigint, o.id
+		FROM outbox o
+		WHERE o.xid < pg_snapshot_xmin(pg_current_snapshot())
+		ORDER BY o.xid DESC, o.id DESC
+		LIMIT 1
+	`
+	var p models.OutboxPosition
+	err := s.db.QueryRowContext(ctx, query).Scan(&p.XID, &p.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OutboxPosition{}, nil
+	}
+	return p, err
+}
+
+func (s *service) ListenOutbox(ctx context.Context, notify func()) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("listen needs a pgx connection, got %T", driverConn)
+		}
+		pgConn := c.Conn()
+		if _, err := pgConn.Exec(ctx, "LISTEN "+OutboxChannel); err != nil {
+			return err
+		}
+		for {
+			if _, err := pgConn.WaitForNotification(ctx); err != nil {
+				return err
+			}
+			notify()
+		}
+	})
+}
@@ -0,0 +1,569 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"space-booking/internal/models"
+	"time"
+)
+
+// ErrPaymentClosed is returned for a payment that was already captured or
+// failed, or whose booking no longer waits for it.
+var ErrPaymentClosed = errors.New("database: payment is closed")
+
+// uncapturedReason is why a payment taken for a booking that was cancelled
+// during the capture is refunded.
+const uncapturedReason = "Payment was taken but the booking could not be confirmed."
+
+// expireBatch caps the bookings expired at once.
+const expireBatch = 100
+
+// paymentColumns are the columns scanned by paymentFields, in order, of
+// the payments aliased p.
+const paymentColumns = `p.id, p.booking_id, p.provider, COALESCE(p.provider_ref, ''), p.amount_cents, p.currency, p.status,
+	COALESCE(p.failure_reason, ''), p.created_at, p.updated_at, p.captured_at, p.capture_attempts, p.next_capture_at`
+
+func paymentFields(p *models.Payment) []any {
+	return []any{
+		&p.ID, &p.BookingID, &p.Provider, &p.ProviderRef, &p.Amount, &p.Currency, &p.Status,
+		&p.FailureReason, &p.CreatedAt, &p.UpdatedAt, &p.CapturedAt, &p.CaptureAttempts, &p.NextCaptureAt,
+	}
+}
+
+// refundColumns are the columns scanned by refundFields, in order, of the
+// refunds aliased r.
+const refundColumns = `r.id, r.payment_id, r.amount_cents, r.reason, COALESCE(r.disrupted_by, ''), r.status, COALESCE(r.provider_ref, ''), r.attempts,
+	r.next_attempt_at, COALESCE(r.last_error, ''), r.refunded_at, r.created_at`
+
+func refundFields(r *models.Refund) []any {
+	return []any{
+		&r.ID, &r.PaymentID, &r.Amount, &r.Reason, &r.DisruptedBy, &r.Status, &r.ProviderRef, &r.Attempts,
+		&r.NextAttemptAt, &r.LastError, &r.RefundedAt, &r.CreatedAt,
+	}
+}
+
+func (s *service) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	now := s.clock.Now()
+	query := `
+		INSERT INTO payments (booking_id, provider, amount_cents, currency, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING id
+	`
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		payment.BookingID,
+		payment.Provider,
+		payment.Amount,
+		payment.Currency,
+		models.PaymentPending,
+		now,
+	).Scan(&payment.ID)
+	if err != nil {
+		return err
+	}
+	payment.Status = models.PaymentPending
+	payment.CreatedAt = now
+	payment.UpdatedAt = now
+	return nil
+}
+
+func (s *service) GetPayments(ctx context.Context, bookingID int) ([]models.Payment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+paymentColumns+` FROM payments p WHERE p.booking_id = $1 ORDER BY p.id`, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []models.Payment
+	index := make(map[int64]int)
+	for rows.Next() {
+		var p models.Payment
+		if err := rows.Scan(paymentFields(&p)...); err != nil {
+			return nil, err
+		}
+		index[p.ID] = len(payments)
+		payments = append(payments, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(payments) == 0 {
+		return payments, nil
+	}
+
+	query := `
+		SELECT ` + refundColumns + `
+		FROM refunds r
+		JOIN payments p ON p.id = r.payment_id
+		WHERE p.booking_id = $1
+		ORDER BY r.id
+	`
+	rows, err = s.db.QueryContext(ctx, query, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r models.Refund
+		if err := rows.Scan(refundFields(&r)...); err != nil {
+			return nil, err
+		}
+		p := &payments[index[r.PaymentID]]
+		p.Refunds = append(p.Refunds, r)
+	}
+	return payments, rows.Err()
+}
+
+// lockPayment reads the payment and the booking it pays, and locks both
+// until tx ends. The booking is locked first, like every change to a
+// booking does.
+func lockPayment(ctx context.Context, tx *sql.Tx, id int64) (*models.Payment, *models.Booking, error) {
+	var bookingID int
+	err := tx.QueryRowContext(ctx, `SELECT booking_id FROM payments WHERE id = $1`, id).Scan(&bookingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	booking, err := lockBooking(ctx, tx, bookingID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var p models.Payment
+	query := `SELECT ` + paymentColumns + ` FROM payments p WHERE p.id = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, id).Scan(paymentFields(&p)...); err != nil {
+		return nil, nil, err
+	}
+	return &p, booking, nil
+}
+
+func (s *service) StartCapture(ctx context.Context, id int64, providerRef string, retryAt time.Time) (*models.Payment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	payment, booking, err := lockPayment(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch payment.Status {
+	case models.PaymentCaptured, models.PaymentFailed:
+		return nil, ErrPaymentClosed
+	}
+
+	now := s.clock.Now()
+	if payment.Status == models.PaymentPending && booking.Status != models.StatusPending {
+		// The authorization lapses without a capture.
+		reason := fmt.Sprintf("Booking was %s before the payment was authorized.", booking.Status)
+		if err := failPayment(ctx, tx, id, providerRef, reason, now); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrPaymentClosed
+	}
+
+	// A payment that is capturing already may have been captured: it is
+	// sent again, and refunded by ConfirmPayment should its booking be gone.
+	query := `
+		UPDATE payments p
+		SET status = $2, provider_ref = COALESCE(NULLIF($3, ''), provider_ref), next_capture_at = $4, updated_at = $5
+		WHERE p.id = $1
+		RETURNING ` + paymentColumns
+	if err := tx.QueryRowContext(ctx, query, id, models.PaymentCapturing, providerRef, retryAt, now).Scan(paymentFields(payment)...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (s *service) ConfirmPayment(ctx context.Context, id int64) (*models.Booking, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	payment, before, err := lockPayment(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch payment.Status {
+	case models.PaymentCaptured:
+		if before.Status == models.StatusPending {
+			// Captured while the booking failed to be confirmed, and
+			// refunded since.
+			return nil, ErrPaymentClosed
+		}
+		return before, nil
+	case models.PaymentPending, models.PaymentFailed:
+		return nil, ErrPaymentClosed
+	}
+
+	now := s.clock.Now()
+	if err := markCaptured(ctx, tx, id, now); err != nil {
+		return nil, err
+	}
+	if before.Status != models.StatusPending {
+		// The booking was cancelled while the money was taken.
+		if err := queueRefund(ctx, tx, id, payment.Amount, models.RefundTerms{Share: 1, Reason: uncapturedReason}, now); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrPaymentClosed
+	}
+	after, err := changeStatus(ctx, tx, before, models.StatusConfirmed, "", now)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// markCaptured marks the payment captured.
+func markCaptured(ctx context.Context, tx *sql.Tx, id int64, now time.Time) error {
+	query := `
+		UPDATE payments
+		SET status = $2, next_capture_at = NULL, captured_at = $3, updated_at = $3
+		WHERE id = $1
+	`
+	_, err := tx.ExecContext(ctx, query, id, models.PaymentCaptured, now)
+	return err
+}
+
+func (s *service) ClaimCaptures(ctx context.Context, limit int, lease time.Duration) ([]models.Payment, error) {
+	now := s.clock.Now()
+	query := `
+		WITH claimed AS (
+			UPDATE payments p
+			SET next_capture_at = $2
+			FROM (
+				SELECT id
+				FROM payments
+				WHERE status = 'capturing' AND next_capture_at <= $1
+				ORDER BY next_capture_at, id
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			) due
+			WHERE p.id = due.id
+			RETURNING p.*
+		)
+		SELECT ` + paymentColumns + `
+		FROM claimed p
+		ORDER BY p.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []models.Payment
+	for rows.Next() {
+		var p models.Payment
+		if err := rows.Scan(paymentFields(&p)...); err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+func (s *service) RecordCaptureAttempt(ctx context.Context, payment *models.Payment) error {
+	query := `
+		UPDATE payments
+		SET status = $2, capture_attempts = $3, next_capture_at = $4, failure_reason = NULLIF($5, ''), updated_at = $6
+		WHERE id = $1 AND status = 'capturing'
+	`
+	res, err := s.db.ExecContext(
+		ctx,
+		query,
+		payment.ID,
+		payment.Status,
+		payment.CaptureAttempts,
+		payment.NextCaptureAt,
+		payment.FailureReason,
+		s.clock.Now(),
+	)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (s *service) FailPayment(ctx context.Context, id int64, providerRef, reason string) (*models.Booking, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	payment, before, err := lockPayment(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch payment.Status {
+	case models.PaymentCapturing, models.PaymentCaptured:
+		return nil, ErrPaymentClosed
+	case models.PaymentFailed:
+		return before, nil
+	}
+
+	now := s.clock.Now()
+	if err := failPayment(ctx, tx, id, providerRef, reason, now); err != nil {
+		return nil, err
+	}
+	after := before
+	if before.Status == models.StatusPending {
+		if after, err = changeStatus(ctx, tx, before, models.StatusCancelled, "", now); err != nil {
+			return nil, err
+		}
+		if before.PromoCode != "" {
+			if err := releasePromo(ctx, tx, before.ID, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// failPayment marks the payment failed.
+func failPayment(ctx context.Context, tx *sql.Tx, id int64, providerRef, reason string, now time.Time) error {
+	query := `
+		UPDATE payments
+		SET status = $2, provider_ref = COALESCE(NULLIF($3, ''), provider_ref), failure_reason = $4, updated_at = $5
+		WHERE id = $1
+	`
+	_, err := tx.ExecContext(ctx, query, id, models.PaymentFailed, providerRef, reason, now)
+	return err
+}
+
+// failPendingPayments gives up the payments the booking still waits for.
+// A late authorization of one of them is never captured.
+func failPendingPayments(ctx context.Context, tx *sql.Tx, bookingID int, reason string, now time.Time) error {
+	query := `
+		UPDATE payments
+		SET status = $2, failure_reason = $3, updated_at = $4
+		WHERE booking_id = $1 AND status = $5
+	`
+	_, err := tx.ExecContext(ctx, query, bookingID, models.PaymentFailed, reason, now, models.PaymentPending)
+	return err
+}
+
+func (s *service) CancelBooking(ctx context.Context, id int, terms func(*models.Booking) models.RefundTerms) (*models.Booking, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := lockBooking(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	refund := terms(before)
+	after, err := changeStatus(ctx, tx, before, models.StatusCancelled, "", now)
+	if err != nil {
+		return nil, err
+	}
+	if err := failPendingPayments(ctx, tx, id, "Booking was cancelled before it was paid.", now); err != nil {
+		return nil, err
+	}
+	if before.Status == models.StatusPending && before.PromoCode != "" {
+		if err := releasePromo(ctx, tx, id, now); err != nil {
+			return nil, err
+		}
+	}
+	if refund.Share > 0 {
+		if err := queueRefunds(ctx, tx, id, refund, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// queueRefunds queues the refund of the terms' share of every captured
+// payment of the booking, less what was already refunded of it.
+func queueRefunds(ctx context.Context, tx *sql.Tx, bookingID int, terms models.RefundTerms, now time.Time) error {
+	query := `
+		SELECT p.id, p.amount_cents, COALESCE(SUM(r.amount_cents) FILTER (WHERE r.status <> 'failed'), 0)
+		FROM payments p
+		LEFT JOIN refunds r ON r.payment_id = p.id
+		WHERE p.booking_id = $1 AND p.status = 'captured'
+		GROUP BY p.id
+		ORDER BY p.id
+	`
+	rows, err := tx.QueryContext(ctx, query, bookingID)
+	if err != nil {
+		return err
+	}
+	type refundable struct {
+		paymentID        int64
+		amount, refunded int64
+	}
+	var payments []refundable
+	for rows.Next() {
+		var p refundable
+		if err := rows.Scan(&p.paymentID, &p.amount, &p.refunded); err != nil {
+			rows.Close()
+			return err
+		}
+		payments = append(payments, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range payments {
+		amount := models.RefundAmount(p.amount, p.refunded, terms.Share)
+		if amount == 0 {
+			continue
+		}
+		if err := queueRefund(ctx, tx, p.paymentID, amount, terms, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queueRefund queues the refund of amount of the payment.
+func queueRefund(ctx context.Context, tx *sql.Tx, paymentID, amount int64, terms models.RefundTerms, now time.Time) error {
+	query := `
+		INSERT INTO refunds (payment_id, amount_cents, reason, disrupted_by, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $5)
+	`
+	_, err := tx.ExecContext(ctx, query, paymentID, amount, terms.Reason, terms.DisruptedBy, now)
+	return err
+}
+
+func (s *service) ExpirePendingBookings(ctx context.Context, before time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Bookings whose payment is being captured are skipped until the
+	// capture is settled. Seats offered from the waitlist are held until
+	// their own time.
+	now := s.clock.Now()
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings
+		WHERE status = 'pending' AND (hold_until IS NULL AND created_at < $1 OR hold_until <= $3)
+			AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.booking_id = bookings.id AND p.status = 'capturing')
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, query, before, expireBatch, now)
+	if err != nil {
+		return 0, err
+	}
+	bookings, err := scanBookings(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range bookings {
+		if _, err := changeStatus(ctx, tx, &bookings[i], models.StatusExpired, "", now); err != nil {
+			return 0, err
+		}
+		if err := failPendingPayments(ctx, tx, bookings[i].ID, "Booking expired before it was paid.", now); err != nil {
+			return 0, err
+		}
+		if bookings[i].PromoCode != "" {
+			if err := releasePromo(ctx, tx, bookings[i].ID, now); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(bookings), nil
+}
+
+func (s *service) ClaimRefunds(ctx context.Context, limit int, lease time.Duration) ([]models.DueRefund, error) {
+	now := s.clock.Now()
+	query := `
+		WITH claimed AS (
+			UPDATE refunds r
+			SET next_attempt_at = $2
+			FROM (
+				SELECT id
+				FROM refunds
+				WHERE status = 'pending' AND next_attempt_at <= $1
+				ORDER BY next_attempt_at, id
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			) due
+			WHERE r.id = due.id
+			RETURNING r.*
+		)
+		SELECT ` + refundColumns + `, ` + paymentColumns + `
+		FROM claimed r
+		JOIN payments p ON p.id = r.payment_id
+		ORDER BY r.next_attempt_at, r.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []models.DueRefund
+	for rows.Next() {
+		var r models.DueRefund
+		if err := rows.Scan(append(refundFields(&r.Refund), paymentFields(&r.Payment)...)...); err != nil {
+			return nil, err
+		}
+		due = append(due, r)
+	}
+	return due, rows.Err()
+}
+
+func (s *service) RecordRefundAttempt(ctx context.Context, refund *models.Refund) error {
+	query := `
+		UPDATE refunds
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = NULLIF($5, ''),
+			provider_ref = NULLIF($6, ''), refunded_at = $7
+		WHERE id = $1
+	`
+	res, err := s.db.ExecContext(
+		ctx,
+		query,
+		refund.ID,
+		refund.Status,
+		refund.Attempts,
+		refund.NextAttemptAt,
+		refund.LastError,
+		refund.ProviderRef,
+		refund.RefundedAt,
+	)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
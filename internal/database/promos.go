@@ -0,0 +1,280 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"space-booking/internal/models"
+	"time"
+)
+
+var (
+	// ErrPromoCodeTaken is returned when creating a promo code that exists.
+	ErrPromoCodeTaken = errors.New("database: promo code is taken")
+	// ErrPromoCodeRedeemed is returned when deleting a promo code that was
+	// redeemed; it can be deactivated instead.
+	ErrPromoCodeRedeemed = errors.New("database: promo code was redeemed")
+)
+
+// promoColumns are the columns scanned by scanPromoCode, in order.
+const promoColumns = `id, code, kind, value, COALESCE(currency, ''), valid_from, valid_until,
+	array_to_json(destination_ids), max_redemptions, redemptions, active, created_at`
+
+func scanPromoCode(row scanner) (models.PromoCode, error) {
+	var p models.PromoCode
+	var destinationIDs []byte
+	err := row.Scan(&p.ID, &p.Code, &p.Kind, &p.Value, &p.Currency, &p.ValidFrom, &p.ValidUntil,
+		&destinationIDs, &p.MaxRedemptions, &p.Redemptions, &p.Active, &p.CreatedAt)
+	if err != nil {
+		return p, err
+	}
+	err = json.Unmarshal(destinationIDs, &p.DestinationIDs)
+	return p, err
+}
+
+func (s *service) ListPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+promoColumns+` FROM promo_codes ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []models.PromoCode
+	for rows.Next() {
+		p, err := scanPromoCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, p)
+	}
+	return codes, rows.Err()
+}
+
+func (s *service) GetPromoCode(ctx context.Context, id int) (*models.PromoCode, error) {
+	p, err := scanPromoCode(s.db.QueryRowContext(ctx, `SELECT `+promoColumns+` FROM promo_codes WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *service) GetPromoCodeByCode(ctx context.Context, code string) (*models.PromoCode, error) {
+	query := `SELECT ` + promoColumns + ` FROM promo_codes WHERE code = $1`
+	p, err := scanPromoCode(s.db.QueryRowContext(ctx, query, models.NormalizePromoCode(code)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *service) CreatePromoCode(ctx context.Context, code *models.PromoCode) error {
+	code.CreatedAt = s.clock.Now()
+	code.Redemptions = 0
+	query := `
+		INSERT INTO promo_codes (code, kind, value, currency, valid_from, valid_until, destination_ids,
+			max_redemptions, active, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING id
+	`
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		code.Code,
+		code.Kind,
+		code.Value,
+		code.Currency,
+		code.ValidFrom,
+		code.ValidUntil,
+		destinationIDs(code.DestinationIDs),
+		code.MaxRedemptions,
+		code.Active,
+		code.CreatedAt,
+	).Scan(&code.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPromoCodeTaken
+	}
+	return err
+}
+
+func (s *service) UpdatePromoCode(ctx context.Context, code *models.PromoCode) error {
+	query := `
+		UPDATE promo_codes
+		SET kind = $2, value = $3, currency = NULLIF($4, ''), valid_from = $5, valid_until = $6,
+			destination_ids = $7, max_redemptions = $8, active = $9
+		WHERE id = $1
+		RETURNING ` + promoColumns
+	updated, err := scanPromoCode(s.db.QueryRowContext(
+		ctx,
+		query,
+		code.ID,
+		code.Kind,
+		code.Value,
+		code.Currency,
+		code.ValidFrom,
+		code.ValidUntil,
+		destinationIDs(code.DestinationIDs),
+		code.MaxRedemptions,
+		code.Active,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	*code = updated
+	return nil
+}
+
+func (s *service) DeletePromoCode(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM promo_codes
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM promo_redemptions WHERE promo_code_id = $1)
+	`
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	err = expectAffected(res)
+	if errors.Is(err, ErrNotFound) {
+		if _, err := s.GetPromoCode(ctx, id); err != nil {
+			return err
+		}
+		return ErrPromoCodeRedeemed
+	}
+	return err
+}
+
+// destinationIDs never passes a nil slice, which would be stored as NULL.
+func destinationIDs(ids []int64) []int64 {
+	if ids == nil {
+		return []int64{}
+	}
+	return ids
+}
+
+func (s *service) ListPromoRedemptions(ctx context.Context, promoCodeID int) ([]models.PromoRedemption, error) {
+	query := `
+		SELECT r.id, r.promo_code_id, r.booking_id, b.status, r.discount_cents, r.currency, r.redeemed_at, r.released_at
+		FROM promo_redemptions r
+		JOIN bookings b ON b.id = r.booking_id
+		WHERE r.promo_code_id = $1
+		ORDER BY r.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, promoCodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var redemptions []models.PromoRedemption
+	for rows.Next() {
+		var r models.PromoRedemption
+		err := rows.Scan(&r.ID, &r.PromoCodeID, &r.BookingID, &r.Status, &r.Discount, &r.Currency, &r.RedeemedAt, &r.ReleasedAt)
+		if err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, r)
+	}
+	return redemptions, rows.Err()
+}
+
+func (s *service) GetPromoReport(ctx context.Context, from, to time.Time) ([]models.PromoReport, error) {
+	query := `
+		SELECT p.id, p.code, p.kind, p.value, p.max_redemptions, COUNT(r.id),
+			COALESCE(SUM(r.discount_cents), 0), COALESCE(SUM(b.price_cents), 0), COALESCE(MIN(r.currency), '')
+		FROM promo_codes p
+		LEFT JOIN promo_redemptions r ON r.promo_code_id = p.id AND r.released_at IS NULL
+			AND ($1::timestamptz IS NULL OR r.redeemed_at >= $1)
+			AND ($2::timestamptz IS NULL OR r.redeemed_at < $2)
+		LEFT JOIN bookings b ON b.id = r.booking_id
+		GROUP BY p.id
+		ORDER BY p.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, bound(from), bound(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []models.PromoReport
+	for rows.Next() {
+		var r models.PromoReport
+		err := rows.Scan(&r.PromoCodeID, &r.Code, &r.Kind, &r.Value, &r.MaxRedemptions, &r.Redemptions,
+			&r.Discount, &r.Revenue, &r.Currency)
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, r)
+	}
+	return report, rows.Err()
+}
+
+// redeemPromo redeems the booking's promo code for it. The code is locked
+// until tx ends, so that its cap holds however many bookings redeem it at
+// once. It returns a *models.PromoError for a code that cannot be redeemed.
+func redeemPromo(ctx context.Context, tx *sql.Tx, booking *models.Booking, now time.Time) error {
+	query := `SELECT ` + promoColumns + ` FROM promo_codes WHERE code = $1 FOR UPDATE`
+	code, err := scanPromoCode(tx.QueryRowContext(ctx, query, booking.PromoCode))
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.PromoError{Code: booking.PromoCode, Reason: "does not exist"}
+	}
+	if err != nil {
+		return err
+	}
+	if err := code.Check(booking.DestinationID, now); err != nil {
+		return err
+	}
+
+	passenger := models.PassengerKey(booking)
+	var used bool
+	query = `
+		SELECT EXISTS (
+			SELECT 1 FROM promo_redemptions
+			WHERE promo_code_id = $1 AND passenger = $2 AND released_at IS NULL
+		)
+	`
+	if err := tx.QueryRowContext(ctx, query, code.ID, passenger).Scan(&used); err != nil {
+		return err
+	}
+	if used {
+		return &models.PromoError{Code: code.Code, Reason: "was already used by this passenger"}
+	}
+
+	query = `
+		INSERT INTO promo_redemptions (promo_code_id, booking_id, passenger, discount_cents, currency, redeemed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = tx.ExecContext(ctx, query, code.ID, booking.ID, passenger, booking.Discount, booking.Currency, now)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE promo_codes SET redemptions = redemptions + 1 WHERE id = $1`, code.ID)
+	return err
+}
+
+// releasePromo gives back the promo code redeemed for a booking that was
+// never paid, so that it counts neither against the cap nor the passenger.
+func releasePromo(ctx context.Context, tx *sql.Tx, bookingID int, now time.Time) error {
+	query := `
+		WITH released AS (
+			UPDATE promo_redemptions
+			SET released_at = $2
+			WHERE booking_id = $1 AND released_at IS NULL
+			RETURNING promo_code_id
+		)
+		UPDATE promo_codes
+		SET redemptions = redemptions - 1
+		WHERE id IN (SELECT promo_code_id FROM released)
+	`
+	_, err := tx.ExecContext(ctx, query, bookingID, now)
+	return err
+}
@@ -0,0 +1,281 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"space-booking/internal/models"
+	"time"
+)
+
+var (
+	// ErrAlreadyWaiting is returned when the passenger is already waiting
+	// for the flight.
+	ErrAlreadyWaiting = errors.New("database: passenger is already waiting")
+	// ErrNotWaiting is returned when leaving a waitlist entry that is no
+	// longer waiting.
+	ErrNotWaiting = errors.New("database: waitlist entry is not waiting")
+)
+
+// waitlistColumns are the columns scanned by scanWaitlistEntry, in order,
+// selected from waitlist_entries w.
+const waitlistColumns = `w.id, w.code, w.first_name, w.last_name, w.email, COALESCE(w.gender, ''), w.birthday,
+	w.launchpad_id, w.destination_id, w.launch_date, w.status,
+	CASE WHEN w.status = 'waiting' THEN (
+		SELECT COUNT(*) FROM waitlist_entries q
+		WHERE q.status = 'waiting' AND q.launchpad_id = w.launchpad_id AND q.launch_date = w.launch_date
+			AND q.id <= w.id
+	) ELSE 0 END,
+	w.booking_id, (SELECT b.hold_until FROM bookings b WHERE b.id = w.booking_id),
+	w.created_at, w.promoted_at, w.closed_at`
+
+func scanWaitlistEntry(row scanner) (models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	err := row.Scan(&e.ID, &e.Code, &e.FirstName, &e.LastName, &e.Email, &e.Gender, &e.Birthday,
+		&e.LaunchpadID, &e.DestinationID, &e.LaunchDate, &e.Status, &e.Position,
+		&e.BookingID, &e.HoldUntil, &e.CreatedAt, &e.PromotedAt, &e.ClosedAt)
+	return e, err
+}
+
+func scanWaitlistEntries(rows *sql.Rows) ([]models.WaitlistEntry, error) {
+	defer rows.Close()
+
+	var entries []models.WaitlistEntry
+	for rows.Next() {
+		e, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *service) JoinWaitlist(ctx context.Context, entry *models.WaitlistEntry) error {
+	now := s.clock.Now()
+	// The entry gets a code like a booking's, the only way its passenger
+	// is known by outside the admin API.
+	code, err := models.NewBookingCode()
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO waitlist_entries (code, first_name, last_name, email, gender, birthday, launchpad_id,
+			destination_id, launch_date, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10)
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	`
+	var id int
+	err = s.db.QueryRowContext(
+		ctx,
+		query,
+		code,
+		entry.FirstName,
+		entry.LastName,
+		entry.Email,
+		entry.Gender,
+		entry.Birthday,
+		entry.LaunchpadID,
+		entry.DestinationID,
+		entry.LaunchDate,
+		now,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAlreadyWaiting
+	}
+	if err != nil {
+		return err
+	}
+	joined, err := s.GetWaitlistEntry(ctx, id)
+	if err != nil {
+		return err
+	}
+	*entry = *joined
+	return nil
+}
+
+func (s *service) GetWaitlistEntry(ctx context.Context, id int) (*models.WaitlistEntry, error) {
+	query := `SELECT ` + waitlistColumns + ` FROM waitlist_entries w WHERE w.id = $1`
+	e, err := scanWaitlistEntry(s.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *service) GetWaitlistEntryByCode(ctx context.Context, code string) (*models.WaitlistEntry, error) {
+	query := `SELECT ` + waitlistColumns + ` FROM waitlist_entries w WHERE w.code = $1`
+	e, err := scanWaitlistEntry(s.db.QueryRowContext(ctx, query, models.NormalizeBookingCode(code)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *service) ListWaitlist(ctx context.Context, launchpadID string, launchDate time.Time) ([]models.WaitlistEntry, error) {
+	query := `
+		SELECT ` + waitlistColumns + `
+		FROM waitlist_entries w
+		WHERE ($1 = '' OR w.launchpad_id = $1) AND ($2::date IS NULL OR w.launch_date = $2)
+		ORDER BY w.launch_date, w.launchpad_id, w.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, launchpadID, bound(launchDate))
+	if err != nil {
+		return nil, err
+	}
+	return scanWaitlistEntries(rows)
+}
+
+func (s *service) LeaveWaitlist(ctx context.Context, id int) (*models.WaitlistEntry, error) {
+	query := `
+		UPDATE waitlist_entries
+		SET status = 'left', closed_at = $2
+		WHERE id = $1 AND status = 'waiting'
+	`
+	res, err := s.db.ExecContext(ctx, query, id, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.GetWaitlistEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return entry, ErrNotWaiting
+	}
+	return entry, nil
+}
+
+func (s *service) GetWaitlistFlights(ctx context.Context, from time.Time) ([]models.WaitlistFlight, error) {
+	query := `
+		SELECT DISTINCT launchpad_id, destination_id, launch_date
+		FROM waitlist_entries
+		WHERE status = 'waiting' AND launch_date >= $1
+		ORDER BY launch_date, launchpad_id, destination_id
+	`
+	rows, err := s.db.QueryContext(ctx, query, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flights []models.WaitlistFlight
+	for rows.Next() {
+		var f models.WaitlistFlight
+		if err := rows.Scan(&f.LaunchpadID, &f.DestinationID, &f.LaunchDate); err != nil {
+			return nil, err
+		}
+		flights = append(flights, f)
+	}
+	return flights, rows.Err()
+}
+
+func (s *service) ExpireWaitlist(ctx context.Context, before time.Time) (int, error) {
+	query := `
+		UPDATE waitlist_entries
+		SET status = 'expired', closed_at = $2
+		WHERE status = 'waiting' AND launch_date < $1
+	`
+	res, err := s.db.ExecContext(ctx, query, before, s.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *service) PromoteWaitlist(ctx context.Context, flight models.WaitlistFlight, seats int, holdUntil time.Time, price func(booking *models.Booking, sold int) error) ([]models.WaitlistEntry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The waiting entries are locked first, so that promotions of the
+	// flight run one at a time and count the seats the others took.
+	query := `
+		SELECT ` + waitlistColumns + `
+		FROM waitlist_entries w
+		WHERE w.status = 'waiting' AND w.launchpad_id = $1 AND w.destination_id = $2 AND w.launch_date = $3
+		ORDER BY w.id
+		FOR UPDATE
+	`
+	rows, err := tx.QueryContext(ctx, query, flight.LaunchpadID, flight.DestinationID, flight.LaunchDate)
+	if err != nil {
+		return nil, err
+	}
+	waiting, err := scanWaitlistEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(waiting) == 0 {
+		return nil, nil
+	}
+
+	// Held seats are taken too; the flight lock keeps new holds out.
+	if err := lockFlight(ctx, tx, flight.LaunchpadID, flight.LaunchDate); err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	sold, err := seatsTaken(ctx, tx, flight.LaunchpadID, flight.LaunchDate, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var promoted []models.WaitlistEntry
+	for i := 0; i < len(waiting) && sold < seats; i++ {
+		entry := waiting[i]
+		booking := entry.Booking(holdUntil)
+		if err := price(booking, sold); err != nil {
+			return nil, err
+		}
+		if err := insertBooking(ctx, tx, booking, now); err != nil {
+			return nil, err
+		}
+		if err := recordEvent(ctx, tx, models.EventCreated, nil, booking, now); err != nil {
+			return nil, err
+		}
+		query := `
+			UPDATE waitlist_entries
+			SET status = 'promoted', booking_id = $2, promoted_at = $3
+			WHERE id = $1
+		`
+		if _, err := tx.ExecContext(ctx, query, entry.ID, booking.ID, now); err != nil {
+			return nil, err
+		}
+		entry.Status = models.WaitlistPromoted
+		entry.Position = 0
+		entry.BookingID = &booking.ID
+		entry.HoldUntil = booking.HoldUntil
+		entry.PromotedAt = &now
+
+		payload, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		if err := publish(ctx, tx, models.SeatOffered, booking.ID, payload, now); err != nil {
+			return nil, err
+		}
+		if err := queueNotification(ctx, tx, models.NotificationSeatOffered, booking, now); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, entry)
+		sold++
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"space-booking/internal/models"
+	"time"
+)
+
+// webhookColumns are the columns scanned by scanWebhook, in order.
+const webhookColumns = `id, url, secret, array_to_json(event_types), active, created_at`
+
+func scanWebhook(row scanner) (models.WebhookSubscription, error) {
+	var w models.WebhookSubscription
+	var eventTypes []byte
+	if err := row.Scan(&w.ID, &w.URL, &w.Secret, &eventTypes, &w.Active, &w.CreatedAt); err != nil {
+		return w, err
+	}
+	err := json.Unmarshal(eventTypes, &w.EventTypes)
+	return w, err
+}
+
+func (s *service) ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.WebhookSubscription
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *service) GetWebhook(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions
+		WHERE id = $1
+	`
+	w, err := scanWebhook(s.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (s *service) CreateWebhook(ctx context.Context, webhook *models.WebhookSubscription) error {
+	webhook.CreatedAt = s.clock.Now()
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, event_types, active, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		webhook.URL,
+		webhook.Secret,
+		eventTypes(webhook.EventTypes),
+		webhook.Active,
+		webhook.CreatedAt,
+	).Scan(&webhook.ID)
+}
+
+func (s *service) UpdateWebhook(ctx context.Context, webhook *models.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, active = $4
+		WHERE id = $1
+	`
+	res, err := s.db.ExecContext(
+		ctx,
+		query,
+		webhook.ID,
+		webhook.URL,
+		eventTypes(webhook.EventTypes),
+		webhook.Active,
+	)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (s *service) DeleteWebhook(ctx context.Context, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// eventTypes never passes a nil slice, which would be stored as NULL.
+func eventTypes(types []string) []string {
+	if types == nil {
+		return []string{}
+	}
+	return types
+}
+
+// deliveryColumns are the columns scanned by scanDelivery, in order, for a
+// query joining webhook_deliveries as d and outbox as o.
+const deliveryColumns = `d.id, d.subscription_id, d.outbox_id, o.type, d.status, d.attempts, d.next_attempt_at,
+	d.last_status_code, COALESCE(d.last_error, ''), d.delivered_at, d.created_at`
+
+func deliveryFields(d *models.WebhookDelivery) []any {
+	return []any{
+		&d.ID, &d.SubscriptionID, &d.OutboxID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt,
+	}
+}
+
+func (s *service) ListWebhookDeliveries(ctx context.Context, subscriptionID int, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d
+		JOIN outbox o ON o.id = d.outbox_id
+		WHERE d.subscription_id = $1
+		ORDER BY d.id DESC
+		LIMIT $2
+	`
+	rows, err := s.db.QueryContext(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(deliveryFields(&d)...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *service) FanOutOutbox(ctx context.Context, limit int) (int, error) {
+	// Skipping locked rows lets several replicas fan out side by side.
+	query := `
+		WITH pending AS (
+			SELECT id, type
+			FROM outbox
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), fanned AS (
+			INSERT INTO webhook_deliveries (subscription_id, outbox_id, next_attempt_at, created_at)
+			SELECT w.id, p.id, $2, $2
+			FROM pending p
+			JOIN webhook_subscriptions w
+				ON w.active AND (cardinality(w.event_types) = 0 OR p.type = ANY(w.event_types))
+			ON CONFLICT (subscription_id, outbox_id) DO NOTHING
+		)
+		UPDATE outbox SET dispatched_at = $2
+		WHERE id IN (SELECT id FROM pending)
+	`
+	res, err := s.db.ExecContext(ctx, query, limit, s.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *service) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error) {
+	now := s.clock.Now()
+	query := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $2
+		FROM (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		) due, webhook_subscriptions w, outbox o
+		WHERE d.id = due.id AND w.id = d.subscription_id AND o.id = d.outbox_id
+		RETURNING ` + deliveryColumns + `, w.url, w.secret, COALESCE(o.booking_id, 0), o.payload, o.created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []models.DueDelivery
+	for rows.Next() {
+		var d models.DueDelivery
+		var payload []byte
+		fields := append(deliveryFields(&d.Delivery), &d.URL, &d.Secret, &d.Message.BookingID, &payload, &d.Message.CreatedAt)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		d.Message.ID = d.Delivery.OutboxID
+		d.Message.Type = d.Delivery.EventType
+		d.Message.Payload = payload
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+func (s *service) RecordWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5,
+			last_error = NULLIF($6, ''), delivered_at = $7
+		WHERE id = $1
+	`
+	res, err := s.db.ExecContext(
+		ctx,
+		query,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		delivery.DeliveredAt,
+	)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"space-booking/internal/models"
+	"sync"
+)
+
+// Hub wakes up the event streams when new messages may have been written
+// to the outbox. It carries no messages itself: the streams read them from
+// the outbox, which lets them resume where a client left off.
+type Hub struct {
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+// NewHub returns a Hub.
+func NewHub() *Hub {
+	return &Hub{changed: make(chan struct{})}
+}
+
+// Changed returns a channel that is closed at the next Notify.
+func (h *Hub) Changed() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.changed
+}
+
+// Notify wakes up everyone waiting on Changed.
+func (h *Hub) Notify() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// LatestStore tells the position of the newest outbox message that can be
+// streamed.
+type LatestStore interface {
+	LatestOutboxPosition(ctx context.Context) (models.OutboxPosition, error)
+}
+
+// Poller notifies a Hub when the newest outbox message changes. It is the
+// fallback for databases whose notifications are not listened to.
+type Poller struct {
+	store LatestStore
+	hub   *Hub
+	last  models.OutboxPosition
+}
+
+// NewPoller returns a Poller.
+func NewPoller(store LatestStore, hub *Hub) *Poller {
+	return &Poller{store: store, hub: hub}
+}
+
+// RunOnce checks the outbox once.
+func (p *Poller) RunOnce(ctx context.Context) error {
+	latest, err := p.store.LatestOutboxPosition(ctx)
+	if err != nil {
+		return err
+	}
+	if latest != p.last {
+		p.last = latest
+		p.hub.Notify()
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type latestStore struct{ position models.OutboxPosition }
+
+func (s *latestStore) LatestOutboxPosition(context.Context) (models.OutboxPosition, error) {
+	return s.position, nil
+}
+
+func closed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestPollerNotifiesOnNewMessages(t *testing.T) {
+	store := &latestStore{}
+	hub := NewHub()
+	p := NewPoller(store, hub)
+
+	changed := hub.Changed()
+	require.NoError(t, p.RunOnce(context.Background()))
+	assert.False(t, closed(changed), "Expected no notification for an empty outbox")
+
+	store.position = models.OutboxPosition{XID: 740, ID: 3}
+	require.NoError(t, p.RunOnce(context.Background()))
+	assert.True(t, closed(changed))
+
+	changed = hub.Changed()
+	assert.False(t, closed(changed), "Expected a fresh channel after a notification")
+	require.NoError(t, p.RunOnce(context.Background()))
+	assert.False(t, closed(changed), "Expected no notification while nothing changed")
+}
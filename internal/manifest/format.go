@@ -0,0 +1,66 @@
+package manifest
+
+import (
+	"embed"
+	"encoding/csv"
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+
+	"space-booking/internal/models"
+)
+
+// csvHeader names the columns written by WriteCSV.
+var csvHeader = []string{
+	"launchpad_id", "launch_date", "destination_id", "destination", "code", "last_name", "first_name",
+	"gender", "birthday", "age", "status", "checked_in_at", "boarded_at",
+}
+
+// WriteCSV writes the manifest with one row per passenger.
+func WriteCSV(w io.Writer, m *models.Manifest) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	date := m.LaunchDate.Format("2006-01-02")
+	for _, d := range m.Destinations {
+		for _, p := range d.Passengers {
+			err := cw.Write([]string{
+				m.LaunchpadID, date, strconv.FormatInt(d.DestinationID, 10), d.Name, p.Code, p.LastName, p.FirstName,
+				p.Gender, p.Birthday.Format("2006-01-02"), strconv.Itoa(p.Age), string(p.Status),
+				formatTime(p.CheckedInAt), formatTime(p.BoardedAt),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+var htmlTemplate = template.Must(template.New("manifest.html").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+	"time": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format("15:04")
+	},
+}).ParseFS(templateFS, "templates/manifest.html"))
+
+// WriteHTML writes the manifest as a page meant to be printed.
+func WriteHTML(w io.Writer, m *models.Manifest) error {
+	return htmlTemplate.Execute(w, m)
+}
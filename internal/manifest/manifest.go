@@ -0,0 +1,104 @@
+// Package manifest lists the passengers of a flight for ground crew and
+// range control.
+package manifest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+)
+
+// flying are the statuses of the bookings listed on a manifest.
+var flying = []models.BookingStatus{models.StatusConfirmed, models.StatusCheckedIn, models.StatusBoarded}
+
+// Source is the part of the database manifests are read from.
+type Source interface {
+	GetBookingsOnLaunchpad(ctx context.Context, launchpadID string, from, to time.Time) ([]models.Booking, error)
+	GetDestinationName(ctx context.Context, id int64) (string, error)
+}
+
+// Load returns the manifest of the flights from a launchpad on a day.
+func Load(ctx context.Context, src Source, launchpadID string, day, now time.Time) (*models.Manifest, error) {
+	day = clock.Day(day)
+	bookings, err := src.GetBookingsOnLaunchpad(ctx, launchpadID, day, day)
+	if err != nil {
+		return nil, fmt.Errorf("list bookings: %w", err)
+	}
+	names := make(map[int64]string)
+	for _, b := range bookings {
+		if _, ok := names[b.DestinationID]; ok {
+			continue
+		}
+		name, err := src.GetDestinationName(ctx, b.DestinationID)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return nil, fmt.Errorf("destination %d: %w", b.DestinationID, err)
+		}
+		names[b.DestinationID] = name
+	}
+	m := Build(launchpadID, day, bookings, names, now)
+	return &m, nil
+}
+
+// Build groups the bookings flying on the day by destination, in the
+// order of the destination IDs, and the passengers by name.
+func Build(launchpadID string, day time.Time, bookings []models.Booking, names map[int64]string, now time.Time) models.Manifest {
+	m := models.Manifest{
+		LaunchpadID:  launchpadID,
+		LaunchDate:   clock.Day(day),
+		GeneratedAt:  now,
+		Destinations: []models.ManifestDestination{},
+	}
+	groups := make(map[int64]*models.ManifestDestination)
+	for _, b := range bookings {
+		if !slices.Contains(flying, b.Status) {
+			continue
+		}
+		g, ok := groups[b.DestinationID]
+		if !ok {
+			g = &models.ManifestDestination{DestinationID: b.DestinationID, Name: names[b.DestinationID]}
+			groups[b.DestinationID] = g
+		}
+		g.Passengers = append(g.Passengers, models.ManifestPassenger{
+			Code:        b.Code,
+			FirstName:   b.FirstName,
+			LastName:    b.LastName,
+			Gender:      b.Gender,
+			Birthday:    b.Birthday,
+			Age:         models.Age(b.Birthday, m.LaunchDate),
+			Status:      b.Status,
+			CheckedInAt: b.CheckedInAt,
+			BoardedAt:   b.BoardedAt,
+		})
+		m.Passengers++
+	}
+
+	for _, g := range groups {
+		slices.SortFunc(g.Passengers, func(a, b models.ManifestPassenger) int {
+			return cmpFold(a.LastName, b.LastName, a.FirstName, b.FirstName, a.Code, b.Code)
+		})
+		m.Destinations = append(m.Destinations, *g)
+	}
+	slices.SortFunc(m.Destinations, func(a, b models.ManifestDestination) int {
+		return cmp.Compare(a.DestinationID, b.DestinationID)
+	})
+	return m
+}
+
+// cmpFold compares pairs of strings ignoring case, the first pair that
+// differs deciding.
+func cmpFold(pairs ...string) int {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if c := strings.Compare(strings.ToLower(pairs[i]), strings.ToLower(pairs[i+1])); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var launchDay = time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+
+func passenger(id int, code, first, last string, destinationID int64, status models.BookingStatus) models.Booking {
+	return models.Booking{
+		ID: id, Code: code, FirstName: first, LastName: last, Gender: "female",
+		Birthday:    time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC),
+		LaunchpadID: "pad_a", DestinationID: destinationID, LaunchDate: launchDay, Status: status,
+	}
+}
+
+// fakeStore serves fixed bookings and keeps the published manifests.
+type fakeStore struct {
+	bookings  []models.Booking
+	names     map[int64]string
+	published map[string]*models.Manifest
+}
+
+func (s *fakeStore) GetBookingsOnLaunchpad(_ context.Context, launchpadID string, from, to time.Time) ([]models.Booking, error) {
+	var bookings []models.Booking
+	for _, b := range s.bookings {
+		if b.LaunchpadID == launchpadID && !b.LaunchDate.Before(from) && !b.LaunchDate.After(to) {
+			bookings = append(bookings, b)
+		}
+	}
+	return bookings, nil
+}
+
+func (s *fakeStore) GetDestinationName(_ context.Context, id int64) (string, error) {
+	name, ok := s.names[id]
+	if !ok {
+		return "", database.ErrNotFound
+	}
+	return name, nil
+}
+
+func (s *fakeStore) GetUpcomingBookings(_ context.Context, from time.Time) ([]models.Booking, error) {
+	var bookings []models.Booking
+	for _, b := range s.bookings {
+		if !b.LaunchDate.Before(from) {
+			bookings = append(bookings, b)
+		}
+	}
+	return bookings, nil
+}
+
+func (s *fakeStore) PublishManifest(_ context.Context, m *models.Manifest) (bool, error) {
+	key := m.LaunchpadID + "/" + m.LaunchDate.Format("2006-01-02")
+	if _, ok := s.published[key]; ok {
+		return false, nil
+	}
+	s.published[key] = m
+	return true, nil
+}
+
+func TestBuild(t *testing.T) {
+	bookings := []models.Booking{
+		passenger(1, "CCCCCCCCCC", "Zoe", "adams", 3, models.StatusBoarded),
+		passenger(2, "BBBBBBBBBB", "Ada", "Lovelace", 1, models.StatusConfirmed),
+		passenger(3, "AAAAAAAAAA", "Alan", "Adams", 3, models.StatusCheckedIn),
+		passenger(4, "DDDDDDDDDD", "Grace", "Hopper", 1, models.StatusCancelled),
+		passenger(5, "EEEEEEEEEE", "Linus", "Pending", 1, models.StatusPending),
+	}
+	now := launchDay.Add(-time.Hour)
+	m := Build("pad_a", launchDay.Add(9*time.Hour), bookings, map[int64]string{1: "Mars"}, now)
+
+	assert.Equal(t, launchDay, m.LaunchDate)
+	assert.Equal(t, 3, m.Passengers)
+	require.Len(t, m.Destinations, 2)
+	assert.Equal(t, "Mars", m.Destinations[0].Name)
+	assert.Equal(t, []string{"BBBBBBBBBB"}, codes(m.Destinations[0]))
+	assert.Equal(t, int64(3), m.Destinations[1].DestinationID)
+	assert.Empty(t, m.Destinations[1].Name)
+	assert.Equal(t, []string{"AAAAAAAAAA", "CCCCCCCCCC"}, codes(m.Destinations[1]))
+	assert.Equal(t, 49, m.Destinations[0].Passengers[0].Age)
+}
+
+func codes(d models.ManifestDestination) []string {
+	var codes []string
+	for _, p := range d.Passengers {
+		codes = append(codes, p.Code)
+	}
+	return codes
+}
+
+func TestWriteCSV(t *testing.T) {
+	boarded := launchDay.Add(7*time.Hour + 30*time.Minute)
+	b := passenger(1, "AAAAAAAAAA", "Ada", "Lovelace, Countess", 1, models.StatusBoarded)
+	b.BoardedAt = &boarded
+	m := Build("pad_a", launchDay, []models.Booking{b}, map[int64]string{1: "Mars"}, launchDay)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, &m))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, strings.Join(csvHeader, ","), lines[0])
+	assert.Equal(t, `pad_a,2049-12-25,1,Mars,AAAAAAAAAA,"Lovelace, Countess",Ada,female,2000-02-29,49,boarded,,2049-12-25T07:30:00Z`, lines[1])
+}
+
+func TestWriteHTML(t *testing.T) {
+	b := passenger(1, "AAAAAAAAAA", "<Ada>", "Lovelace", 1, models.StatusConfirmed)
+	m := Build("pad_a", launchDay, []models.Booking{b}, nil, launchDay)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTML(&buf, &m))
+	assert.Contains(t, buf.String(), "<h2>Destination 1 (1)</h2>")
+	assert.Contains(t, buf.String(), "&lt;Ada&gt;")
+}
+
+func TestPublisher(t *testing.T) {
+	store := &fakeStore{
+		bookings: []models.Booking{
+			passenger(1, "AAAAAAAAAA", "Ada", "Lovelace", 1, models.StatusConfirmed),
+			passenger(2, "BBBBBBBBBB", "Alan", "Turing", 1, models.StatusConfirmed),
+			func() models.Booking {
+				b := passenger(3, "CCCCCCCCCC", "Grace", "Hopper", 1, models.StatusConfirmed)
+				b.LaunchDate = launchDay.AddDate(0, 0, 2)
+				return b
+			}(),
+		},
+		names:     map[int64]string{1: "Mars"},
+		published: make(map[string]*models.Manifest),
+	}
+	clk := clock.NewFake(launchDay.Add(-24 * time.Hour))
+	p := NewPublisher(store, clk, 24*time.Hour, nil)
+
+	n, err := p.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Contains(t, store.published, "pad_a/2049-12-25")
+	assert.Equal(t, 2, store.published["pad_a/2049-12-25"].Passengers)
+
+	n, err = p.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "a flight's manifest is published once")
+}
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+)
+
+// Store is the part of the database the publisher works on.
+type Store interface {
+	Source
+	GetUpcomingBookings(ctx context.Context, from time.Time) ([]models.Booking, error)
+	// PublishManifest writes the manifest to the outbox unless one was
+	// already published for its flight, and reports whether it did.
+	PublishManifest(ctx context.Context, manifest *models.Manifest) (bool, error)
+}
+
+// Publisher hands the manifest of every flight to range control, through
+// the outbox, once the launch day is at most lead away. Each flight's
+// manifest is published once; later changes are read from the API.
+type Publisher struct {
+	store  Store
+	clock  clock.Clock
+	lead   time.Duration
+	logger *log.Logger
+}
+
+// NewPublisher returns a Publisher.
+func NewPublisher(store Store, clk clock.Clock, lead time.Duration, logger *log.Logger) *Publisher {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Publisher{store: store, clock: clk, lead: lead, logger: logger}
+}
+
+// flight is a launchpad on a launch day.
+type flight struct {
+	launchpadID string
+	day         time.Time
+}
+
+// RunOnce publishes the manifests that are due and returns how many it
+// published.
+func (p *Publisher) RunOnce(ctx context.Context) (int, error) {
+	now := p.clock.Now()
+	bookings, err := p.store.GetUpcomingBookings(ctx, clock.Day(now))
+	if err != nil {
+		return 0, fmt.Errorf("list upcoming bookings: %w", err)
+	}
+
+	var due []flight
+	seen := make(map[flight]bool)
+	for _, b := range bookings {
+		f := flight{launchpadID: b.LaunchpadID, day: clock.Day(b.LaunchDate)}
+		if seen[f] || f.day.Add(-p.lead).After(now) {
+			continue
+		}
+		seen[f] = true
+		due = append(due, f)
+	}
+
+	published := 0
+	for _, f := range due {
+		m, err := Load(ctx, p.store, f.launchpadID, f.day, now)
+		if err != nil {
+			return published, err
+		}
+		ok, err := p.store.PublishManifest(ctx, m)
+		if err != nil {
+			return published, fmt.Errorf("publish manifest of %s on %s: %w", f.launchpadID, f.day.Format("2006-01-02"), err)
+		}
+		if ok {
+			published++
+			p.logger.Printf("Published the manifest of %s on %s with %d passengers", f.launchpadID, f.day.Format("2006-01-02"), m.Passengers)
+		}
+	}
+	return published, nil
+}
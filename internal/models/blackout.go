@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Blackout closes a launchpad for the days from StartsOn to EndsOn, both
+// included.
+type Blackout struct {
+	ID          int       `json:"id"`
+	LaunchpadID string    `json:"launchpad_id"`
+	StartsOn    time.Time `json:"starts_on"`
+	EndsOn      time.Time `json:"ends_on"`
+	Reason      string    `json:"reason"`
+}
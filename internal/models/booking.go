@@ -3,12 +3,103 @@ package models
 import "time"
 
 type Booking struct {
-	ID            int       `json:"id"`
-	FirstName     string    `json:"first_name"`
-	LastName      string    `json:"last_name"`
-	Gender        string    `json:"gender"`
-	Birthday      time.Time `json:"birthday"`
-	LaunchpadID   string    `json:"launchpad_id"`
-	DestinationID int64     `json:"destination_id"`
-	LaunchDate    time.Time `json:"launch_date"`
+	ID            int           `json:"id"`
+	FirstName     string        `json:"first_name"`
+	LastName      string        `json:"last_name"`
+	Email         string        `json:"email,omitempty"`
+	Gender        string        `json:"gender"`
+	Birthday      time.Time     `json:"birthday"`
+	LaunchpadID   string        `json:"launchpad_id"`
+	DestinationID int64         `json:"destination_id"`
+	LaunchDate    time.Time     `json:"launch_date"`
+	Status        BookingStatus `json:"status"`
+	CreatedAt     time.Time     `json:"created_at"`
+
+	// The time the booking entered each status; CreatedAt stands for
+	// pending.
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	BoardedAt   *time.Time `json:"boarded_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	DisruptedAt *time.Time `json:"disrupted_at,omitempty"`
+	RebookedAt  *time.Time `json:"rebooked_at,omitempty"`
+	FlownAt     *time.Time `json:"flown_at,omitempty"`
+	ExpiredAt   *time.Time `json:"expired_at,omitempty"`
+
+	// DisruptionReason says why a disrupted booking can no longer fly.
+	DisruptionReason string `json:"disruption_reason,omitempty"`
+	// DisruptedBy is the source of the disruption: the conflict provider
+	// that reported the launchpad taken, e.g. "spacex", or DisruptedBySchedule.
+	DisruptedBy string `json:"disrupted_by,omitempty"`
+	// RebookedFrom is the booking this one replaces.
+	RebookedFrom *int `json:"rebooked_from,omitempty"`
+	// Code is the public reference of the booking, printed on its ticket;
+	// the ID is not shown to passengers.
+	Code string `json:"code"`
+	// Price is the fare paid, in cents of Currency. Bookings made before
+	// fares were introduced have none.
+	Price    int64  `json:"price_cents,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	// PromoCode is the code redeemed for the booking, and Discount what it
+	// took off the fare; Price is net of it.
+	PromoCode string `json:"promo_code,omitempty"`
+	Discount  int64  `json:"discount_cents,omitempty"`
+	// HoldUntil is when a pending booking offered from the waitlist
+	// expires; other pending bookings expire after the payment TTL.
+	HoldUntil *time.Time `json:"hold_until,omitempty"`
+	// HoldID is the seat hold the booking took its seat from, see SeatHold.
+	HoldID *int `json:"hold_id,omitempty"`
+	// HoldToken is the token of the seat hold a new booking takes its seat
+	// from; CreateBooking sets HoldID from it. It is never stored.
+	HoldToken string `json:"-"`
+}
+
+// BookingSummary is what anyone may see of a booking: its flight and
+// status, but neither the passenger nor the code that proves it is theirs.
+type BookingSummary struct {
+	LaunchpadID   string        `json:"launchpad_id"`
+	DestinationID int64         `json:"destination_id"`
+	LaunchDate    time.Time     `json:"launch_date"`
+	Status        BookingStatus `json:"status"`
+}
+
+// Summary returns the public part of the booking.
+func (b *Booking) Summary() BookingSummary {
+	return BookingSummary{
+		LaunchpadID:   b.LaunchpadID,
+		DestinationID: b.DestinationID,
+		LaunchDate:    b.LaunchDate,
+		Status:        b.Status,
+	}
+}
+
+// DisruptedBySchedule is the source of the disruptions of bookings whose
+// destination is no longer flown on their launch day.
+const DisruptedBySchedule = "schedule"
+
+// BookingFilter narrows down a list of bookings. Empty fields match all.
+type BookingFilter struct {
+	Statuses      []BookingStatus
+	LaunchpadID   string
+	DestinationID int64
+	// LaunchFrom and LaunchTo bound the launch dates, both included.
+	LaunchFrom, LaunchTo time.Time
+	// CreatedFrom and CreatedTo bound the creation times, from included and
+	// to excluded.
+	CreatedFrom, CreatedTo time.Time
+}
+
+// Age returns the age in whole years of someone born on birthday, on the
+// given day. People born on February 29 age on March 1 in common years.
+func Age(birthday, on time.Time) int {
+	if birthday.IsZero() {
+		return 0
+	}
+	by, bm, bd := birthday.Date()
+	y, m, d := on.Date()
+	age := y - by
+	if m < bm || (m == bm && d < bd) {
+		age--
+	}
+	return age
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAge(t *testing.T) {
+	leapling := time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		birthday time.Time
+		on       time.Time
+		want     int
+	}{
+		{"day before birthday", time.Date(1990, time.June, 15, 0, 0, 0, 0, time.UTC), time.Date(2049, time.June, 14, 0, 0, 0, 0, time.UTC), 58},
+		{"on birthday", time.Date(1990, time.June, 15, 0, 0, 0, 0, time.UTC), time.Date(2049, time.June, 15, 0, 0, 0, 0, time.UTC), 59},
+		{"leapling before March in a common year", leapling, time.Date(2049, time.February, 28, 0, 0, 0, 0, time.UTC), 48},
+		{"leapling on March 1 in a common year", leapling, time.Date(2049, time.March, 1, 0, 0, 0, 0, time.UTC), 49},
+		{"leapling on February 29", leapling, time.Date(2048, time.February, 29, 0, 0, 0, 0, time.UTC), 48},
+		{"unknown birthday", time.Time{}, time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Age(tt.birthday, tt.on))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "math"
+
+// RefundTerms are what cancelling a booking refunds of its fare.
+type RefundTerms struct {
+	// Share is the part of every captured payment refunded, from 0 to 1.
+	Share float64 `json:"refund_share"`
+	// Reason is recorded on the refunds.
+	Reason string `json:"reason,omitempty"`
+	// DisruptedBy flags the refunds owed to a disruption, see
+	// Booking.DisruptedBy.
+	DisruptedBy string `json:"disrupted_by,omitempty"`
+}
+
+// RefundAmount returns what the share of a payment of amount comes to,
+// less what was already refunded of it.
+func RefundAmount(amount, refunded int64, share float64) int64 {
+	owed := int64(math.Round(float64(amount)*math.Min(share, 1))) - refunded
+	if owed < 0 {
+		return 0
+	}
+	return owed
+}
+
+// CancellationQuote previews what cancelling a booking would refund.
+type CancellationQuote struct {
+	BookingID int           `json:"booking_id"`
+	Status    BookingStatus `json:"status"`
+	// DaysUntilLaunch is counted in calendar days (UTC), as the policy is.
+	DaysUntilLaunch int `json:"days_until_launch"`
+	RefundTerms
+	// Paid is what was captured for the booking, less the refunds so far.
+	Paid     int64  `json:"paid_cents"`
+	Refund   int64  `json:"refund_cents"`
+	Currency string `json:"currency,omitempty"`
+}
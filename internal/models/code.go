@@ -0,0 +1,34 @@
+package models
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// codeAlphabet leaves out the letters and digits that are easily confused,
+// such as O and 0 or I and 1.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// codeLength gives 50 random bits.
+const codeLength = 10
+
+// NewBookingCode returns a random booking code, the public reference of a
+// booking.
+func NewBookingCode() (string, error) {
+	buf := make([]byte, codeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := make([]byte, codeLength)
+	for i, b := range buf {
+		// 256 is a multiple of 32, so every letter is equally likely.
+		code[i] = codeAlphabet[int(b)%len(codeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// NormalizeBookingCode returns code as it is stored, so that codes read out
+// or typed in lower case are found.
+func NormalizeBookingCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,10 @@
+package models
+
+// Destination is a place flown to.
+type Destination struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// BasePrice is the fare before any adjustment, in cents; zero means
+	// the destination is not for sale.
+	BasePrice int64 `json:"base_price_cents"`
+}
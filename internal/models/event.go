@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BookingEventType is the kind of change recorded in a BookingEvent.
+type BookingEventType string
+
+const (
+	EventCreated       BookingEventType = "created"
+	EventCancelled     BookingEventType = "cancelled"
+	EventDisrupted     BookingEventType = "disrupted"
+	EventRebooked      BookingEventType = "rebooked"
+	EventStatusChanged BookingEventType = "status_changed"
+)
+
+// StatusEvent returns the event recorded when a booking moves to status.
+func StatusEvent(status BookingStatus) BookingEventType {
+	switch status {
+	case StatusCancelled:
+		return EventCancelled
+	case StatusDisrupted:
+		return EventDisrupted
+	case StatusRebooked:
+		return EventRebooked
+	}
+	return EventStatusChanged
+}
+
+// BookingEvent is an entry of the audit log of a booking. Entries are
+// never changed once written.
+type BookingEvent struct {
+	ID        int64            `json:"id"`
+	BookingID int              `json:"booking_id"`
+	Type      BookingEventType `json:"type"`
+	// Actor names who made the change, see the actor package.
+	Actor     string `json:"actor"`
+	RequestID string `json:"request_id,omitempty"`
+	// Before and After are the booking as JSON around the change. Before is
+	// empty for a new booking.
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
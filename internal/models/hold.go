@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"space-booking/internal/clock"
+)
+
+// Seat hold statuses.
+const (
+	// HoldActive is a hold whose seats are reserved until it expires.
+	HoldActive = "active"
+	// HoldConverted is a hold all of whose seats were booked.
+	HoldConverted = "converted"
+	// HoldReleased is a hold given up before all its seats were booked.
+	HoldReleased = "released"
+	// HoldExpired is a hold that ran out before all its seats were booked.
+	HoldExpired = "expired"
+)
+
+// SeatHold reserves seats on a flight while a customer checks out. Its
+// seats count against the capacity of the flight until they are booked or
+// the hold ends.
+type SeatHold struct {
+	ID int `json:"id"`
+	// Token is the secret the customer who made the hold reads, releases
+	// and books it with. Like a booking code it cannot be guessed.
+	Token         string    `json:"token"`
+	LaunchpadID   string    `json:"launchpad_id"`
+	DestinationID int64     `json:"destination_id"`
+	LaunchDate    time.Time `json:"launch_date"`
+	// Seats is how many seats are held, and Booked how many of them have
+	// been turned into bookings.
+	Seats     int        `json:"seats"`
+	Booked    int        `json:"booked"`
+	Status    string     `json:"status"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// HoldError rejects booking a seat of a hold. Its message is safe to return
+// to the client, and leaves out the ID of the hold, which is 0 when no hold
+// has the token given.
+type HoldError struct {
+	ID     int
+	Reason string
+}
+
+func (e *HoldError) Error() string {
+	return fmt.Sprintf("Seat hold %s.", e.Reason)
+}
+
+// Check returns a *HoldError when booking cannot take a seat from the hold
+// at now: the hold has to be active, on the booking's flight and have a
+// seat left.
+func (h *SeatHold) Check(booking *Booking, now time.Time) error {
+	reason := ""
+	switch {
+	case h.Status != HoldActive:
+		reason = "is " + h.Status
+	case !now.Before(h.ExpiresAt):
+		reason = "has expired"
+	case h.LaunchpadID != booking.LaunchpadID || h.DestinationID != booking.DestinationID ||
+		!clock.Day(h.LaunchDate).Equal(clock.Day(booking.LaunchDate)):
+		reason = "is for another flight"
+	case h.Booked >= h.Seats:
+		reason = "has no seats left"
+	default:
+		return nil
+	}
+	return &HoldError{ID: h.ID, Reason: reason}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Manifest lists the passengers flying from a launchpad on a day.
+type Manifest struct {
+	LaunchpadID string    `json:"launchpad_id"`
+	LaunchDate  time.Time `json:"launch_date"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Passengers is the number of passengers on all destinations.
+	Passengers   int                   `json:"passengers"`
+	Destinations []ManifestDestination `json:"destinations"`
+}
+
+// ManifestDestination groups the passengers of a manifest flying to one
+// destination.
+type ManifestDestination struct {
+	DestinationID int64               `json:"destination_id"`
+	Name          string              `json:"name"`
+	Passengers    []ManifestPassenger `json:"passengers"`
+}
+
+// ManifestPassenger is one line of a manifest.
+type ManifestPassenger struct {
+	Code      string    `json:"code"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Gender    string    `json:"gender"`
+	Birthday  time.Time `json:"birthday"`
+	// Age is the age of the passenger on the launch day.
+	Age         int           `json:"age"`
+	Status      BookingStatus `json:"status"`
+	CheckedInAt *time.Time    `json:"checked_in_at,omitempty"`
+	BoardedAt   *time.Time    `json:"boarded_at,omitempty"`
+}
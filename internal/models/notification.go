@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Notification kinds.
+const (
+	// NotificationConfirmation tells the passenger their booking is
+	// confirmed.
+	NotificationConfirmation = "confirmation"
+	// NotificationSeatOffered tells a waitlisted passenger a seat is held
+	// for them until they pay for it.
+	NotificationSeatOffered = "seat_offered"
+)
+
+// Notification statuses.
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// Notification is an email about a booking, across all attempts to send
+// it.
+type Notification struct {
+	ID            int64      `json:"id"`
+	BookingID     int        `json:"booking_id"`
+	Kind          string     `json:"kind"`
+	Recipient     string     `json:"recipient"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// DueNotification is a notification claimed for an attempt, with what is
+// needed to render it.
+type DueNotification struct {
+	Notification    Notification
+	Booking         Booking
+	DestinationName string
+}
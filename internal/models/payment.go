@@ -0,0 +1,84 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Payment statuses.
+const (
+	// PaymentPending is a payment waiting for the provider's answer.
+	PaymentPending = "pending"
+	// PaymentCapturing is an authorized payment whose capture was sent to
+	// the provider, and whose outcome is not recorded yet: the money may
+	// have been taken. The capture is retried until it is.
+	PaymentCapturing = "capturing"
+	// PaymentCaptured is a payment whose money was taken.
+	PaymentCaptured = "captured"
+	// PaymentFailed is a payment that was declined or given up.
+	PaymentFailed = "failed"
+)
+
+// Payment is an attempt to pay the fare of a booking through a payment
+// provider.
+type Payment struct {
+	ID        int64  `json:"id"`
+	BookingID int    `json:"booking_id"`
+	Provider  string `json:"provider"`
+	// ProviderRef is the provider's ID of the payment, known once it
+	// answered.
+	ProviderRef string `json:"provider_ref,omitempty"`
+	Amount      int64  `json:"amount_cents"`
+	Currency    string `json:"currency"`
+	Status      string `json:"status"`
+	// FailureReason says why the payment failed, or why the last attempt
+	// to capture it did.
+	FailureReason string     `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CapturedAt    *time.Time `json:"captured_at,omitempty"`
+	// CaptureAttempts counts the failed attempts to capture the payment,
+	// and NextCaptureAt is when a capturing payment is tried again.
+	CaptureAttempts int        `json:"capture_attempts,omitempty"`
+	NextCaptureAt   *time.Time `json:"next_capture_at,omitempty"`
+	Refunds         []Refund   `json:"refunds,omitempty"`
+}
+
+// Reference returns how the payment is named to the provider, which
+// echoes it in the events it posts.
+func (p *Payment) Reference() string {
+	return strconv.FormatInt(p.ID, 10)
+}
+
+// Refund statuses.
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// Refund gives back part or all of a captured payment, across all
+// attempts to issue it.
+type Refund struct {
+	ID        int64  `json:"id"`
+	PaymentID int64  `json:"payment_id"`
+	Amount    int64  `json:"amount_cents"`
+	Reason    string `json:"reason"`
+	// DisruptedBy flags a refund owed to a disruption with its source, see
+	// Booking.DisruptedBy.
+	DisruptedBy   string     `json:"disrupted_by,omitempty"`
+	Status        string     `json:"status"`
+	ProviderRef   string     `json:"provider_ref,omitempty"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	RefundedAt    *time.Time `json:"refunded_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// DueRefund is a refund claimed for an attempt, with the payment it gives
+// back.
+type DueRefund struct {
+	Refund  Refund
+	Payment Payment
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Promo code kinds.
+const (
+	// PromoPercent takes a percentage off the fare.
+	PromoPercent = "percent"
+	// PromoFixed takes a fixed amount off the fare.
+	PromoFixed = "fixed"
+)
+
+// PromoCode is a discount on fares, handed out for marketing promotions.
+type PromoCode struct {
+	ID int `json:"id"`
+	// Code is what passengers enter, in upper case.
+	Code string `json:"code"`
+	Kind string `json:"kind"`
+	// Value is the percentage off of a percent code, and the cents of
+	// Currency off of a fixed one.
+	Value    int64  `json:"value"`
+	Currency string `json:"currency,omitempty"`
+	// ValidFrom and ValidUntil bound when the code can be redeemed, from
+	// included and until excluded; nil leaves that side open.
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	// DestinationIDs restrict the code to flights to them; empty is all.
+	DestinationIDs []int64 `json:"destination_ids"`
+	// MaxRedemptions caps the bookings the code is redeemed for; 0 is no
+	// cap. Redemptions counts them, less those released by bookings that
+	// were never paid.
+	MaxRedemptions int       `json:"max_redemptions"`
+	Redemptions    int       `json:"redemptions"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// NormalizePromoCode returns code as it is stored.
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// PromoError rejects a promo code. Its message is safe to return to the
+// client.
+type PromoError struct {
+	Code   string
+	Reason string
+}
+
+func (e *PromoError) Error() string {
+	return fmt.Sprintf("Promo code %s %s.", e.Code, e.Reason)
+}
+
+// Check returns a *PromoError when the code cannot be redeemed at now for
+// a flight to the destination. The one-per-passenger rule is checked when
+// the code is redeemed.
+func (p *PromoCode) Check(destinationID int64, now time.Time) error {
+	reason := ""
+	switch {
+	case !p.Active:
+		reason = "is no longer valid"
+	case p.ValidFrom != nil && now.Before(*p.ValidFrom):
+		reason = "is not valid yet"
+	case p.ValidUntil != nil && !now.Before(*p.ValidUntil):
+		reason = "has expired"
+	case len(p.DestinationIDs) > 0 && !slices.Contains(p.DestinationIDs, destinationID):
+		reason = "is not valid for this destination"
+	case p.MaxRedemptions > 0 && p.Redemptions >= p.MaxRedemptions:
+		reason = "has been used up"
+	default:
+		return nil
+	}
+	return &PromoError{Code: p.Code, Reason: reason}
+}
+
+// Discount returns what the code takes off a fare in currency, rounded to
+// the cent and never more than the fare. A fixed discount in another
+// currency takes nothing off.
+func (p *PromoCode) Discount(fare int64, currency string) int64 {
+	var discount int64
+	switch p.Kind {
+	case PromoPercent:
+		discount = int64(math.Round(float64(fare) * float64(p.Value) / 100))
+	case PromoFixed:
+		if p.Currency == currency {
+			discount = p.Value
+		}
+	}
+	return min(discount, fare)
+}
+
+// PassengerKey names the passenger of a booking for the one-per-passenger
+// rule of promo codes: their name in lower case and their birthday.
+func PassengerKey(b *Booking) string {
+	return strings.ToLower(strings.TrimSpace(b.FirstName)) + "|" +
+		strings.ToLower(strings.TrimSpace(b.LastName)) + "|" +
+		b.Birthday.Format("2006-01-02")
+}
+
+// PromoRedemption is a promo code redeemed for a booking.
+type PromoRedemption struct {
+	ID          int64         `json:"id"`
+	PromoCodeID int           `json:"promo_code_id"`
+	BookingID   int           `json:"booking_id"`
+	Status      BookingStatus `json:"booking_status"`
+	Discount    int64         `json:"discount_cents"`
+	Currency    string        `json:"currency"`
+	RedeemedAt  time.Time     `json:"redeemed_at"`
+	// ReleasedAt is set when the booking expired or was cancelled before
+	// it was paid, which gave the redemption back.
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// PromoReport sums up the redemptions of a promo code that were not
+// released.
+type PromoReport struct {
+	PromoCodeID    int    `json:"promo_code_id"`
+	Code           string `json:"code"`
+	Kind           string `json:"kind"`
+	Value          int64  `json:"value"`
+	MaxRedemptions int    `json:"max_redemptions"`
+	Redemptions    int    `json:"redemptions"`
+	// Discount is the total taken off the fares, Revenue the total of the
+	// discounted fares.
+	Discount int64  `json:"discount_cents"`
+	Revenue  int64  `json:"revenue_cents"`
+	Currency string `json:"currency,omitempty"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromoCodeCheck(t *testing.T) {
+	from := time.Date(2049, time.December, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2050, time.January, 1, 0, 0, 0, 0, time.UTC)
+	code := func(mod func(p *PromoCode)) *PromoCode {
+		p := &PromoCode{
+			Code: "LAUNCH25", Kind: PromoPercent, Value: 25, ValidFrom: &from, ValidUntil: &until,
+			DestinationIDs: []int64{1, 2}, MaxRedemptions: 10, Redemptions: 9, Active: true,
+		}
+		if mod != nil {
+			mod(p)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name        string
+		code        *PromoCode
+		destination int64
+		now         time.Time
+		want        string
+	}{
+		{"valid", code(nil), 1, from, ""},
+		{"inactive", code(func(p *PromoCode) { p.Active = false }), 1, from, "Promo code LAUNCH25 is no longer valid."},
+		{"too early", code(nil), 1, from.Add(-time.Second), "Promo code LAUNCH25 is not valid yet."},
+		{"expired", code(nil), 1, until, "Promo code LAUNCH25 has expired."},
+		{"other destination", code(nil), 3, from, "Promo code LAUNCH25 is not valid for this destination."},
+		{"any destination", code(func(p *PromoCode) { p.DestinationIDs = nil }), 3, from, ""},
+		{"used up", code(func(p *PromoCode) { p.Redemptions = 10 }), 1, from, "Promo code LAUNCH25 has been used up."},
+		{"no cap", code(func(p *PromoCode) { p.MaxRedemptions, p.Redemptions = 0, 1000 }), 1, from, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.code.Check(tt.destination, tt.now)
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var perr *PromoError
+			assert.True(t, errors.As(err, &perr))
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestPromoCodeDiscount(t *testing.T) {
+	percent := &PromoCode{Kind: PromoPercent, Value: 15}
+	assert.Equal(t, int64(15000), percent.Discount(100000, "USD"))
+	assert.Equal(t, int64(2), percent.Discount(15, "USD"), "Expected the discount rounded to the cent")
+
+	fixed := &PromoCode{Kind: PromoFixed, Value: 5000000, Currency: "USD"}
+	assert.Equal(t, int64(5000000), fixed.Discount(100000000, "USD"))
+	assert.Equal(t, int64(3000000), fixed.Discount(3000000, "USD"), "Expected no more than the fare off")
+	assert.Equal(t, int64(0), fixed.Discount(100000000, "EUR"))
+}
+
+func TestPassengerKey(t *testing.T) {
+	birthday := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+	a := PassengerKey(&Booking{FirstName: "Ada ", LastName: "LOVELACE", Birthday: birthday})
+	b := PassengerKey(&Booking{FirstName: "ada", LastName: "Lovelace", Birthday: birthday})
+	assert.Equal(t, "ada|lovelace|1990-01-01", a)
+	assert.Equal(t, a, b)
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Quote is a fare offered for a flight to a passenger until it expires.
+// Its ID carries the terms, signed, so quotes are not stored.
+type Quote struct {
+	ID            string    `json:"id"`
+	LaunchpadID   string    `json:"launchpad_id"`
+	DestinationID int64     `json:"destination_id"`
+	LaunchDate    time.Time `json:"launch_date"`
+	Birthday      time.Time `json:"birthday"`
+	// Price is in cents of Currency, net of the Discount of the PromoCode.
+	Price     int64         `json:"price_cents"`
+	Currency  string        `json:"currency"`
+	PromoCode string        `json:"promo_code,omitempty"`
+	Discount  int64         `json:"discount_cents,omitempty"`
+	Breakdown FareBreakdown `json:"breakdown"`
+	IssuedAt  time.Time     `json:"issued_at"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// FareBreakdown shows how a fare was computed: the base price of the
+// destination times the advance, load and age factors.
+type FareBreakdown struct {
+	BasePrice       int64   `json:"base_price_cents"`
+	DaysUntilLaunch int     `json:"days_until_launch"`
+	AdvanceFactor   float64 `json:"advance_factor"`
+	// SeatsSold and Seats give the load of the flight before this booking.
+	SeatsSold  int     `json:"seats_sold"`
+	Seats      int     `json:"seats"`
+	LoadFactor float64 `json:"load_factor"`
+	Age        int     `json:"age"`
+	AgeFactor  float64 `json:"age_factor"`
+}
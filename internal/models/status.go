@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BookingStatus is the state of a booking in its lifecycle.
+type BookingStatus string
+
+const (
+	// StatusPending is a booking that is not confirmed yet.
+	StatusPending BookingStatus = "pending"
+	// StatusConfirmed is a booking that will fly.
+	StatusConfirmed BookingStatus = "confirmed"
+	// StatusCheckedIn is a booking whose passenger has checked in.
+	StatusCheckedIn BookingStatus = "checked_in"
+	// StatusBoarded is a booking whose passenger has passed the gate.
+	StatusBoarded BookingStatus = "boarded"
+	// StatusCancelled is a booking that was called off.
+	StatusCancelled BookingStatus = "cancelled"
+	// StatusDisrupted is a booking that can no longer fly as booked.
+	StatusDisrupted BookingStatus = "disrupted"
+	// StatusRebooked is a booking replaced by another one.
+	StatusRebooked BookingStatus = "rebooked"
+	// StatusFlown is a booking whose passenger has launched.
+	StatusFlown BookingStatus = "flown"
+	// StatusExpired is a pending booking that was not paid in time.
+	StatusExpired BookingStatus = "expired"
+)
+
+// transitions lists the statuses each status may move to. Cancelled,
+// rebooked, flown and expired bookings are final.
+var transitions = map[BookingStatus][]BookingStatus{
+	StatusPending:   {StatusConfirmed, StatusCancelled, StatusDisrupted, StatusExpired},
+	StatusConfirmed: {StatusCheckedIn, StatusCancelled, StatusDisrupted, StatusRebooked, StatusFlown},
+	StatusCheckedIn: {StatusBoarded, StatusCancelled, StatusDisrupted, StatusRebooked},
+	StatusBoarded:   {StatusFlown, StatusDisrupted},
+	StatusDisrupted: {StatusConfirmed, StatusCancelled, StatusRebooked},
+	StatusCancelled: nil,
+	StatusRebooked:  nil,
+	StatusFlown:     nil,
+	StatusExpired:   nil,
+}
+
+// ActiveStatuses are the statuses of bookings that still hold a seat.
+var ActiveStatuses = []BookingStatus{StatusPending, StatusConfirmed, StatusCheckedIn, StatusBoarded}
+
+// ParseBookingStatus returns the status named s.
+func ParseBookingStatus(s string) (BookingStatus, error) {
+	status := BookingStatus(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := transitions[status]; !ok {
+		return "", fmt.Errorf("unknown booking status %q", s)
+	}
+	return status, nil
+}
+
+// CanTransitionTo reports whether a booking may move from s to next.
+func (s BookingStatus) CanTransitionTo(next BookingStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned for a status change the state machine does
+// not allow.
+type TransitionError struct {
+	From BookingStatus
+	To   BookingStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("booking cannot move from %s to %s", e.From, e.To)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to BookingStatus
+		allowed  bool
+	}{
+		{StatusPending, StatusConfirmed, true},
+		{StatusPending, StatusExpired, true},
+		{StatusConfirmed, StatusCancelled, true},
+		{StatusConfirmed, StatusDisrupted, true},
+		{StatusDisrupted, StatusRebooked, true},
+		{StatusConfirmed, StatusFlown, true},
+		{StatusConfirmed, StatusCheckedIn, true},
+		{StatusCheckedIn, StatusBoarded, true},
+		{StatusBoarded, StatusFlown, true},
+		{StatusConfirmed, StatusBoarded, false},
+		{StatusBoarded, StatusBoarded, false},
+		{StatusBoarded, StatusCancelled, false},
+		{StatusConfirmed, StatusPending, false},
+		{StatusConfirmed, StatusConfirmed, false},
+		{StatusCancelled, StatusConfirmed, false},
+		{StatusFlown, StatusCancelled, false},
+		{StatusRebooked, StatusDisrupted, false},
+		{StatusConfirmed, StatusExpired, false},
+		{StatusExpired, StatusConfirmed, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, tt.from.CanTransitionTo(tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+func TestParseBookingStatus(t *testing.T) {
+	status, err := ParseBookingStatus(" Confirmed ")
+	require.NoError(t, err)
+	assert.Equal(t, StatusConfirmed, status)
+
+	_, err = ParseBookingStatus("boarding")
+	assert.EqualError(t, err, `unknown booking status "boarding"`)
+}
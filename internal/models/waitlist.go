@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// Waitlist entry statuses.
+const (
+	// WaitlistWaiting is an entry waiting for a seat.
+	WaitlistWaiting = "waiting"
+	// WaitlistPromoted is an entry that was offered a seat, held by a
+	// pending booking until the passenger pays for it or the hold ends.
+	WaitlistPromoted = "promoted"
+	// WaitlistLeft is an entry the passenger withdrew.
+	WaitlistLeft = "left"
+	// WaitlistExpired is an entry whose flight launched before a seat
+	// freed up.
+	WaitlistExpired = "expired"
+)
+
+// WaitlistEntry is a passenger waiting for a seat on a flight that is sold
+// out or blocked. Entries are promoted in the order they joined.
+type WaitlistEntry struct {
+	ID int `json:"id"`
+	// Code is the public reference of the entry, like a booking code: it
+	// proves the passenger is the one who joined.
+	Code          string    `json:"code"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Email         string    `json:"email"`
+	Gender        string    `json:"gender"`
+	Birthday      time.Time `json:"birthday"`
+	LaunchpadID   string    `json:"launchpad_id"`
+	DestinationID int64     `json:"destination_id"`
+	LaunchDate    time.Time `json:"launch_date"`
+	Status        string    `json:"status"`
+	// Position is the place of a waiting entry in the queue of its flight,
+	// from 1.
+	Position int `json:"position,omitempty"`
+	// BookingID is the booking holding the seat offered to a promoted
+	// entry, until HoldUntil.
+	BookingID  *int       `json:"booking_id,omitempty"`
+	HoldUntil  *time.Time `json:"hold_until,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	PromotedAt *time.Time `json:"promoted_at,omitempty"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+}
+
+// WaitlistSummary is what the holder of a waitlist code sees of the entry:
+// its flight and place in the queue, but not the passenger.
+type WaitlistSummary struct {
+	Code          string     `json:"code"`
+	LaunchpadID   string     `json:"launchpad_id"`
+	DestinationID int64      `json:"destination_id"`
+	LaunchDate    time.Time  `json:"launch_date"`
+	Status        string     `json:"status"`
+	Position      int        `json:"position,omitempty"`
+	HoldUntil     *time.Time `json:"hold_until,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PromotedAt    *time.Time `json:"promoted_at,omitempty"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+}
+
+// Summary returns the public part of the entry.
+func (e *WaitlistEntry) Summary() WaitlistSummary {
+	return WaitlistSummary{
+		Code:          e.Code,
+		LaunchpadID:   e.LaunchpadID,
+		DestinationID: e.DestinationID,
+		LaunchDate:    e.LaunchDate,
+		Status:        e.Status,
+		Position:      e.Position,
+		HoldUntil:     e.HoldUntil,
+		CreatedAt:     e.CreatedAt,
+		PromotedAt:    e.PromotedAt,
+		ClosedAt:      e.ClosedAt,
+	}
+}
+
+// Booking returns the booking offered to the entry's passenger on its
+// flight, pending until holdUntil.
+func (e *WaitlistEntry) Booking(holdUntil time.Time) *Booking {
+	return &Booking{
+		FirstName:     e.FirstName,
+		LastName:      e.LastName,
+		Email:         e.Email,
+		Gender:        e.Gender,
+		Birthday:      e.Birthday,
+		LaunchpadID:   e.LaunchpadID,
+		DestinationID: e.DestinationID,
+		LaunchDate:    e.LaunchDate,
+		Status:        StatusPending,
+		HoldUntil:     &holdUntil,
+	}
+}
+
+// WaitlistFlight is a flight that passengers are waiting for.
+type WaitlistFlight struct {
+	LaunchpadID   string
+	DestinationID int64
+	LaunchDate    time.Time
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outbox message types for changes to the launch schedule. Their payload
+// is the blackout.
+const (
+	BlackoutCreated = "schedule.blackout_created"
+	BlackoutUpdated = "schedule.blackout_updated"
+	BlackoutDeleted = "schedule.blackout_deleted"
+)
+
+// ManifestPublished is the outbox message type of a flight manifest handed
+// to range control ahead of the launch. Its payload is the Manifest.
+const ManifestPublished = "manifest.published"
+
+// SeatOffered is the outbox message type of a waitlist entry promoted to
+// a pending booking. Its payload is the WaitlistEntry.
+const SeatOffered = "waitlist.seat_offered"
+
+// WebhookEventTypes are the message types published through the outbox:
+// one per BookingEventType, the schedule changes, manifests and waitlist
+// offers.
+var WebhookEventTypes = []string{
+	OutboxType(EventCreated),
+	OutboxType(EventCancelled),
+	OutboxType(EventDisrupted),
+	OutboxType(EventRebooked),
+	OutboxType(EventStatusChanged),
+	BlackoutCreated,
+	BlackoutUpdated,
+	BlackoutDeleted,
+	ManifestPublished,
+	SeatOffered,
+}
+
+// OutboxType returns the type a booking event is published as, e.g.
+// "booking.cancelled".
+func OutboxType(eventType BookingEventType) string {
+	return "booking." + string(eventType)
+}
+
+// OutboxMessage is a booking or schedule change waiting to be, or
+// already, published.
+type OutboxMessage struct {
+	ID int64 `json:"id"`
+	// XID is the transaction that wrote the message.
+	XID  int64  `json:"-"`
+	Type string `json:"type"`
+	// BookingID is zero for schedule changes.
+	BookingID int `json:"booking_id,omitempty"`
+	// Payload is the booking after the change, the blackout or the
+	// manifest, as JSON.
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// WebhookSubscription is a partner endpoint receiving booking changes.
+type WebhookSubscription struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// Secret signs the deliveries. It is only shown when the subscription
+	// is created.
+	Secret string `json:"secret,omitempty"`
+	// EventTypes are the message types delivered; empty means all.
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is the delivery of one outbox message to one
+// subscription, across all its attempts.
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int        `json:"subscription_id"`
+	OutboxID       int64      `json:"outbox_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// DueDelivery is a delivery claimed for an attempt, with what is needed to
+// make it.
+type DueDelivery struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+	Message  OutboxMessage
+}
+
+// Position returns where the message is in the outbox stream.
+func (m OutboxMessage) Position() OutboxPosition {
+	return OutboxPosition{XID: m.XID, ID: m.ID}
+}
+
+// OutboxPosition is a place in the outbox stream, which is ordered by the
+// transaction that wrote a message, then by its ID. Unlike IDs, which are
+// taken before commit, transactions only ever end after the ones whose
+// messages were streamed, so a stream following positions misses nothing.
+type OutboxPosition struct {
+	XID int64
+	ID  int64
+}
+
+// String returns the position as an event ID, e.g. "1042-7".
+func (p OutboxPosition) String() string {
+	return fmt.Sprintf("%d-%d", p.XID, p.ID)
+}
+
+// ParseOutboxPosition reads a position written by String.
+func ParseOutboxPosition(s string) (OutboxPosition, error) {
+	xid, id, ok := strings.Cut(s, "-")
+	var p OutboxPosition
+	var err error
+	if ok {
+		p.XID, err = strconv.ParseInt(xid, 10, 64)
+	}
+	if ok && err == nil {
+		p.ID, err = strconv.ParseInt(id, 10, 64)
+	}
+	if !ok || err != nil || p.XID < 0 || p.ID < 0 {
+		return OutboxPosition{}, fmt.Errorf("invalid outbox position %q", s)
+	}
+	return p, nil
+}
+
+// OutboxFilter selects outbox messages.
+type OutboxFilter struct {
+	// After is the position of the last message already seen.
+	After OutboxPosition
+	// Types are the message types to return; empty means all.
+	Types []string
+	// Bookings limits the messages to those about these bookings, and the
+	// schedule changes; nil means all.
+	Bookings []int
+	Limit    int
+}
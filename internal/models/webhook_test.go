@@ -0,0 +1,20 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutboxPosition(t *testing.T) {
+	p, err := ParseOutboxPosition("1042-7")
+	require.NoError(t, err)
+	assert.Equal(t, OutboxPosition{XID: 1042, ID: 7}, p)
+	assert.Equal(t, "1042-7", p.String())
+
+	for _, s := range []string{"", "7", "x-7", "1042-", "-1-7"} {
+		_, err := ParseOutboxPosition(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+)
+
+// Store is the part of the database the sender works on.
+type Store interface {
+	ClaimNotifications(ctx context.Context, limit int, lease time.Duration) ([]models.DueNotification, error)
+	RecordNotificationAttempt(ctx context.Context, notification *models.Notification) error
+}
+
+// Sender sends the queued notifications. Bookings only queue them, so a
+// mail server that is down delays the emails but never fails a booking.
+// Failed attempts are retried with exponential backoff until the attempts
+// run out.
+type Sender struct {
+	store       Store
+	mailer      Mailer
+	clock       clock.Clock
+	logger      *log.Logger
+	batch       int
+	maxAttempts int
+	timeout     time.Duration
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// Option configures a Sender built by New.
+type Option func(*Sender)
+
+// WithMaxAttempts sets how many attempts a notification gets before it
+// fails.
+func WithMaxAttempts(n int) Option {
+	return func(s *Sender) { s.maxAttempts = n }
+}
+
+// WithBackoff sets the delay before the second attempt and the cap it
+// doubles up to.
+func WithBackoff(min, max time.Duration) Option {
+	return func(s *Sender) {
+		s.minBackoff = min
+		s.maxBackoff = max
+	}
+}
+
+// New returns a Sender.
+func New(store Store, mailer Mailer, clk clock.Clock, logger *log.Logger, opts ...Option) *Sender {
+	if logger == nil {
+		logger = log.Default()
+	}
+	s := &Sender{
+		store:       store,
+		mailer:      mailer,
+		clock:       clk,
+		logger:      logger,
+		batch:       20,
+		maxAttempts: 5,
+		timeout:     30 * time.Second,
+		minBackoff:  time.Minute,
+		maxBackoff:  time.Hour,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// RunOnce sends one batch of due notifications, one after the other, and
+// returns how many were sent.
+func (s *Sender) RunOnce(ctx context.Context) (int, error) {
+	lease := time.Duration(s.batch) * s.timeout
+	due, err := s.store.ClaimNotifications(ctx, s.batch, lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim notifications: %w", err)
+	}
+
+	sent := 0
+	for _, job := range due {
+		ok, err := s.send(ctx, job)
+		if err != nil {
+			return sent, err
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// send makes one attempt and records its outcome. It reports whether the
+// email was sent; the error is about recording it.
+func (s *Sender) send(ctx context.Context, job models.DueNotification) (bool, error) {
+	n := job.Notification
+	err := s.attempt(ctx, job)
+	now := s.clock.Now()
+
+	n.Attempts++
+	n.LastError = ""
+	switch {
+	case err == nil:
+		n.Status = models.NotificationSent
+		n.SentAt = &now
+	case n.Attempts >= s.maxAttempts:
+		n.Status = models.NotificationFailed
+		n.LastError = err.Error()
+		s.logger.Printf("Notification %d to %s failed for good: %v", n.ID, n.Recipient, err)
+	default:
+		n.LastError = err.Error()
+		n.NextAttemptAt = now.Add(s.backoff(n.Attempts))
+	}
+
+	if err := s.store.RecordNotificationAttempt(ctx, &n); err != nil {
+		return false, fmt.Errorf("record notification %d: %w", n.ID, err)
+	}
+	return n.Status == models.NotificationSent, nil
+}
+
+func (s *Sender) attempt(ctx context.Context, job models.DueNotification) error {
+	msg, err := Render(job)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.mailer.Send(ctx, msg)
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (s *Sender) backoff(attempts int) time.Duration {
+	delay := s.minBackoff << (attempts - 1)
+	if delay > s.maxBackoff || delay <= 0 {
+		delay = s.maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore serves fixed notifications and keeps the recorded attempts.
+type fakeStore struct {
+	due      []models.DueNotification
+	recorded map[int64]models.Notification
+}
+
+func (s *fakeStore) ClaimNotifications(context.Context, int, time.Duration) ([]models.DueNotification, error) {
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *fakeStore) RecordNotificationAttempt(_ context.Context, n *models.Notification) error {
+	s.recorded[n.ID] = *n
+	return nil
+}
+
+// fakeMailer keeps the sent messages and fails for some recipients.
+type fakeMailer struct {
+	sent    []Message
+	failing map[string]bool
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg Message) error {
+	if m.failing[msg.To] {
+		return errors.New("mail server unreachable")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func dueConfirmation(id int64, recipient string, attempts int) models.DueNotification {
+	return models.DueNotification{
+		Notification: models.Notification{
+			ID: id, BookingID: 7, Kind: models.NotificationConfirmation, Recipient: recipient,
+			Status: models.NotificationPending, Attempts: attempts,
+		},
+		Booking: models.Booking{
+			ID: 7, FirstName: "Ada", LastName: "<Lovelace>", LaunchpadID: "pad_a", DestinationID: 1,
+			LaunchDate: time.Date(2049, time.December, 20, 0, 0, 0, 0, time.UTC),
+		},
+		DestinationName: "Mars",
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	store := &fakeStore{
+		recorded: make(map[int64]models.Notification),
+		due: []models.DueNotification{
+			dueConfirmation(1, "ada@example.com", 0),
+			dueConfirmation(2, "down@example.com", 0),
+			dueConfirmation(3, "down@example.com", 2),
+		},
+	}
+	mailer := &fakeMailer{failing: map[string]bool{"down@example.com": true}}
+	now := time.Date(2049, time.December, 1, 9, 30, 0, 0, time.UTC)
+	s := New(store, mailer, clock.NewFake(now), nil, WithMaxAttempts(3), WithBackoff(time.Minute, time.Hour))
+
+	n, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Len(t, mailer.sent, 1)
+	msg := mailer.sent[0]
+	assert.Equal(t, "ada@example.com", msg.To)
+	assert.Equal(t, "Your flight to Mars on 2049-12-20 is confirmed", msg.Subject)
+	assert.Contains(t, msg.Text, "Hello Ada <Lovelace>,")
+	assert.Contains(t, msg.Text, "Launch date: Monday, December 20, 2049")
+	assert.Contains(t, msg.HTML, "Hello Ada &lt;Lovelace&gt;,", "Expected the HTML to be escaped")
+	assert.Contains(t, msg.HTML, "<td>pad_a</td>")
+
+	sent := store.recorded[1]
+	assert.Equal(t, models.NotificationSent, sent.Status)
+	assert.Equal(t, &now, sent.SentAt)
+
+	retried := store.recorded[2]
+	assert.Equal(t, models.NotificationPending, retried.Status)
+	assert.Equal(t, 1, retried.Attempts)
+	assert.Equal(t, now.Add(time.Minute), retried.NextAttemptAt)
+	assert.Equal(t, "mail server unreachable", retried.LastError)
+
+	assert.Equal(t, models.NotificationFailed, store.recorded[3].Status, "Expected the last attempt to fail for good")
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "SpaceTrouble <bookings@example.com>")
+	require.NoError(t, err)
+
+	msg, err := Render(dueConfirmation(1, "ada@example.com", 0))
+	require.NoError(t, err)
+	require.NoError(t, m.Send(context.Background(), msg))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	email := string(data)
+	assert.True(t, strings.HasPrefix(email, "From: SpaceTrouble <bookings@example.com>\r\nTo: ada@example.com\r\n"))
+	assert.Contains(t, email, "Content-Type: multipart/alternative")
+	assert.Contains(t, email, "Content-Type: text/plain; charset=utf-8")
+	assert.Contains(t, email, "Content-Type: text/html; charset=utf-8")
+}
+
+func TestRenderSeatOffered(t *testing.T) {
+	job := dueConfirmation(1, "ada@example.com", 0)
+	holdUntil := time.Date(2049, time.December, 2, 9, 30, 0, 0, time.UTC)
+	job.Notification.Kind = models.NotificationSeatOffered
+	job.Booking.Code, job.Booking.Price, job.Booking.Currency = "K7QX2MWP9D", 100000050, "USD"
+	job.Booking.HoldUntil = &holdUntil
+
+	msg, err := Render(job)
+	require.NoError(t, err)
+	assert.Equal(t, "A seat to Mars on 2049-12-20 is held for you", msg.Subject)
+	assert.Contains(t, msg.Text, "until Thursday, December 2, 2049 at 09:30 UTC")
+	assert.Contains(t, msg.Text, "Fare:        1000000.50 USD")
+	assert.Contains(t, msg.HTML, "&lt;Lovelace&gt;")
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"space-booking/internal/models"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+)
+
+// subjects are the subject lines per notification kind.
+var subjects = map[string]string{
+	models.NotificationConfirmation: "Your flight to %s on %s is confirmed",
+	models.NotificationSeatOffered:  "A seat to %s on %s is held for you",
+}
+
+// templateData is what the templates are rendered with.
+type templateData struct {
+	Code        string
+	FirstName   string
+	LastName    string
+	Destination string
+	LaunchpadID string
+	LaunchDate  string
+	// Fare and HoldUntil are set for a seat offered from the waitlist.
+	Fare      string
+	HoldUntil string
+}
+
+// Render returns the email of the given kind about a booking.
+func Render(n models.DueNotification) (Message, error) {
+	subject, ok := subjects[n.Notification.Kind]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown notification kind %q", n.Notification.Kind)
+	}
+	destination := n.DestinationName
+	if destination == "" {
+		destination = fmt.Sprintf("destination %d", n.Booking.DestinationID)
+	}
+	data := templateData{
+		Code:        n.Booking.Code,
+		FirstName:   n.Booking.FirstName,
+		LastName:    n.Booking.LastName,
+		Destination: destination,
+		LaunchpadID: n.Booking.LaunchpadID,
+		LaunchDate:  n.Booking.LaunchDate.Format("Monday, January 2, 2006"),
+	}
+	if n.Booking.Price > 0 {
+		data.Fare = fmt.Sprintf("%d.%02d %s", n.Booking.Price/100, n.Booking.Price%100, n.Booking.Currency)
+	}
+	if n.Booking.HoldUntil != nil {
+		data.HoldUntil = n.Booking.HoldUntil.UTC().Format("Monday, January 2, 2006 at 15:04 UTC")
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&text, n.Notification.Kind+".txt", data); err != nil {
+		return Message{}, err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, n.Notification.Kind+".html", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      n.Notification.Recipient,
+		Subject: fmt.Sprintf(subject, destination, n.Booking.LaunchDate.Format("2006-01-02")),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,139 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+)
+
+// CaptureStore is the part of the database the capturer works on.
+type CaptureStore interface {
+	StartCapture(ctx context.Context, id int64, providerRef string, retryAt time.Time) (*models.Payment, error)
+	ConfirmPayment(ctx context.Context, id int64) (*models.Booking, error)
+	ClaimCaptures(ctx context.Context, limit int, lease time.Duration) ([]models.Payment, error)
+	RecordCaptureAttempt(ctx context.Context, payment *models.Payment) error
+}
+
+// Capturer takes authorized payments and confirms their bookings. The
+// payment is marked capturing before the provider is called, and no
+// database transaction is held open meanwhile. A capture whose outcome is
+// lost, to an error or a crash, leaves the payment capturing: RunOnce sends
+// it again under the same key, so the money is taken once, and settles it.
+type Capturer struct {
+	store       CaptureStore
+	provider    Provider
+	clock       clock.Clock
+	logger      *log.Logger
+	batch       int
+	maxAttempts int
+	timeout     time.Duration
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// NewCapturer returns a Capturer.
+func NewCapturer(store CaptureStore, provider Provider, clk clock.Clock, logger *log.Logger) *Capturer {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Capturer{
+		store:       store,
+		provider:    provider,
+		clock:       clk,
+		logger:      logger,
+		batch:       20,
+		maxAttempts: 5,
+		timeout:     30 * time.Second,
+		minBackoff:  30 * time.Second,
+		maxBackoff:  10 * time.Minute,
+	}
+}
+
+// Capture takes the payment the provider authorized as providerRef and
+// confirms its booking. Should the capture fail, the payment is left to
+// RunOnce.
+func (c *Capturer) Capture(ctx context.Context, id int64, providerRef string) (*models.Booking, error) {
+	// RunOnce leaves the payment alone while the capture is under way.
+	p, err := c.store.StartCapture(ctx, id, providerRef, c.clock.Now().Add(c.timeout))
+	if err != nil {
+		return nil, err
+	}
+	return c.settle(ctx, *p)
+}
+
+// RunOnce retries one batch of captures whose outcome was lost, one after
+// the other, and returns how many were settled. Failures are logged and
+// the payment is tried again later.
+func (c *Capturer) RunOnce(ctx context.Context) (int, error) {
+	lease := time.Duration(c.batch) * c.timeout
+	due, err := c.store.ClaimCaptures(ctx, c.batch, lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim captures: %w", err)
+	}
+
+	settled := 0
+	for _, p := range due {
+		booking, err := c.settle(ctx, p)
+		if err != nil {
+			c.logger.Printf("Error capturing payment %d: %v", p.ID, err)
+			continue
+		}
+		c.logger.Printf("Captured payment %d, booking %d is now %s", p.ID, booking.ID, booking.Status)
+		settled++
+	}
+	return settled, nil
+}
+
+// settle sends the capture of a capturing payment and confirms its
+// booking once it succeeds. A failed capture is recorded with the time it
+// is tried again, or fails the payment once the attempts run out.
+func (c *Capturer) settle(ctx context.Context, p models.Payment) (*models.Booking, error) {
+	err := c.attempt(ctx, p)
+	if err == nil {
+		booking, err := c.store.ConfirmPayment(ctx, p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("confirm payment %d: %w", p.ID, err)
+		}
+		return booking, nil
+	}
+
+	now := c.clock.Now()
+	p.CaptureAttempts++
+	p.FailureReason = err.Error()
+	if p.CaptureAttempts >= c.maxAttempts {
+		// The booking is left to expire.
+		p.Status = models.PaymentFailed
+		p.NextCaptureAt = nil
+		c.logger.Printf("Capture of payment %d failed for good: %v", p.ID, err)
+	} else {
+		next := now.Add(c.backoff(p.CaptureAttempts))
+		p.NextCaptureAt = &next
+	}
+	if rerr := c.store.RecordCaptureAttempt(ctx, &p); rerr != nil {
+		c.logger.Printf("Error recording the capture of payment %d: %v", p.ID, rerr)
+	}
+	return nil, fmt.Errorf("capture payment %d: %w", p.ID, err)
+}
+
+// attempt asks the provider for the capture, keyed by the payment ID so
+// that a retry after a lost answer does not take the money twice.
+func (c *Capturer) attempt(ctx context.Context, p models.Payment) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	key := "capture-" + strconv.FormatInt(p.ID, 10)
+	return c.provider.Capture(ctx, p.ProviderRef, p.Amount, key)
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (c *Capturer) backoff(attempts int) time.Duration {
+	delay := c.minBackoff << (attempts - 1)
+	if delay > c.maxBackoff || delay <= 0 {
+		delay = c.maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,218 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"space-booking/internal/clock"
+)
+
+// Payment methods the Fake provider understands. Any other method, and
+// none, is authorized.
+const (
+	// FakeDeclined is declined.
+	FakeDeclined = "fake_declined"
+	// FakeAsync stays pending until an event is posted, see Fake.Event.
+	FakeAsync = "fake_async"
+	// FakeUnavailable fails as if the provider could not be reached.
+	FakeUnavailable = "fake_unavailable"
+)
+
+// FakeSignatureHeader carries the signature of the events of the Fake
+// provider: "t=<unix time>,v1=<hex encoded HMAC-SHA256 of the time, a dot
+// and the body>".
+const FakeSignatureHeader = "Fake-Signature"
+
+// fakeTolerance is how old an event may be.
+const fakeTolerance = 5 * time.Minute
+
+// Fake is an in-memory provider for development and tests. It decides by
+// the payment method and checks captures and refunds against what it
+// authorized, which it forgets when the process exits.
+type Fake struct {
+	secret []byte
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+	captures map[string]string
+	refunds  map[string]string
+}
+
+type fakePayment struct {
+	amount     int64
+	authorized bool
+	captured   bool
+	refunded   int64
+}
+
+// NewFake returns a Fake provider whose events are signed with secret.
+func NewFake(secret string, clk clock.Clock) *Fake {
+	return &Fake{
+		secret:   []byte(secret),
+		clock:    clk,
+		payments: make(map[string]*fakePayment),
+		captures: make(map[string]string),
+		refunds:  make(map[string]string),
+	}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Authorize(_ context.Context, req Request) (Authorization, error) {
+	if req.Method == FakeUnavailable {
+		return Authorization{}, errors.New("fake: provider unavailable")
+	}
+	id, err := fakeID("pay")
+	if err != nil {
+		return Authorization{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := &fakePayment{amount: req.Amount}
+	f.payments[id] = p
+
+	switch req.Method {
+	case FakeDeclined:
+		return Authorization{ID: id, Status: Declined, Reason: "Card declined."}, nil
+	case FakeAsync:
+		return Authorization{ID: id, Status: Pending}, nil
+	}
+	p.authorized = true
+	return Authorization{ID: id, Status: Authorized}, nil
+}
+
+func (f *Fake) Capture(_ context.Context, paymentID string, amount int64, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.captures[key] == paymentID {
+		return nil
+	}
+	p, ok := f.payments[paymentID]
+	switch {
+	case !ok:
+		return fmt.Errorf("fake: unknown payment %s", paymentID)
+	case !p.authorized:
+		return fmt.Errorf("fake: payment %s is not authorized", paymentID)
+	case p.captured:
+		return fmt.Errorf("fake: payment %s is already captured", paymentID)
+	case amount != p.amount:
+		return fmt.Errorf("fake: payment %s authorized %d, not %d", paymentID, p.amount, amount)
+	}
+	p.captured = true
+	f.captures[key] = paymentID
+	return nil
+}
+
+func (f *Fake) Refund(_ context.Context, paymentID string, amount int64, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id, ok := f.refunds[key]; ok {
+		return id, nil
+	}
+	p, ok := f.payments[paymentID]
+	switch {
+	case !ok:
+		return "", fmt.Errorf("fake: unknown payment %s", paymentID)
+	case !p.captured:
+		return "", fmt.Errorf("fake: payment %s is not captured", paymentID)
+	case amount <= 0 || p.refunded+amount > p.amount:
+		return "", fmt.Errorf("fake: cannot refund %d of payment %s", amount, paymentID)
+	}
+	id, err := fakeID("re")
+	if err != nil {
+		return "", err
+	}
+	p.refunded += amount
+	f.refunds[key] = id
+	return id, nil
+}
+
+// Refunded returns how much of the payment was refunded.
+func (f *Fake) Refunded(paymentID string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := f.payments[paymentID]; ok {
+		return p.refunded
+	}
+	return 0
+}
+
+// Event settles a pending payment and returns the signed request the
+// provider would post to the webhook: its header and body.
+func (f *Fake) Event(ev Event) (http.Header, []byte, error) {
+	f.mu.Lock()
+	if p, ok := f.payments[ev.PaymentID]; ok && ev.Status == Authorized {
+		p.authorized = true
+	}
+	f.mu.Unlock()
+
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return nil, nil, err
+	}
+	timestamp := strconv.FormatInt(f.clock.Now().Unix(), 10)
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(FakeSignatureHeader, "t="+timestamp+",v1="+f.sign(timestamp, body))
+	return header, body, nil
+}
+
+func (f *Fake) ParseEvent(header http.Header, body []byte) (Event, error) {
+	var timestamp, signature string
+	for _, part := range strings.Split(header.Get(FakeSignatureHeader), ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			signature = v
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(f.sign(timestamp, body))) {
+		return Event{}, ErrInvalidEvent
+	}
+	if age := f.clock.Now().Sub(time.Unix(unix, 0)); age > fakeTolerance || age < -fakeTolerance {
+		return Event{}, fmt.Errorf("%w: sent %s ago", ErrInvalidEvent, age)
+	}
+
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if ev.Status != Authorized && ev.Status != Declined {
+		return Event{}, fmt.Errorf("%w: status %q", ErrInvalidEvent, ev.Status)
+	}
+	return ev, nil
+}
+
+func (f *Fake) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// fakeID returns a random ID with the prefix, e.g. "fake_pay_1f2e3d4c5b6a7988".
+func fakeID(prefix string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "fake_" + prefix + "_" + hex.EncodeToString(b), nil
+}
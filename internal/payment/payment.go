@@ -0,0 +1,81 @@
+// Package payment talks to the payment provider taking the fares, and
+// issues the refunds queued by cancellations.
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Status is the outcome of an authorization.
+type Status string
+
+const (
+	// Authorized is an authorization that holds the amount, ready to be
+	// captured.
+	Authorized Status = "authorized"
+	// Pending is an authorization whose outcome the provider posts later
+	// as an Event.
+	Pending Status = "pending"
+	// Declined is an authorization the provider refused.
+	Declined Status = "declined"
+)
+
+// ErrInvalidEvent is returned for an event that is malformed, not signed
+// by the provider or too old.
+var ErrInvalidEvent = errors.New("payment: invalid event")
+
+// Request asks the provider to authorize an amount.
+type Request struct {
+	// Reference is our name of the payment, echoed in the events.
+	Reference string
+	// Amount is in cents of Currency.
+	Amount   int64
+	Currency string
+	// Method is the provider's token for how the passenger pays, e.g. a
+	// tokenized card.
+	Method string
+}
+
+// Authorization is the provider's answer to a Request.
+type Authorization struct {
+	// ID is the provider's ID of the payment.
+	ID     string
+	Status Status
+	// Reason says why the authorization was declined.
+	Reason string
+}
+
+// Event is the outcome of a pending authorization, posted by the provider
+// to the payment webhook.
+type Event struct {
+	// PaymentID is the provider's ID of the payment.
+	PaymentID string `json:"payment_id"`
+	// Reference is the Request's.
+	Reference string `json:"reference"`
+	// Status is Authorized or Declined.
+	Status Status `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Provider is a payment provider. Fares are authorized when a booking is
+// made, captured once the booking is confirmed and refunded, in part or
+// in full, when it is cancelled.
+type Provider interface {
+	// Name is stored with every payment made through the provider.
+	Name() string
+	// Authorize holds the amount. An error means the provider could not be
+	// reached or did not answer; a refusal is a Declined authorization.
+	Authorize(ctx context.Context, req Request) (Authorization, error)
+	// Capture takes the authorized amount of the payment. Calls with the
+	// same key capture once, so a capture whose answer was lost can be
+	// sent again.
+	Capture(ctx context.Context, paymentID string, amount int64, key string) error
+	// Refund gives back amount of the captured payment and returns the
+	// provider's ID of the refund. Calls with the same key refund once.
+	Refund(ctx context.Context, paymentID string, amount int64, key string) (string, error)
+	// ParseEvent verifies and decodes a request posted to the payment
+	// webhook. It returns ErrInvalidEvent for anything it cannot trust.
+	ParseEvent(header http.Header, body []byte) (Event, error)
+}
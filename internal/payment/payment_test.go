@@ -0,0 +1,247 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2049, time.December, 1, 9, 30, 0, 0, time.UTC)
+
+func TestFakeAuthorize(t *testing.T) {
+	f := NewFake("whsec", clock.NewFake(now))
+	ctx := context.Background()
+
+	auth, err := f.Authorize(ctx, Request{Reference: "1", Amount: 100, Method: "tok_visa"})
+	require.NoError(t, err)
+	assert.Equal(t, Authorized, auth.Status)
+
+	declined, err := f.Authorize(ctx, Request{Reference: "2", Amount: 100, Method: FakeDeclined})
+	require.NoError(t, err)
+	assert.Equal(t, Declined, declined.Status)
+	assert.Error(t, f.Capture(ctx, declined.ID, 100, "capture-3"), "Expected a declined payment not to be captured")
+
+	pending, err := f.Authorize(ctx, Request{Reference: "3", Amount: 100, Method: FakeAsync})
+	require.NoError(t, err)
+	assert.Equal(t, Pending, pending.Status)
+
+	_, err = f.Authorize(ctx, Request{Reference: "4", Amount: 100, Method: FakeUnavailable})
+	assert.Error(t, err)
+}
+
+func TestFakeCaptureAndRefund(t *testing.T) {
+	f := NewFake("whsec", clock.NewFake(now))
+	ctx := context.Background()
+	auth, err := f.Authorize(ctx, Request{Reference: "1", Amount: 100})
+	require.NoError(t, err)
+
+	_, err = f.Refund(ctx, auth.ID, 50, "refund-1")
+	assert.Error(t, err, "Expected an uncaptured payment not to be refunded")
+	assert.Error(t, f.Capture(ctx, auth.ID, 90, "capture-3"), "Expected the amount to be checked")
+	require.NoError(t, f.Capture(ctx, auth.ID, 100, "capture-3"))
+	assert.NoError(t, f.Capture(ctx, auth.ID, 100, "capture-3"), "Expected a retried capture to be answered from its key")
+	assert.Error(t, f.Capture(ctx, auth.ID, 100, "capture-4"), "Expected a payment to be captured once")
+
+	first, err := f.Refund(ctx, auth.ID, 60, "refund-1")
+	require.NoError(t, err)
+	again, err := f.Refund(ctx, auth.ID, 60, "refund-1")
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "Expected a retried refund to be answered from its key")
+	assert.Equal(t, int64(60), f.Refunded(auth.ID))
+
+	_, err = f.Refund(ctx, auth.ID, 50, "refund-2")
+	assert.Error(t, err, "Expected refunds not to exceed the payment")
+}
+
+func TestFakeEvent(t *testing.T) {
+	clk := clock.NewFake(now)
+	f := NewFake("whsec", clk)
+	ctx := context.Background()
+	auth, err := f.Authorize(ctx, Request{Reference: "1", Amount: 100, Method: FakeAsync})
+	require.NoError(t, err)
+
+	header, body, err := f.Event(Event{PaymentID: auth.ID, Reference: "1", Status: Authorized})
+	require.NoError(t, err)
+	ev, err := f.ParseEvent(header, body)
+	require.NoError(t, err)
+	assert.Equal(t, Event{PaymentID: auth.ID, Reference: "1", Status: Authorized}, ev)
+	assert.NoError(t, f.Capture(ctx, auth.ID, 100, "capture-3"), "Expected the event to authorize the payment")
+
+	_, err = NewFake("other", clk).ParseEvent(header, body)
+	assert.ErrorIs(t, err, ErrInvalidEvent, "Expected another secret to be rejected")
+	_, err = f.ParseEvent(header, append(body, ' '))
+	assert.ErrorIs(t, err, ErrInvalidEvent, "Expected a changed body to be rejected")
+
+	clk.Advance(6 * time.Minute)
+	_, err = f.ParseEvent(header, body)
+	assert.ErrorIs(t, err, ErrInvalidEvent, "Expected a replayed event to be rejected")
+
+	header, body, err = f.Event(Event{PaymentID: auth.ID, Reference: "1", Status: Pending})
+	require.NoError(t, err)
+	_, err = f.ParseEvent(header, body)
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
+
+// fakeStore serves fixed refunds and keeps the recorded attempts.
+type fakeStore struct {
+	due      []models.DueRefund
+	recorded map[int64]models.Refund
+}
+
+func (s *fakeStore) ClaimRefunds(context.Context, int, time.Duration) ([]models.DueRefund, error) {
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *fakeStore) RecordRefundAttempt(_ context.Context, r *models.Refund) error {
+	s.recorded[r.ID] = *r
+	return nil
+}
+
+func TestRefunderRunOnce(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake("whsec", clock.NewFake(now))
+	auth, err := f.Authorize(ctx, Request{Reference: "3", Amount: 100})
+	require.NoError(t, err)
+	require.NoError(t, f.Capture(ctx, auth.ID, 100, "capture-3"))
+
+	paid := models.Payment{ID: 3, ProviderRef: auth.ID, Amount: 100, Currency: "USD", Status: models.PaymentCaptured}
+	unknown := models.Payment{ID: 4, ProviderRef: "fake_pay_unknown", Amount: 100, Currency: "USD", Status: models.PaymentCaptured}
+	store := &fakeStore{
+		recorded: make(map[int64]models.Refund),
+		due: []models.DueRefund{
+			{Refund: models.Refund{ID: 1, PaymentID: 3, Amount: 100, Status: models.RefundPending}, Payment: paid},
+			{Refund: models.Refund{ID: 2, PaymentID: 4, Amount: 100, Status: models.RefundPending}, Payment: unknown},
+			{Refund: models.Refund{ID: 3, PaymentID: 4, Amount: 100, Status: models.RefundPending, Attempts: 2}, Payment: unknown},
+		},
+	}
+	r := NewRefunder(store, f, clock.NewFake(now), nil, WithMaxAttempts(3), WithBackoff(time.Minute, time.Hour))
+
+	n, err := r.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, int64(100), f.Refunded(auth.ID))
+
+	refunded := store.recorded[1]
+	assert.Equal(t, models.RefundSucceeded, refunded.Status)
+	assert.NotEmpty(t, refunded.ProviderRef)
+	require.NotNil(t, refunded.RefundedAt)
+
+	retried := store.recorded[2]
+	assert.Equal(t, models.RefundPending, retried.Status)
+	assert.Equal(t, 1, retried.Attempts)
+	assert.Equal(t, now.Add(time.Minute), retried.NextAttemptAt)
+	assert.Contains(t, retried.LastError, "unknown payment")
+
+	failed := store.recorded[3]
+	assert.Equal(t, models.RefundFailed, failed.Status)
+	assert.Equal(t, 3, failed.Attempts)
+}
+
+// fakeCaptureStore keeps capturing payments and the bookings they pay.
+type fakeCaptureStore struct {
+	payments  map[int64]models.Payment
+	confirmed map[int64]bool
+	retryAt   time.Time
+}
+
+func (s *fakeCaptureStore) StartCapture(_ context.Context, id int64, providerRef string, retryAt time.Time) (*models.Payment, error) {
+	p := s.payments[id]
+	p.Status = models.PaymentCapturing
+	p.ProviderRef = providerRef
+	p.NextCaptureAt = &retryAt
+	s.payments[id] = p
+	s.retryAt = retryAt
+	return &p, nil
+}
+
+func (s *fakeCaptureStore) ConfirmPayment(_ context.Context, id int64) (*models.Booking, error) {
+	p := s.payments[id]
+	p.Status = models.PaymentCaptured
+	s.payments[id] = p
+	s.confirmed[id] = true
+	return &models.Booking{ID: p.BookingID, Status: models.StatusConfirmed}, nil
+}
+
+func (s *fakeCaptureStore) ClaimCaptures(context.Context, int, time.Duration) ([]models.Payment, error) {
+	var due []models.Payment
+	for _, p := range s.payments {
+		if p.Status == models.PaymentCapturing {
+			due = append(due, p)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeCaptureStore) RecordCaptureAttempt(_ context.Context, p *models.Payment) error {
+	s.payments[p.ID] = *p
+	return nil
+}
+
+func TestCapturerCapture(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake("whsec", clock.NewFake(now))
+	auth, err := f.Authorize(ctx, Request{Reference: "3", Amount: 100})
+	require.NoError(t, err)
+	store := &fakeCaptureStore{
+		confirmed: make(map[int64]bool),
+		payments: map[int64]models.Payment{
+			3: {ID: 3, BookingID: 7, Amount: 100, Currency: "USD", Status: models.PaymentPending},
+			4: {ID: 4, BookingID: 8, Amount: 100, Currency: "USD", Status: models.PaymentPending},
+		},
+	}
+	c := NewCapturer(store, f, clock.NewFake(now), nil)
+
+	booking, err := c.Capture(ctx, 3, auth.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusConfirmed, booking.Status)
+	assert.True(t, store.confirmed[3])
+	assert.Equal(t, now.Add(30*time.Second), store.retryAt, "Expected the capture to be retried only after it times out")
+	assert.Error(t, f.Capture(ctx, auth.ID, 100, "capture-4"), "Expected the payment to be captured")
+
+	_, err = c.Capture(ctx, 4, "fake_pay_unknown")
+	assert.Error(t, err)
+	lost := store.payments[4]
+	assert.Equal(t, models.PaymentCapturing, lost.Status, "Expected a failed capture to be left to the retries")
+	assert.Equal(t, 1, lost.CaptureAttempts)
+	require.NotNil(t, lost.NextCaptureAt)
+	assert.Equal(t, now.Add(30*time.Second), *lost.NextCaptureAt)
+	assert.Contains(t, lost.FailureReason, "unknown payment")
+	assert.False(t, store.confirmed[4])
+}
+
+func TestCapturerRunOnce(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake("whsec", clock.NewFake(now))
+	auth, err := f.Authorize(ctx, Request{Reference: "3", Amount: 100})
+	require.NoError(t, err)
+	// The money was taken, but the answer was lost.
+	require.NoError(t, f.Capture(ctx, auth.ID, 100, "capture-3"))
+
+	store := &fakeCaptureStore{
+		confirmed: make(map[int64]bool),
+		payments: map[int64]models.Payment{
+			3: {ID: 3, BookingID: 7, ProviderRef: auth.ID, Amount: 100, Status: models.PaymentCapturing},
+			4: {ID: 4, BookingID: 8, ProviderRef: "fake_pay_unknown", Amount: 100, Status: models.PaymentCapturing, CaptureAttempts: 4},
+		},
+	}
+	c := NewCapturer(store, f, clock.NewFake(now), nil)
+
+	n, err := c.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.True(t, store.confirmed[3], "Expected a retried capture to confirm its booking")
+
+	failed := store.payments[4]
+	assert.Equal(t, models.PaymentFailed, failed.Status)
+	assert.Equal(t, 5, failed.CaptureAttempts)
+	assert.Nil(t, failed.NextCaptureAt)
+	assert.False(t, store.confirmed[4])
+}
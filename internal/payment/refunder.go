@@ -0,0 +1,143 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+)
+
+// Store is the part of the database the refunder works on.
+type Store interface {
+	ClaimRefunds(ctx context.Context, limit int, lease time.Duration) ([]models.DueRefund, error)
+	RecordRefundAttempt(ctx context.Context, refund *models.Refund) error
+}
+
+// Refunder issues the queued refunds. Cancellations only queue them, so a
+// provider that is down delays the refunds but never fails a
+// cancellation. Failed attempts are retried with exponential backoff until
+// the attempts run out.
+type Refunder struct {
+	store       Store
+	provider    Provider
+	clock       clock.Clock
+	logger      *log.Logger
+	batch       int
+	maxAttempts int
+	timeout     time.Duration
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// Option configures a Refunder built by NewRefunder.
+type Option func(*Refunder)
+
+// WithMaxAttempts sets how many attempts a refund gets before it fails.
+func WithMaxAttempts(n int) Option {
+	return func(r *Refunder) { r.maxAttempts = n }
+}
+
+// WithBackoff sets the delay before the second attempt and the cap it
+// doubles up to.
+func WithBackoff(min, max time.Duration) Option {
+	return func(r *Refunder) {
+		r.minBackoff = min
+		r.maxBackoff = max
+	}
+}
+
+// NewRefunder returns a Refunder.
+func NewRefunder(store Store, provider Provider, clk clock.Clock, logger *log.Logger, opts ...Option) *Refunder {
+	if logger == nil {
+		logger = log.Default()
+	}
+	r := &Refunder{
+		store:       store,
+		provider:    provider,
+		clock:       clk,
+		logger:      logger,
+		batch:       20,
+		maxAttempts: 5,
+		timeout:     30 * time.Second,
+		minBackoff:  time.Minute,
+		maxBackoff:  time.Hour,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RunOnce issues one batch of due refunds, one after the other, and
+// returns how many succeeded.
+func (r *Refunder) RunOnce(ctx context.Context) (int, error) {
+	lease := time.Duration(r.batch) * r.timeout
+	due, err := r.store.ClaimRefunds(ctx, r.batch, lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim refunds: %w", err)
+	}
+
+	refunded := 0
+	for _, job := range due {
+		ok, err := r.refund(ctx, job)
+		if err != nil {
+			return refunded, err
+		}
+		if ok {
+			refunded++
+		}
+	}
+	return refunded, nil
+}
+
+// refund makes one attempt and records its outcome. It reports whether the
+// refund succeeded; the error is about recording it.
+func (r *Refunder) refund(ctx context.Context, job models.DueRefund) (bool, error) {
+	refund := job.Refund
+	id, err := r.attempt(ctx, job)
+	now := r.clock.Now()
+
+	refund.Attempts++
+	refund.LastError = ""
+	switch {
+	case err == nil:
+		refund.Status = models.RefundSucceeded
+		refund.ProviderRef = id
+		refund.RefundedAt = &now
+		r.logger.Printf("Refunded %d %s of payment %d", refund.Amount, job.Payment.Currency, job.Payment.ID)
+	case refund.Attempts >= r.maxAttempts:
+		refund.Status = models.RefundFailed
+		refund.LastError = err.Error()
+		r.logger.Printf("Refund %d of payment %d failed for good: %v", refund.ID, job.Payment.ID, err)
+	default:
+		refund.LastError = err.Error()
+		refund.NextAttemptAt = now.Add(r.backoff(refund.Attempts))
+	}
+
+	if err := r.store.RecordRefundAttempt(ctx, &refund); err != nil {
+		return false, fmt.Errorf("record refund %d: %w", refund.ID, err)
+	}
+	return refund.Status == models.RefundSucceeded, nil
+}
+
+// attempt asks the provider for the refund, keyed by its ID so that a
+// retry after a lost answer does not refund twice.
+func (r *Refunder) attempt(ctx context.Context, job models.DueRefund) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	key := "refund-" + strconv.FormatInt(job.Refund.ID, 10)
+	return r.provider.Refund(ctx, job.Payment.ProviderRef, job.Refund.Amount, key)
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (r *Refunder) backoff(attempts int) time.Duration {
+	delay := r.minBackoff << (attempts - 1)
+	if delay > r.maxBackoff || delay <= 0 {
+		delay = r.maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,86 @@
+// Package pricing computes fares and signs the quotes offering them.
+package pricing
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+)
+
+// ErrSoldOut is returned when every seat of the flight is taken.
+var ErrSoldOut = errors.New("pricing: flight is sold out")
+
+// ErrNotForSale is returned for a destination without a base price.
+var ErrNotForSale = errors.New("pricing: destination is not for sale")
+
+// Tier is a factor that applies from a threshold on.
+type Tier struct {
+	From   int
+	Factor float64
+}
+
+// Rules are the fare rules. A fare is the base price of the destination
+// times the advance, load and age factors, rounded to the cent.
+type Rules struct {
+	Currency string
+	// Seats is how many passengers a flight takes.
+	Seats int
+	// LoadSurcharge is the load factor of the last seat less one; the
+	// factor grows linearly with the share of seats sold.
+	LoadSurcharge float64
+	// Advance are the factors by days until launch.
+	Advance []Tier
+	// AgeBands are the factors by age on the launch day.
+	AgeBands []Tier
+}
+
+// Input is what a fare depends on.
+type Input struct {
+	Destination models.Destination
+	LaunchDate  time.Time
+	Birthday    time.Time
+	// SeatsSold counts the active bookings on the flight.
+	SeatsSold int
+	Now       time.Time
+}
+
+// Price returns the fare and how it was computed.
+func (r Rules) Price(in Input) (int64, models.FareBreakdown, error) {
+	if in.Destination.BasePrice <= 0 {
+		return 0, models.FareBreakdown{}, ErrNotForSale
+	}
+	if in.SeatsSold >= r.Seats {
+		return 0, models.FareBreakdown{}, ErrSoldOut
+	}
+
+	launchDay := clock.Day(in.LaunchDate)
+	days := int(launchDay.Sub(clock.Day(in.Now)).Hours() / 24)
+	age := models.Age(in.Birthday, launchDay)
+	b := models.FareBreakdown{
+		BasePrice:       in.Destination.BasePrice,
+		DaysUntilLaunch: days,
+		AdvanceFactor:   factor(r.Advance, days),
+		SeatsSold:       in.SeatsSold,
+		Seats:           r.Seats,
+		LoadFactor:      1 + r.LoadSurcharge*float64(in.SeatsSold)/float64(r.Seats),
+		Age:             age,
+		AgeFactor:       factor(r.AgeBands, age),
+	}
+	price := float64(b.BasePrice) * b.AdvanceFactor * b.LoadFactor * b.AgeFactor
+	return int64(math.Round(price)), b, nil
+}
+
+// factor returns the factor of the tier with the highest threshold not
+// above v, or 1 when there is none.
+func factor(tiers []Tier, v int) float64 {
+	f, from := 1.0, math.MinInt
+	for _, t := range tiers {
+		if t.From <= v && t.From > from {
+			f, from = t.Factor, t.From
+		}
+	}
+	return f
+}
@@ -0,0 +1,125 @@
+package pricing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rules = Rules{
+	Currency:      "USD",
+	Seats:         50,
+	LoadSurcharge: 0.5,
+	Advance:       []Tier{{0, 1.25}, {14, 1}, {60, 0.95}, {180, 0.85}},
+	AgeBands:      []Tier{{0, 0.75}, {18, 1}, {65, 0.9}},
+}
+
+var now = time.Date(2049, time.December, 1, 15, 30, 0, 0, time.UTC)
+
+func TestPrice(t *testing.T) {
+	mars := models.Destination{ID: 1, Name: "Mars", BasePrice: 100000000}
+	adult := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		in    Input
+		price int64
+	}{
+		{"standard fare", Input{Destination: mars, LaunchDate: now.AddDate(0, 0, 20), Birthday: adult}, 100000000},
+		{"last minute", Input{Destination: mars, LaunchDate: now, Birthday: adult}, 125000000},
+		{"booked early", Input{Destination: mars, LaunchDate: now.AddDate(0, 0, 200), Birthday: adult}, 85000000},
+		{"half full", Input{Destination: mars, LaunchDate: now.AddDate(0, 0, 20), Birthday: adult, SeatsSold: 25}, 125000000},
+		{"child", Input{Destination: mars, LaunchDate: now.AddDate(0, 0, 20), Birthday: time.Date(2040, time.June, 1, 0, 0, 0, 0, time.UTC)}, 75000000},
+		{"senior", Input{Destination: mars, LaunchDate: now.AddDate(0, 0, 20), Birthday: time.Date(1970, time.June, 1, 0, 0, 0, 0, time.UTC)}, 90000000},
+		{"rounded to the cent", Input{Destination: models.Destination{BasePrice: 333}, LaunchDate: now.AddDate(0, 0, 20), Birthday: adult, SeatsSold: 1}, 336},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.in.Now = now
+			price, _, err := rules.Price(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.price, price)
+		})
+	}
+
+	t.Run("breakdown", func(t *testing.T) {
+		_, b, err := rules.Price(Input{Destination: mars, LaunchDate: now.AddDate(0, 0, 60), Birthday: adult, SeatsSold: 10, Now: now})
+		require.NoError(t, err)
+		assert.Equal(t, models.FareBreakdown{
+			BasePrice: 100000000, DaysUntilLaunch: 60, AdvanceFactor: 0.95,
+			SeatsSold: 10, Seats: 50, LoadFactor: 1.1, Age: 60, AgeFactor: 1,
+		}, b)
+	})
+
+	t.Run("sold out", func(t *testing.T) {
+		_, _, err := rules.Price(Input{Destination: mars, LaunchDate: now, Birthday: adult, SeatsSold: 50, Now: now})
+		assert.ErrorIs(t, err, ErrSoldOut)
+	})
+
+	t.Run("not for sale", func(t *testing.T) {
+		_, _, err := rules.Price(Input{Destination: models.Destination{ID: 9}, LaunchDate: now, Birthday: adult, Now: now})
+		assert.ErrorIs(t, err, ErrNotForSale)
+	})
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer, err := NewSigner(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	require.NoError(t, err)
+	quote := &models.Quote{
+		LaunchpadID:   "pad_a",
+		DestinationID: 1,
+		LaunchDate:    time.Date(2049, time.December, 20, 0, 0, 0, 0, time.UTC),
+		Birthday:      time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Price:         100000000,
+		Currency:      "USD",
+		ExpiresAt:     now.Add(15 * time.Minute),
+	}
+	require.NoError(t, signer.Sign(quote))
+	assert.True(t, strings.HasPrefix(quote.ID, "Q1."))
+
+	terms, err := signer.Verify(quote.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, TermsOf(quote), terms)
+	assert.True(t, terms.Covers(&models.Booking{
+		LaunchpadID: "pad_a", DestinationID: 1, LaunchDate: quote.LaunchDate, Birthday: quote.Birthday,
+	}))
+	assert.False(t, terms.Covers(&models.Booking{
+		LaunchpadID: "pad_b", DestinationID: 1, LaunchDate: quote.LaunchDate, Birthday: quote.Birthday,
+	}))
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := signer.Verify(quote.ID, quote.ExpiresAt)
+		assert.ErrorIs(t, err, ErrQuoteExpired)
+	})
+
+	t.Run("another key", func(t *testing.T) {
+		other, err := GenerateSigner()
+		require.NoError(t, err)
+		_, err = other.Verify(quote.ID, now)
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		parts := strings.Split(quote.ID, ".")
+		cheaper := *quote
+		cheaper.Price = 1
+		require.NoError(t, signer.Sign(&cheaper))
+		forged := parts[0] + "." + strings.Split(cheaper.ID, ".")[1] + "." + parts[2]
+		_, err := signer.Verify(forged, now)
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, id := range []string{"", "Q1", "Q2.e30.AAAA", "Q1..."} {
+			_, err := signer.Verify(id, now)
+			assert.ErrorIs(t, err, ErrInvalidQuote, id)
+		}
+	})
+}
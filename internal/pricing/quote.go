@@ -0,0 +1,137 @@
+package pricing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"space-booking/internal/models"
+)
+
+// quotePrefix starts every quote ID and names its version.
+const quotePrefix = "Q1"
+
+var (
+	// ErrInvalidQuote is returned for a quote ID that is malformed or not
+	// signed with the expected key.
+	ErrInvalidQuote = errors.New("pricing: invalid quote")
+	// ErrQuoteExpired is returned for a quote past its expiry.
+	ErrQuoteExpired = errors.New("pricing: quote expired")
+)
+
+// Terms are what a quote ID vouches for.
+type Terms struct {
+	LaunchpadID   string `json:"launchpad_id"`
+	DestinationID int64  `json:"destination_id"`
+	// LaunchDate and Birthday are days as YYYY-MM-DD.
+	LaunchDate string    `json:"launch_date"`
+	Birthday   string    `json:"birthday"`
+	Price      int64     `json:"price_cents"`
+	Currency   string    `json:"currency"`
+	PromoCode  string    `json:"promo_code,omitempty"`
+	Discount   int64     `json:"discount_cents,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// TermsOf returns the terms of a quote.
+func TermsOf(q *models.Quote) Terms {
+	return Terms{
+		LaunchpadID:   q.LaunchpadID,
+		DestinationID: q.DestinationID,
+		LaunchDate:    q.LaunchDate.Format("2006-01-02"),
+		Birthday:      q.Birthday.Format("2006-01-02"),
+		Price:         q.Price,
+		Currency:      q.Currency,
+		PromoCode:     q.PromoCode,
+		Discount:      q.Discount,
+		ExpiresAt:     q.ExpiresAt.UTC(),
+	}
+}
+
+// Covers reports whether the terms were quoted for the booking's flight
+// and passenger.
+func (t Terms) Covers(b *models.Booking) bool {
+	return t.LaunchpadID == b.LaunchpadID &&
+		t.DestinationID == b.DestinationID &&
+		t.LaunchDate == b.LaunchDate.Format("2006-01-02") &&
+		t.Birthday == b.Birthday.Format("2006-01-02")
+}
+
+// Signer signs quote IDs with an HMAC-SHA256 key. Only the server checks
+// them, so the key is shared rather than public.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner returns a Signer for a base64 encoded key of at least 32
+// bytes.
+func NewSigner(key string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) < 32 {
+		return nil, errors.New("pricing: signing key must be at least 32 bytes in base64")
+	}
+	return &Signer{key: raw}, nil
+}
+
+// GenerateSigner returns a Signer with a new random key.
+func GenerateSigner() (*Signer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &Signer{key: key}, nil
+}
+
+// Sign sets the ID of the quote: "Q1.", the base64url encoded JSON terms,
+// "." and the base64url encoded MAC of everything before it.
+func (s *Signer) Sign(q *models.Quote) error {
+	payload, err := json.Marshal(TermsOf(q))
+	if err != nil {
+		return err
+	}
+	signed := quotePrefix + "." + base64.RawURLEncoding.EncodeToString(payload)
+	q.ID = signed + "." + base64.RawURLEncoding.EncodeToString(s.mac(signed))
+	return nil
+}
+
+// Verify checks the quote ID and returns its terms, or ErrQuoteExpired
+// when it expired by now.
+func (s *Signer) Verify(id string, now time.Time) (Terms, error) {
+	var terms Terms
+	i := strings.LastIndexByte(id, '.')
+	if i < 0 {
+		return terms, ErrInvalidQuote
+	}
+	signed, encodedMAC := id[:i], id[i+1:]
+	prefix, encodedPayload, ok := strings.Cut(signed, ".")
+	if !ok || prefix != quotePrefix {
+		return terms, ErrInvalidQuote
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.mac(signed)) {
+		return terms, ErrInvalidQuote
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return terms, ErrInvalidQuote
+	}
+	if err := json.Unmarshal(payload, &terms); err != nil {
+		return terms, fmt.Errorf("%w: %v", ErrInvalidQuote, err)
+	}
+	if !now.Before(terms.ExpiresAt) {
+		return terms, ErrQuoteExpired
+	}
+	return terms, nil
+}
+
+func (s *Signer) mac(signed string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(signed))
+	return h.Sum(nil)
+}
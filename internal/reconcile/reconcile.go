@@ -0,0 +1,156 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"space-booking/internal/actor"
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+)
+
+// Store is the part of the database the reconciler works on.
+type Store interface {
+	GetUpcomingBookings(ctx context.Context, from time.Time) ([]models.Booking, error)
+	GetDestinationIDs(ctx context.Context) ([]int64, error)
+	DisruptBooking(ctx context.Context, id int, reason, source string) (*models.Booking, error)
+}
+
+// Reconciler re-checks future bookings against the current launchpad
+// conflicts and destination rotation, and marks the bookings that no
+// longer hold as disrupted. A booking that was valid when it was made turns
+// invalid when, say, SpaceX later schedules a launch from its launchpad.
+type Reconciler struct {
+	store     Store
+	conflicts conflict.Provider
+	clock     clock.Clock
+	logger    *log.Logger
+}
+
+// New returns a Reconciler.
+func New(store Store, conflicts conflict.Provider, clk clock.Clock, logger *log.Logger) *Reconciler {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Reconciler{store: store, conflicts: conflicts, clock: clk, logger: logger}
+}
+
+// RunOnce checks every booking launching today or later and returns how
+// many it marked as disrupted. Conflicts are fetched once per launchpad
+// for the whole range of booked dates. A launchpad whose conflicts cannot
+// be fetched is skipped until the next run rather than failing the others.
+func (r *Reconciler) RunOnce(ctx context.Context) (int, error) {
+	ctx = actor.With(ctx, actor.System("reconciler"))
+	bookings, err := r.store.GetUpcomingBookings(ctx, clock.Day(r.clock.Now()))
+	if err != nil {
+		return 0, fmt.Errorf("list upcoming bookings: %w", err)
+	}
+	if len(bookings) == 0 {
+		return 0, nil
+	}
+	destinationIDs, err := r.store.GetDestinationIDs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list destinations: %w", err)
+	}
+
+	disrupted := 0
+	for _, group := range byLaunchpad(bookings) {
+		if err := ctx.Err(); err != nil {
+			return disrupted, err
+		}
+
+		blocked, err := r.blockedDays(ctx, group)
+		if err != nil {
+			r.logger.Printf("Reconciler skipped launchpad %s: %v", group[0].LaunchpadID, err)
+			continue
+		}
+
+		for _, booking := range group {
+			reason, source, err := disruption(booking, blocked, destinationIDs)
+			if err != nil {
+				return disrupted, err
+			}
+			if reason == "" {
+				continue
+			}
+			if _, err := r.store.DisruptBooking(ctx, booking.ID, reason, source); err != nil {
+				var terr *models.TransitionError
+				if errors.Is(err, database.ErrNotFound) || errors.As(err, &terr) {
+					continue // cancelled or disrupted in the meantime
+				}
+				return disrupted, fmt.Errorf("mark booking %d disrupted: %w", booking.ID, err)
+			}
+			r.logger.Printf("Booking %d disrupted: %s", booking.ID, reason)
+			disrupted++
+		}
+	}
+	return disrupted, nil
+}
+
+// blockedDays returns the first conflict of each day in the range of the
+// group's launch dates. All bookings in the group share a launchpad.
+func (r *Reconciler) blockedDays(ctx context.Context, group []models.Booking) (map[time.Time]conflict.Conflict, error) {
+	from, to := clock.Day(group[0].LaunchDate), clock.Day(group[0].LaunchDate)
+	for _, b := range group[1:] {
+		day := clock.Day(b.LaunchDate)
+		if day.Before(from) {
+			from = day
+		}
+		if day.After(to) {
+			to = day
+		}
+	}
+
+	conflicts, err := r.conflicts.Conflicts(ctx, group[0].LaunchpadID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[time.Time]conflict.Conflict, len(conflicts))
+	for _, c := range conflicts {
+		if _, ok := blocked[c.Date]; !ok {
+			blocked[c.Date] = c
+		}
+	}
+	return blocked, nil
+}
+
+// disruption returns why the booking can no longer fly and the source of
+// the disruption, or "" if it can.
+func disruption(booking models.Booking, blocked map[time.Time]conflict.Conflict, destinationIDs []int64) (string, string, error) {
+	day := clock.Day(booking.LaunchDate)
+	if c, ok := blocked[day]; ok {
+		return fmt.Sprintf("Launchpad %s is taken on %s: %s (reported by %s)",
+			booking.LaunchpadID, day.Format(time.DateOnly), c.Reason, c.Provider), c.Provider, nil
+	}
+
+	expected, err := database.ExpectedDestination(destinationIDs, day)
+	if err != nil {
+		return "", "", err
+	}
+	if expected != booking.DestinationID {
+		return fmt.Sprintf("Destination %d is no longer flown on %s, destination %d is",
+			booking.DestinationID, day.Format(time.DateOnly), expected), models.DisruptedBySchedule, nil
+	}
+	return "", "", nil
+}
+
+// byLaunchpad groups the bookings by launchpad, keeping their order.
+func byLaunchpad(bookings []models.Booking) [][]models.Booking {
+	index := make(map[string]int)
+	var groups [][]models.Booking
+	for _, b := range bookings {
+		i, ok := index[b.LaunchpadID]
+		if !ok {
+			i = len(groups)
+			index[b.LaunchpadID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], b)
+	}
+	return groups
+}
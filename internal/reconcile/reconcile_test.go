@@ -0,0 +1,106 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"space-booking/internal/actor"
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	bookings  []models.Booking
+	from      time.Time
+	disrupted map[int]string
+	sources   map[int]string
+	actors    map[string]bool
+}
+
+func (s *fakeStore) GetUpcomingBookings(_ context.Context, from time.Time) ([]models.Booking, error) {
+	s.from = from
+	return s.bookings, nil
+}
+
+func (s *fakeStore) GetDestinationIDs(context.Context) ([]int64, error) {
+	return []int64{1, 2, 3, 4, 5, 6, 7}, nil
+}
+
+func (s *fakeStore) DisruptBooking(ctx context.Context, id int, reason, source string) (*models.Booking, error) {
+	s.disrupted[id] = reason
+	s.sources[id] = source
+	s.actors[actor.From(ctx).ID] = true
+	return &models.Booking{ID: id, Status: models.StatusDisrupted, DisruptionReason: reason, DisruptedBy: source}, nil
+}
+
+// fakeProvider reports fixed conflicts and counts the queries per launchpad.
+type fakeProvider struct {
+	conflicts map[string][]conflict.Conflict
+	failing   map[string]bool
+	queries   map[string]int
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Conflicts(_ context.Context, launchpadID string, from, to time.Time) ([]conflict.Conflict, error) {
+	p.queries[launchpadID]++
+	if p.failing[launchpadID] {
+		return nil, errors.New("unreachable")
+	}
+	var out []conflict.Conflict
+	for _, c := range p.conflicts[launchpadID] {
+		if !c.Date.Before(from) && !c.Date.After(to) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func day(d int) time.Time {
+	return time.Date(2049, time.December, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRunOnce(t *testing.T) {
+	// December 20, 2049 is a Monday, so destination 1 flies on the 20th, 2 on the 21st, ...
+	store := &fakeStore{
+		disrupted: make(map[int]string),
+		sources:   make(map[int]string),
+		actors:    make(map[string]bool),
+		bookings: []models.Booking{
+			{ID: 1, LaunchpadID: "pad_a", DestinationID: 1, LaunchDate: day(20)}, // still valid
+			{ID: 2, LaunchpadID: "pad_a", DestinationID: 2, LaunchDate: day(21)}, // SpaceX launch added
+			{ID: 3, LaunchpadID: "pad_a", DestinationID: 4, LaunchDate: day(22)}, // wrong destination
+			{ID: 4, LaunchpadID: "pad_b", DestinationID: 1, LaunchDate: day(20)}, // provider down
+		},
+	}
+	provider := &fakeProvider{
+		conflicts: map[string][]conflict.Conflict{
+			"pad_a": {{Provider: "spacex", LaunchpadID: "pad_a", Date: day(21), Reason: `SpaceX launch "Starlink"`}},
+			"pad_b": {{Provider: "spacex", LaunchpadID: "pad_b", Date: day(20), Reason: "never seen"}},
+		},
+		failing: map[string]bool{"pad_b": true},
+		queries: make(map[string]int),
+	}
+
+	now := time.Date(2049, time.December, 19, 15, 0, 0, 0, time.UTC)
+	r := New(store, provider, clock.NewFake(now), nil)
+
+	n, err := r.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, day(19), store.from, "Expected bookings from today on")
+
+	assert.Equal(t, map[int]string{
+		2: `Launchpad pad_a is taken on 2049-12-21: SpaceX launch "Starlink" (reported by spacex)`,
+		3: "Destination 4 is no longer flown on 2049-12-22, destination 3 is",
+	}, store.disrupted)
+	assert.Equal(t, map[int]string{2: "spacex", 3: models.DisruptedBySchedule}, store.sources)
+	assert.Equal(t, 1, provider.queries["pad_a"], "Expected one conflict query per launchpad")
+	assert.Equal(t, map[string]bool{"system:reconciler": true}, store.actors, "Expected the changes to be attributed to the reconciler")
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"space-booking/internal/clock"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+)
+
+// blackoutResponse is returned when a blackout is created or changed. It
+// lists the bookings inside the blackout, which have to be rebooked.
+type blackoutResponse struct {
+	Blackout         models.Blackout  `json:"blackout"`
+	AffectedBookings []models.Booking `json:"affected_bookings"`
+}
+
+// ListBlackoutsHandler lists the blackouts, optionally of one launchpad.
+func (s *Server) ListBlackoutsHandler(w http.ResponseWriter, r *http.Request) {
+	blackouts, err := s.db.ListBlackouts(r.Context(), r.URL.Query().Get("launchpad"))
+	if err != nil {
+		s.logger.Printf("Error retrieving blackouts: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if blackouts == nil {
+		blackouts = []models.Blackout{}
+	}
+	writeJSON(w, http.StatusOK, blackouts)
+}
+
+// GetBlackoutHandler returns a single blackout.
+func (s *Server) GetBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+	blackout, err := s.db.GetBlackout(r.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Blackout not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error retrieving blackout %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, blackout)
+}
+
+// CreateBlackoutHandler closes a launchpad and lists the bookings caught by
+// the closure.
+func (s *Server) CreateBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	blackout, ok := s.decodeBlackout(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.db.CreateBlackout(r.Context(), blackout); err != nil {
+		s.logger.Printf("Error creating blackout: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.writeBlackout(w, r, http.StatusCreated, blackout)
+}
+
+// UpdateBlackoutHandler changes a blackout and lists the bookings caught by
+// its new dates.
+func (s *Server) UpdateBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+	blackout, ok := s.decodeBlackout(w, r)
+	if !ok {
+		return
+	}
+	blackout.ID = id
+
+	err := s.db.UpdateBlackout(r.Context(), blackout)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Blackout not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error updating blackout %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.writeBlackout(w, r, http.StatusOK, blackout)
+}
+
+// DeleteBlackoutHandler reopens a launchpad.
+func (s *Server) DeleteBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+	err := s.db.DeleteBlackout(r.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Blackout not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error deleting blackout %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeBlackout reads and validates a blackout from the request body. It
+// writes the error response itself and reports whether decoding succeeded.
+func (s *Server) decodeBlackout(w http.ResponseWriter, r *http.Request) (*models.Blackout, bool) {
+	var blackout models.Blackout
+	if err := json.NewDecoder(r.Body).Decode(&blackout); err != nil {
+		s.logger.Printf("Invalid blackout data: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return nil, false
+	}
+
+	switch {
+	case blackout.LaunchpadID == "":
+		http.Error(w, "Launchpad ID must be provided.", http.StatusBadRequest)
+		return nil, false
+	case blackout.StartsOn.IsZero() || blackout.EndsOn.IsZero():
+		http.Error(w, "Start and end dates must be provided.", http.StatusBadRequest)
+		return nil, false
+	case blackout.Reason == "":
+		http.Error(w, "Reason must be provided.", http.StatusBadRequest)
+		return nil, false
+	}
+
+	blackout.StartsOn = clock.Day(blackout.StartsOn)
+	blackout.EndsOn = clock.Day(blackout.EndsOn)
+	if blackout.EndsOn.Before(blackout.StartsOn) {
+		http.Error(w, "End date must not be before start date.", http.StatusBadRequest)
+		return nil, false
+	}
+	return &blackout, true
+}
+
+// writeBlackout responds with the blackout and the bookings inside it.
+func (s *Server) writeBlackout(w http.ResponseWriter, r *http.Request, status int, blackout *models.Blackout) {
+	affected, err := s.db.GetBookingsOnLaunchpad(r.Context(), blackout.LaunchpadID, blackout.StartsOn, blackout.EndsOn)
+	if err != nil {
+		// The blackout is stored; failing the request would invite a retry
+		// that creates it twice.
+		s.logger.Printf("Error retrieving bookings affected by blackout %d: %v", blackout.ID, err)
+	}
+	if affected == nil {
+		affected = []models.Booking{}
+	}
+	writeJSON(w, status, blackoutResponse{Blackout: *blackout, AffectedBookings: affected})
+}
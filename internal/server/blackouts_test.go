@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"space-booking/internal/config"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// adminConfig enables the admin API with a known token.
+func adminConfig() *config.Config {
+	cfg := config.Default()
+	cfg.AdminToken = "s3cret"
+	return cfg
+}
+
+// adminRequest builds a request to the router that carries the admin token.
+func adminRequest(method, target string, body []byte) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	return req
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+
+	// Disabled without a configured token
+	handler := newServer(WithDatabase(db)).RegisterRoutes()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/blackouts", nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Rejects a wrong token
+	handler = newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+	req := httptest.NewRequest(http.MethodGet, "/admin/blackouts", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	db.AssertNotCalled(t, "ListBlackouts", mock.Anything)
+}
+
+func TestCreateBlackoutHandlerListsAffectedBookings(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	startsOn := time.Date(2049, time.December, 24, 0, 0, 0, 0, time.UTC)
+	endsOn := time.Date(2049, time.December, 26, 0, 0, 0, 0, time.UTC)
+	affected := []models.Booking{{ID: 7, FirstName: "Test", LaunchpadID: "test_launchpad", LaunchDate: endsOn}}
+
+	db.On("CreateBlackout", mock.AnythingOfType("*models.Blackout")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Blackout).ID = 3
+	}).Return(nil)
+	db.On("GetBookingsOnLaunchpad", "test_launchpad", startsOn, endsOn).Return(affected, nil)
+
+	body, err := json.Marshal(models.Blackout{
+		LaunchpadID: "test_launchpad",
+		StartsOn:    startsOn,
+		EndsOn:      endsOn,
+		Reason:      "Pad maintenance",
+	})
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/blackouts", body))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var resp blackoutResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Blackout.ID)
+	require.Len(t, resp.AffectedBookings, 1)
+	assert.Equal(t, 7, resp.AffectedBookings[0].ID)
+	db.AssertExpectations(t)
+}
+
+func TestCreateBlackoutHandlerValidation(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	body, err := json.Marshal(models.Blackout{
+		LaunchpadID: "test_launchpad",
+		StartsOn:    time.Date(2049, time.December, 26, 0, 0, 0, 0, time.UTC),
+		EndsOn:      time.Date(2049, time.December, 24, 0, 0, 0, 0, time.UTC),
+		Reason:      "Backwards",
+	})
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/blackouts", body))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	db.AssertNotCalled(t, "CreateBlackout", mock.Anything)
+}
+
+func TestDeleteBlackoutHandlerNotFound(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	db.On("DeleteBlackout", 42).Return(database.ErrNotFound)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodDelete, "/admin/blackouts/42", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	db.AssertExpectations(t)
+}
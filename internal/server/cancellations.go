@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"space-booking/internal/cancellation"
+	"space-booking/internal/models"
+)
+
+// cancellationPolicy returns the refund policy of the configuration.
+func (s *Server) cancellationPolicy() cancellation.Policy {
+	tiers := make([]cancellation.Tier, len(s.cfg.Cancellation.Refunds))
+	for i, t := range s.cfg.Cancellation.Refunds {
+		tiers[i] = cancellation.Tier{From: t.From, Share: t.Share}
+	}
+	return cancellation.Policy{Tiers: tiers}
+}
+
+// cancelBooking cancels the booking like changeBookingStatus and refunds
+// what the cancellation policy grants of what was paid for it. byOperator
+// tells an operator's cancellation from the passenger's own.
+func (s *Server) cancelBooking(w http.ResponseWriter, r *http.Request, id int, byOperator bool) {
+	policy, now := s.cancellationPolicy(), s.clock.Now()
+	booking, err := s.db.CancelBooking(r.Context(), id, func(b *models.Booking) models.RefundTerms {
+		return policy.Terms(b, now, byOperator)
+	})
+	s.writeStatusChange(w, id, models.StatusCancelled, booking, err)
+}
+
+// CancellationQuoteHandler previews what cancelling a booking now would
+// refund, by the same policy DELETE /bookings/{id} applies for the caller.
+func (s *Server) CancellationQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	booking, ok := s.lookupBooking(w, r)
+	if !ok {
+		return
+	}
+	id := booking.ID
+	if !booking.Status.CanTransitionTo(models.StatusCancelled) {
+		http.Error(w, fmt.Sprintf("Booking is %s and cannot be cancelled.", booking.Status), http.StatusConflict)
+		return
+	}
+	payments, err := s.db.GetPayments(r.Context(), id)
+	if err != nil {
+		s.logger.Printf("Error retrieving the payments of booking %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	now := s.clock.Now()
+	quote := models.CancellationQuote{
+		BookingID:       booking.ID,
+		Status:          booking.Status,
+		DaysUntilLaunch: cancellation.DaysUntilLaunch(booking.LaunchDate, now),
+		RefundTerms:     s.cancellationPolicy().Terms(booking, now, s.isAdmin(r)),
+		Currency:        booking.Currency,
+	}
+	for _, p := range payments {
+		if p.Status != models.PaymentCaptured {
+			continue
+		}
+		var refunded int64
+		for _, refund := range p.Refunds {
+			if refund.Status != models.RefundFailed {
+				refunded += refund.Amount
+			}
+		}
+		quote.Paid += p.Amount - refunded
+		quote.Refund += models.RefundAmount(p.Amount, refunded, quote.Share)
+		quote.Currency = p.Currency
+	}
+	writeJSON(w, http.StatusOK, quote)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// refundTerms matches the terms of a cancellation that grant want for the
+// booking.
+func refundTerms(booking *models.Booking, want models.RefundTerms) any {
+	return mock.MatchedBy(func(terms func(*models.Booking) models.RefundTerms) bool {
+		return terms(booking) == want
+	})
+}
+
+// cancellable launches on December 25, 2049, 24 days after bookingDay.
+func cancellable(status models.BookingStatus) *models.Booking {
+	return &models.Booking{
+		ID: 7, Code: "K7QX2MWP9D", Status: status, Currency: "USD",
+		LaunchDate: time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestCancelBookingHandlerPolicy(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay))).RegisterRoutes()
+
+	cancelledAt := bookingDay
+	db.On("GetBookingByCode", "K7QX2MWP9D").Return(cancellable(models.StatusConfirmed), nil)
+	db.On("GetBookingByCode", "7").Return(nil, database.ErrNotFound)
+	db.On("CancelBooking", 7, refundTerms(cancellable(models.StatusConfirmed),
+		models.RefundTerms{Share: 0.5, Reason: "Cancelled 24 days before launch."})).
+		Return(&models.Booking{ID: 7, Status: models.StatusCancelled, CancelledAt: &cancelledAt}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/bookings/K7QX2MWP9D", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Passengers cannot name a booking by its ID.
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/bookings/7", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	db.AssertExpectations(t)
+}
+
+func TestCancellationQuoteHandler(t *testing.T) {
+	payments := []models.Payment{
+		{ID: 2, BookingID: 7, Amount: 120000000, Currency: "USD", Status: models.PaymentFailed},
+		{ID: 3, BookingID: 7, Amount: 120000000, Currency: "USD", Status: models.PaymentCaptured, Refunds: []models.Refund{
+			{ID: 1, PaymentID: 3, Amount: 20000000, Status: models.RefundSucceeded},
+			{ID: 2, PaymentID: 3, Amount: 100000000, Status: models.RefundFailed},
+		}},
+	}
+	disrupted := cancellable(models.StatusDisrupted)
+	disrupted.DisruptionReason = "Launchpad test_launchpad is taken"
+	disrupted.DisruptedBy = "spacex"
+
+	tests := []struct {
+		name    string
+		booking *models.Booking
+		admin   bool
+		want    models.CancellationQuote
+	}{
+		{"passenger", cancellable(models.StatusConfirmed), false, models.CancellationQuote{
+			BookingID: 7, Status: models.StatusConfirmed, DaysUntilLaunch: 24,
+			RefundTerms: models.RefundTerms{Share: 0.5, Reason: "Cancelled 24 days before launch."},
+			Paid:        100000000, Refund: 40000000, Currency: "USD",
+		}},
+		{"operator", cancellable(models.StatusConfirmed), true, models.CancellationQuote{
+			BookingID: 7, Status: models.StatusConfirmed, DaysUntilLaunch: 24,
+			RefundTerms: models.RefundTerms{Share: 1, Reason: "Cancelled by the operator."},
+			Paid:        100000000, Refund: 100000000, Currency: "USD",
+		}},
+		{"disrupted", disrupted, false, models.CancellationQuote{
+			BookingID: 7, Status: models.StatusDisrupted, DaysUntilLaunch: 24,
+			RefundTerms: models.RefundTerms{Share: 1, Reason: "Flight was disrupted: Launchpad test_launchpad is taken",
+				DisruptedBy: "spacex"},
+			Paid: 100000000, Refund: 100000000, Currency: "USD",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			db.On("GetBooking", 7).Return(tt.booking, nil)
+			db.On("GetBookingByCode", "K7QX2MWP9D").Return(tt.booking, nil)
+			db.On("GetPayments", 7).Return(payments, nil)
+			handler := newServer(WithDatabase(db), WithConfig(adminConfig()), WithClock(clock.NewFake(bookingDay))).RegisterRoutes()
+
+			req := httptest.NewRequest(http.MethodGet, "/bookings/K7QX2MWP9D/cancellation-quote", nil)
+			if tt.admin {
+				req = adminRequest(http.MethodGet, "/bookings/7/cancellation-quote", nil)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			var got models.CancellationQuote
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCancellationQuoteHandlerNotCancellable(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	db.On("GetBookingByCode", "K7QX2MWP9D").Return(cancellable(models.StatusFlown), nil)
+	db.On("GetBookingByCode", "NOPE").Return(nil, database.ErrNotFound)
+	handler := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay))).RegisterRoutes()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/K7QX2MWP9D/cancellation-quote", nil))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "Booking is flown and cannot be cancelled.\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/NOPE/cancellation-quote", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
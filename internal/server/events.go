@@ -0,0 +1,143 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"strings"
+	"time"
+)
+
+// eventBatch caps the messages read from the outbox at once.
+const eventBatch = 100
+
+// maxEventBookings caps the bookings a passenger's stream follows.
+const maxEventBookings = 20
+
+// EventsHandler streams the outbox as server-sent events. Operators see
+// every event; passengers name their bookings by code in the bookings query
+// parameter, which is what proves they may see them, and get the events
+// about those and all schedule changes. The optional comma separated types query parameter
+// narrows the stream down. A client that reconnects with the Last-Event-ID
+// header, or the last_event_id query parameter, receives what it missed;
+// a new one starts with the events written after it connected.
+func (s *Server) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter := models.OutboxFilter{Limit: eventBatch}
+	if !s.isAdmin(r) {
+		codes := r.URL.Query().Get("bookings")
+		if codes == "" {
+			http.Error(w, "Send booking codes or the admin token to follow events", http.StatusUnauthorized)
+			return
+		}
+		refs := strings.Split(codes, ",")
+		if len(refs) > maxEventBookings {
+			http.Error(w, fmt.Sprintf("At most %d bookings can be followed at once", maxEventBookings), http.StatusBadRequest)
+			return
+		}
+		filter.Bookings = []int{}
+		for _, code := range refs {
+			booking, err := s.db.GetBookingByCode(ctx, strings.TrimSpace(code))
+			if errors.Is(err, database.ErrNotFound) {
+				http.Error(w, fmt.Sprintf("Booking %s not found", code), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				s.logger.Printf("Error retrieving booking %s: %v", code, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			filter.Bookings = append(filter.Bookings, booking.ID)
+		}
+	}
+
+	if v := r.URL.Query().Get("types"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if !slices.Contains(models.WebhookEventTypes, t) {
+				http.Error(w, fmt.Sprintf("Unknown event type %q", t), http.StatusBadRequest)
+				return
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	if lastID != "" {
+		after, err := models.ParseOutboxPosition(lastID)
+		if err != nil {
+			http.Error(w, "Invalid last event ID", http.StatusBadRequest)
+			return
+		}
+		filter.After = after
+	} else {
+		latest, err := s.db.LatestOutboxPosition(ctx)
+		if err != nil {
+			s.logger.Printf("Error reading the outbox: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		filter.After = latest
+	}
+
+	// The stream outlives the write timeout of the server.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		s.logger.Printf("Event stream cannot be flushed: %v", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(s.cfg.Events.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		// Wait on the hub before reading, so no notification is missed.
+		changed := s.hub.Changed()
+
+		messages, err := s.db.GetOutboxMessages(ctx, filter)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Printf("Error reading the outbox: %v", err)
+			}
+			return
+		}
+		for _, m := range messages {
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", m.Position(), m.Type, m.Payload)
+			filter.After = m.Position()
+		}
+		if len(messages) > 0 {
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+		if len(messages) == eventBatch {
+			continue
+		}
+
+		// The heartbeat reads the outbox again too: messages held back by
+		// an older transaction become visible when it ends, which nothing
+		// notifies.
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEventsHandler(t *testing.T) {
+	t.Run("passenger starts at the newest event", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		latest := models.OutboxPosition{XID: 1040, ID: 41}
+		db.On("GetBookingByCode", "K7QX2MWP9D").Return(&models.Booking{ID: 7, Code: "K7QX2MWP9D"}, nil).Once()
+		db.On("LatestOutboxPosition").Return(latest, nil).Once()
+		db.On("GetOutboxMessages", models.OutboxFilter{After: latest, Bookings: []int{7}, Limit: eventBatch}).
+			Return([]models.OutboxMessage{
+				{ID: 42, XID: 1042, Type: "booking.cancelled", BookingID: 7, Payload: json.RawMessage(`{"id":7,"status":"cancelled"}`)},
+			}, nil).
+			Run(func(mock.Arguments) { cancel() }).
+			Once()
+
+		handler := newServer(WithDatabase(db)).RegisterRoutes()
+		req := httptest.NewRequest(http.MethodGet, "/events?bookings=K7QX2MWP9D", nil).WithContext(ctx)
+		req.Header.Set(clientIDHeader, "ops")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "id: 1042-42\nevent: booking.cancelled\ndata: {\"id\":7,\"status\":\"cancelled\"}\n\n", rr.Body.String())
+		db.AssertExpectations(t)
+	})
+
+	t.Run("operator resumes after the last event ID", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		after := models.OutboxPosition{XID: 1039, ID: 3}
+		db.On("GetOutboxMessages", models.OutboxFilter{After: after, Types: []string{models.BlackoutCreated}, Limit: eventBatch}).
+			Return([]models.OutboxMessage{}, nil).
+			Run(func(mock.Arguments) { cancel() }).
+			Once()
+
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+		req := adminRequest(http.MethodGet, "/events?types=schedule.blackout_created", nil).WithContext(ctx)
+		req.Header.Set("Last-Event-ID", "1039-3")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Body.String())
+		db.AssertExpectations(t)
+	})
+
+	t.Run("stream recovers once an unrelated transaction ends", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// The message committed, but an older transaction still running
+		// holds it back; its end is notified to no one.
+		after := models.OutboxPosition{XID: 1039, ID: 3}
+		filter := models.OutboxFilter{After: after, Limit: eventBatch}
+		db.On("GetOutboxMessages", filter).Return([]models.OutboxMessage{}, nil).Once()
+		db.On("GetOutboxMessages", filter).
+			Return([]models.OutboxMessage{
+				{ID: 4, XID: 1041, Type: models.BlackoutCreated, Payload: json.RawMessage(`{"id":2}`)},
+			}, nil).
+			Run(func(mock.Arguments) { cancel() }).
+			Once()
+
+		cfg := adminConfig()
+		cfg.Events.Heartbeat = time.Millisecond
+		handler := newServer(WithConfig(cfg), WithDatabase(db)).RegisterRoutes()
+		req := adminRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+		req.Header.Set("Last-Event-ID", "1039-3")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, ": ping\n\nid: 1041-4\nevent: schedule.blackout_created\ndata: {\"id\":2}\n\n", rr.Body.String())
+		db.AssertExpectations(t)
+	})
+
+	t.Run("rejected requests", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			target string
+			client string
+			want   int
+		}{
+			{"anonymous", "/events", "", http.StatusUnauthorized},
+			{"client ID is no credential", "/events", "ops", http.StatusUnauthorized},
+			{"unknown booking", "/events?bookings=NOPE", "", http.StatusNotFound},
+			{"unknown type", "/events?bookings=K7QX2MWP9D&types=booking.exploded", "", http.StatusBadRequest},
+			{"invalid last event ID", "/events?bookings=K7QX2MWP9D&last_event_id=x", "", http.StatusBadRequest},
+			{"last event ID without transaction", "/events?bookings=K7QX2MWP9D&last_event_id=42", "", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resetVisitors()
+				db := new(MockDatabase)
+				db.On("GetBookingByCode", "K7QX2MWP9D").Return(&models.Booking{ID: 7, Code: "K7QX2MWP9D"}, nil).Maybe()
+				db.On("GetBookingByCode", "NOPE").Return(nil, database.ErrNotFound).Maybe()
+				handler := newServer(WithDatabase(db)).RegisterRoutes()
+				req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+				if tt.client != "" {
+					req.Header.Set(clientIDHeader, tt.client)
+				}
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				assert.Equal(t, tt.want, rr.Code)
+				db.AssertExpectations(t)
+			})
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"space-booking/internal/bulk"
+	"space-booking/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Media types of the booking lists.
+const (
+	mediaJSON    = "application/json"
+	mediaCSV     = "text/csv"
+	mediaNDJSON  = "application/x-ndjson"
+	mediaParquet = "application/vnd.apache.parquet"
+)
+
+// exportFlushEvery is how many bookings are sent at a time while
+// streaming.
+const exportFlushEvery = 500
+
+// ExportBookingsHandler streams the bookings matching the filters in the
+// query, see bookingFilter, as the file given by the format query
+// parameter or else the Accept header: CSV, the default, NDJSON or Parquet.
+func (s *Server) ExportBookingsHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := bookingFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := bulk.Format(r.URL.Query().Get("format"))
+	if format == "" {
+		switch negotiate(r.Header.Get("Accept"), mediaCSV, mediaNDJSON, mediaParquet) {
+		case mediaCSV:
+			format = bulk.CSV
+		case mediaNDJSON:
+			format = bulk.NDJSON
+		case mediaParquet:
+			format = bulk.Parquet
+		default:
+			http.Error(w, "Exports are available as text/csv, application/x-ndjson or application/vnd.apache.parquet", http.StatusNotAcceptable)
+			return
+		}
+	}
+	switch format {
+	case bulk.CSV, bulk.NDJSON, bulk.Parquet:
+	default:
+		http.Error(w, "Unknown format, expected csv, ndjson or parquet", http.StatusBadRequest)
+		return
+	}
+
+	name := fmt.Sprintf("bookings-%s.%s", s.clock.Now().UTC().Format("20060102-150405"), format)
+	s.streamBookings(w, r, filter, format, name)
+}
+
+// streamBookings writes the bookings matching filter as they are read from
+// the database, as an attachment when name is not empty. A failure after
+// the first bytes went out aborts the response, so that the client does
+// not take a truncated file for a complete one.
+func (s *Server) streamBookings(w http.ResponseWriter, r *http.Request, filter models.BookingFilter, format bulk.Format, name string) {
+	// The export outlives the write timeout of the server.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	switch format {
+	case bulk.CSV:
+		w.Header().Set("Content-Type", mediaCSV+"; charset=utf-8")
+	case bulk.NDJSON:
+		w.Header().Set("Content-Type", mediaNDJSON)
+	case bulk.Parquet:
+		w.Header().Set("Content-Type", mediaParquet)
+	}
+	if name != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	}
+
+	out := &countingWriter{w: w}
+	bw, err := bulk.NewWriter(out, format)
+	if err != nil {
+		s.logger.Printf("Error exporting bookings: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	count := 0
+	err = s.db.ExportBookings(r.Context(), filter, func(b *models.Booking) error {
+		if err := bw.Write(b); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = bw.Close()
+	}
+	switch {
+	case err == nil:
+		s.logger.Printf("Exported %d bookings as %s", count, format)
+	case r.Context().Err() != nil:
+		// The client went away.
+	case out.n == 0:
+		s.logger.Printf("Error exporting bookings: %v", err)
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	default:
+		s.logger.Printf("Error exporting bookings after %d rows: %v", count, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// bookingFilter reads the filters of a booking list from the query: the
+// comma separated status, launchpad, destination, launch_from and
+// launch_to as YYYY-MM-DD, and created_from and created_to as RFC 3339
+// times. The error message is safe to return to the client.
+func bookingFilter(r *http.Request) (models.BookingFilter, error) {
+	var filter models.BookingFilter
+	q := r.URL.Query()
+	if v := q.Get("status"); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			status, err := models.ParseBookingStatus(name)
+			if err != nil {
+				return filter, err
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	filter.LaunchpadID = q.Get("launchpad")
+	if v := q.Get("destination"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return filter, &validationError{"Invalid destination ID"}
+		}
+		filter.DestinationID = id
+	}
+
+	times := []struct {
+		param  string
+		layout string
+		dst    *time.Time
+	}{
+		{"launch_from", "2006-01-02", &filter.LaunchFrom},
+		{"launch_to", "2006-01-02", &filter.LaunchTo},
+		{"created_from", time.RFC3339, &filter.CreatedFrom},
+		{"created_to", time.RFC3339, &filter.CreatedTo},
+	}
+	for _, t := range times {
+		v := q.Get(t.param)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(t.layout, v)
+		if err != nil {
+			if t.layout == time.RFC3339 {
+				return filter, &validationError{fmt.Sprintf("Invalid %s, expected an RFC 3339 time", t.param)}
+			}
+			return filter, &validationError{fmt.Sprintf("Invalid %s, expected YYYY-MM-DD", t.param)}
+		}
+		*t.dst = parsed
+	}
+	return filter, nil
+}
+
+// negotiate returns the offer the Accept header prefers, the first offer
+// when the header is empty, and "" when it accepts none of them. Offers
+// equally preferred are taken in the order given.
+func negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q := acceptQuality(accept, offer)
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality the Accept header gives the media
+// type, taken from its most specific matching range.
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		rng, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		s := -1
+		switch {
+		case rng == mediaType:
+			s = 2
+		case rng == typ+"/*":
+			s = 1
+		case rng == "*/*":
+			s = 0
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+	}
+	return q
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportBookings(n int) []models.Booking {
+	bookings := make([]models.Booking, n)
+	for i := range bookings {
+		b := ticketBooking(models.StatusConfirmed)
+		b.ID = i + 1
+		bookings[i] = *b
+	}
+	return bookings
+}
+
+func TestExportBookingsHandler(t *testing.T) {
+	t.Run("csv with filters", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		filter := models.BookingFilter{
+			Statuses:    []models.BookingStatus{models.StatusConfirmed, models.StatusFlown},
+			LaunchpadID: "pad_a",
+			LaunchFrom:  time.Date(2049, time.December, 1, 0, 0, 0, 0, time.UTC),
+			CreatedTo:   time.Date(2049, time.December, 1, 12, 0, 0, 0, time.UTC),
+		}
+		db.On("ExportBookings", filter).Return(exportBookings(2), nil).Once()
+
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(db), WithClock(clock.NewFake(bookingDay))).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, adminRequest(http.MethodGet,
+			"/bookings/export?format=csv&status=confirmed,flown&launchpad=pad_a&launch_from=2049-12-01&created_to=2049-12-01T12:00:00Z", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="bookings-20491201-093000.csv"`, rr.Header().Get("Content-Disposition"))
+		lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[2], "2,K7QX2MWP9D,confirmed,"))
+		db.AssertExpectations(t)
+	})
+
+	t.Run("ndjson from the Accept header", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		db.On("ExportBookings", models.BookingFilter{}).Return(exportBookings(3), nil).Once()
+
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+		req := adminRequest(http.MethodGet, "/bookings/export", nil)
+		req.Header.Set("Accept", "text/csv;q=0.5, application/x-ndjson")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+		assert.Len(t, strings.Split(strings.TrimSpace(rr.Body.String()), "\n"), 3)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("failure before the first row", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		db.On("ExportBookings", models.BookingFilter{}).Return([]models.Booking{}, errors.New("connection reset")).Once()
+
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/bookings/export", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Empty(t, rr.Header().Get("Content-Disposition"))
+		db.AssertExpectations(t)
+	})
+
+	t.Run("failure midway aborts the response", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		db.On("ExportBookings", models.BookingFilter{}).Return(exportBookings(exportFlushEvery+1), errors.New("connection reset")).Once()
+
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/bookings/export", nil))
+		})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		db.On("ExportBookings", models.BookingFilter{}).Return(exportBookings(2), nil).Once()
+
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(db), WithClock(clock.NewFake(bookingDay))).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/bookings/export?format=parquet", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, "application/vnd.apache.parquet", rr.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="bookings-20491201-093000.parquet"`, rr.Header().Get("Content-Disposition"))
+		body := rr.Body.Bytes()
+		f, err := parquet.OpenFile(bytes.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), f.NumRows())
+		db.AssertExpectations(t)
+	})
+
+	t.Run("rejected requests", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			target string
+			accept string
+			admin  bool
+			want   int
+		}{
+			{"not an operator", "/bookings/export", "", false, http.StatusUnauthorized},
+			{"unknown format", "/bookings/export?format=xlsx", "", true, http.StatusBadRequest},
+			{"not acceptable", "/bookings/export", "application/json", true, http.StatusNotAcceptable},
+			{"invalid filter", "/bookings/export?launch_from=tomorrow", "", true, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resetVisitors()
+				db := new(MockDatabase)
+				handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+				req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+				if tt.admin {
+					req = adminRequest(http.MethodGet, tt.target, nil)
+				}
+				if tt.accept != "" {
+					req.Header.Set("Accept", tt.accept)
+				}
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				assert.Equal(t, tt.want, rr.Code)
+				db.AssertExpectations(t)
+			})
+		}
+	})
+}
+
+func TestGetAllBookingsHandlerNegotiates(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	filter := models.BookingFilter{DestinationID: 1}
+	db.On("ExportBookings", filter).Return(exportBookings(1), nil).Once()
+
+	handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+	req := adminRequest(http.MethodGet, "/bookings?destination=1", nil)
+	req.Header.Set("Accept", "text/csv")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Empty(t, rr.Header().Get("Content-Disposition"))
+	assert.Len(t, strings.Split(strings.TrimSpace(rr.Body.String()), "\n"), 2)
+
+	// The files carry the passengers, so they are for operators only.
+	for _, accept := range []string{"text/csv", "application/x-ndjson"} {
+		req = httptest.NewRequest(http.MethodGet, "/bookings?destination=1", nil)
+		req.Header.Set("Accept", accept)
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, accept)
+	}
+	db.AssertExpectations(t)
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{mediaJSON, mediaCSV, mediaNDJSON}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", mediaJSON},
+		{"*/*", mediaJSON},
+		{"text/*", mediaCSV},
+		{"text/html, application/x-ndjson;q=0.9", mediaNDJSON},
+		{"application/json;q=0.2, text/csv;q=0.8", mediaCSV},
+		{"*/*;q=0.1, text/csv;q=0", mediaJSON},
+		{"image/png", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.want, negotiate(tt.accept, offers...))
+		})
+	}
+}
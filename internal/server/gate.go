@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"space-booking/internal/clock"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/ticket"
+	"strings"
+	"time"
+)
+
+// checkInRequest is the body of CheckInHandler: the passenger proves who
+// they are with the details given when booking.
+type checkInRequest struct {
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Birthday  time.Time `json:"birthday"`
+}
+
+// CheckInHandler checks a confirmed booking in during the check-in window,
+// once the passenger's name and birthday match the booking.
+func (s *Server) CheckInHandler(w http.ResponseWriter, r *http.Request) {
+	var req checkInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	booking, ok := s.lookupBooking(w, r)
+	if !ok {
+		return
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(req.FirstName), booking.FirstName) ||
+		!strings.EqualFold(strings.TrimSpace(req.LastName), booking.LastName) ||
+		!clock.Day(req.Birthday).Equal(clock.Day(booking.Birthday)) {
+		http.Error(w, "Passenger details do not match the booking.", http.StatusForbidden)
+		return
+	}
+	if booking.Status == models.StatusCheckedIn {
+		http.Error(w, "Booking is already checked in.", http.StatusConflict)
+		return
+	}
+
+	launchDay := clock.Day(booking.LaunchDate)
+	opens := launchDay.Add(-s.cfg.CheckIn.OpensBefore)
+	closes := launchDay.Add(s.cfg.CheckIn.ClosesAfter)
+	now := s.clock.Now()
+	if now.Before(opens) {
+		http.Error(w, fmt.Sprintf("Check-in opens at %s.", opens.Format(time.RFC3339)), http.StatusConflict)
+		return
+	}
+	if !now.Before(closes) {
+		http.Error(w, fmt.Sprintf("Check-in closed at %s.", closes.Format(time.RFC3339)), http.StatusConflict)
+		return
+	}
+
+	s.changeBookingStatus(w, r, booking.ID, models.StatusCheckedIn, "")
+}
+
+// boardingRequest is the body of BoardingHandler.
+type boardingRequest struct {
+	// Token is the content of the QR code on the ticket.
+	Token string `json:"token"`
+}
+
+// BoardingHandler lets a checked in passenger through the gate on the
+// launch day, once their ticket is verified. A passenger boards only once.
+func (s *Server) BoardingHandler(w http.ResponseWriter, r *http.Request) {
+	var req boardingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	claims, err := ticket.Verify(s.tickets.PublicKey(), strings.TrimSpace(req.Token))
+	if err != nil {
+		http.Error(w, "Ticket is not valid.", http.StatusBadRequest)
+		return
+	}
+
+	booking, err := s.db.GetBookingByCode(r.Context(), claims.Code)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Booking not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error retrieving booking %s: %v", claims.Code, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// A ticket printed before the booking changed no longer holds.
+	if claims != ticket.NewClaims(*booking) {
+		http.Error(w, "Ticket does not match the booking, please print it again.", http.StatusConflict)
+		return
+	}
+	if today := clock.Day(s.clock.Now()); !clock.Day(booking.LaunchDate).Equal(today) {
+		http.Error(w, fmt.Sprintf("Booking launches on %s, not today.", booking.LaunchDate.Format("2006-01-02")), http.StatusConflict)
+		return
+	}
+	switch booking.Status {
+	case models.StatusCheckedIn:
+	case models.StatusBoarded:
+		msg := "Passenger already boarded."
+		if booking.BoardedAt != nil {
+			msg = fmt.Sprintf("Passenger already boarded at %s.", booking.BoardedAt.Format(time.RFC3339))
+		}
+		http.Error(w, msg, http.StatusConflict)
+		return
+	case models.StatusConfirmed:
+		http.Error(w, "Passenger has not checked in.", http.StatusConflict)
+		return
+	default:
+		http.Error(w, fmt.Sprintf("Booking is %s and cannot board.", booking.Status), http.StatusConflict)
+		return
+	}
+
+	// The status change locks the booking, so of two scans of the same
+	// ticket only one boards.
+	s.changeBookingStatus(w, r, booking.ID, models.StatusBoarded, "")
+}
+
+// ListBoardedHandler lists the passengers who boarded at a launchpad on a
+// day, today unless the date query parameter says otherwise.
+func (s *Server) ListBoardedHandler(w http.ResponseWriter, r *http.Request) {
+	launchpadID := r.URL.Query().Get("launchpad")
+	if launchpadID == "" {
+		http.Error(w, "The launchpad query parameter is required", http.StatusBadRequest)
+		return
+	}
+	day := clock.Day(s.clock.Now())
+	if v := r.URL.Query().Get("date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		day = d
+	}
+
+	bookings, err := s.db.GetBookingsOnLaunchpad(r.Context(), launchpadID, day, day)
+	if err != nil {
+		s.logger.Printf("Error retrieving bookings on %s: %v", launchpadID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	boarded := []models.Booking{}
+	for _, b := range bookings {
+		if b.Status == models.StatusBoarded {
+			boarded = append(boarded, b)
+		}
+	}
+	writeJSON(w, http.StatusOK, boarded)
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+	"space-booking/internal/ticket"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gateBooking launches on December 25, 2049.
+func gateBooking(status models.BookingStatus) *models.Booking {
+	b := ticketBooking(status)
+	b.Birthday = time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return b
+}
+
+func TestCheckInHandler(t *testing.T) {
+	identity := `{"first_name": "test", "last_name": "USER", "birthday": "1990-01-01T00:00:00Z"}`
+	tests := []struct {
+		name   string
+		now    time.Time
+		status models.BookingStatus
+		body   string
+		want   int
+		msg    string
+	}{
+		{"too early", time.Date(2049, time.December, 22, 23, 0, 0, 0, time.UTC), models.StatusConfirmed, identity,
+			http.StatusConflict, "Check-in opens at 2049-12-23T00:00:00Z.\n"},
+		{"too late", time.Date(2049, time.December, 25, 6, 0, 0, 0, time.UTC), models.StatusConfirmed, identity,
+			http.StatusConflict, "Check-in closed at 2049-12-25T06:00:00Z.\n"},
+		{"wrong birthday", time.Date(2049, time.December, 24, 12, 0, 0, 0, time.UTC), models.StatusConfirmed,
+			`{"first_name": "Test", "last_name": "User", "birthday": "1990-01-02T00:00:00Z"}`,
+			http.StatusForbidden, "Passenger details do not match the booking.\n"},
+		{"twice", time.Date(2049, time.December, 24, 12, 0, 0, 0, time.UTC), models.StatusCheckedIn, identity,
+			http.StatusConflict, "Booking is already checked in.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			db.On("GetBookingByCode", "K7QX2MWP9D").Return(gateBooking(tt.status), nil).Once()
+
+			handler := newServer(WithDatabase(db), WithClock(clock.NewFake(tt.now))).RegisterRoutes()
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings/K7QX2MWP9D/checkin", bytes.NewBufferString(tt.body)))
+
+			assert.Equal(t, tt.want, rr.Code)
+			assert.Equal(t, tt.msg, rr.Body.String())
+			db.AssertExpectations(t)
+		})
+	}
+
+	t.Run("within the window", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		checkedIn := gateBooking(models.StatusCheckedIn)
+		db.On("GetBookingByCode", "K7QX2MWP9D").Return(gateBooking(models.StatusConfirmed), nil).Once()
+		db.On("UpdateBookingStatus", 7, models.StatusCheckedIn, "").Return(checkedIn, nil).Once()
+
+		now := time.Date(2049, time.December, 25, 5, 59, 0, 0, time.UTC)
+		handler := newServer(WithDatabase(db), WithClock(clock.NewFake(now))).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings/K7QX2MWP9D/checkin", bytes.NewBufferString(identity)))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		db.AssertExpectations(t)
+	})
+}
+
+func TestBoardingHandler(t *testing.T) {
+	signer, err := ticket.GenerateSigner()
+	require.NoError(t, err)
+	token, err := signer.Sign(ticket.NewClaims(*gateBooking(models.StatusCheckedIn)))
+	require.NoError(t, err)
+	body, err := json.Marshal(boardingRequest{Token: token})
+	require.NoError(t, err)
+
+	launchDay := time.Date(2049, time.December, 25, 8, 0, 0, 0, time.UTC)
+	boardedAt := launchDay.Add(-time.Hour)
+	boarded := gateBooking(models.StatusBoarded)
+	boarded.BoardedAt = &boardedAt
+	moved := gateBooking(models.StatusCheckedIn)
+	moved.LaunchpadID = "other_launchpad"
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		booking *models.Booking
+		board   bool
+		want    int
+		msg     string
+	}{
+		{"boards", launchDay, gateBooking(models.StatusCheckedIn), true, http.StatusOK, ""},
+		{"twice", launchDay, boarded, false, http.StatusConflict, "Passenger already boarded at 2049-12-25T07:00:00Z.\n"},
+		{"not checked in", launchDay, gateBooking(models.StatusConfirmed), false, http.StatusConflict, "Passenger has not checked in.\n"},
+		{"not today", launchDay.AddDate(0, 0, -1), gateBooking(models.StatusCheckedIn), false, http.StatusConflict, "Booking launches on 2049-12-25, not today.\n"},
+		{"outdated ticket", launchDay, moved, false, http.StatusConflict, "Ticket does not match the booking, please print it again.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			db.On("GetBookingByCode", "K7QX2MWP9D").Return(tt.booking, nil).Once()
+			if tt.board {
+				db.On("UpdateBookingStatus", 7, models.StatusBoarded, "").Return(gateBooking(models.StatusBoarded), nil).Once()
+			}
+
+			handler := newServer(WithConfig(adminConfig()), WithDatabase(db), WithTicketSigner(signer), WithClock(clock.NewFake(tt.now))).RegisterRoutes()
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/boarding", body))
+
+			assert.Equal(t, tt.want, rr.Code)
+			if tt.msg != "" {
+				assert.Equal(t, tt.msg, rr.Body.String())
+			}
+			db.AssertExpectations(t)
+		})
+	}
+
+	t.Run("forged ticket", func(t *testing.T) {
+		resetVisitors()
+		other, err := ticket.GenerateSigner()
+		require.NoError(t, err)
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(new(MockDatabase)), WithTicketSigner(other)).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/boarding", body))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestListBoardedHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	day := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	db.On("GetBookingsOnLaunchpad", "test_launchpad", day, day).Return([]models.Booking{
+		*gateBooking(models.StatusBoarded),
+		*gateBooking(models.StatusCheckedIn),
+	}, nil).Once()
+
+	handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/boarding?launchpad=test_launchpad&date=2049-12-25", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var boarded []models.Booking
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &boarded))
+	require.Len(t, boarded, 1)
+	assert.Equal(t, models.StatusBoarded, boarded[0].Status)
+	db.AssertExpectations(t)
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"space-booking/internal/clock"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/pricing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateHoldHandler reserves seats on a flight for HOLD_TTL while the
+// customer checks out. The flight is validated like that of a booking and
+// its seats are taken from the capacity at once; bookings naming the hold
+// then take them one at a time.
+func (s *Server) CreateHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var hold models.SeatHold
+	if err := json.NewDecoder(r.Body).Decode(&hold); err != nil {
+		s.logger.Printf("Invalid hold data: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if hold.Seats == 0 {
+		hold.Seats = 1
+	}
+	if hold.Seats < 0 || hold.Seats > s.cfg.Holds.MaxSeats {
+		s.writeBookingError(w, &validationError{fmt.Sprintf("Seats must be between 1 and %d.", s.cfg.Holds.MaxSeats)})
+		return
+	}
+	if hold.LaunchDate.IsZero() {
+		s.writeBookingError(w, &validationError{"Launch date must be provided."})
+		return
+	}
+
+	ctx := r.Context()
+	if err := s.checkFlight(ctx, hold.LaunchpadID, hold.DestinationID, hold.LaunchDate, s.conflicts); err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	destination, err := s.db.GetDestination(ctx, hold.DestinationID)
+	if errors.Is(err, database.ErrNotFound) {
+		s.writeBookingError(w, &validationError{fmt.Sprintf("Destination %d does not exist.", hold.DestinationID)})
+		return
+	}
+	if err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	if destination.BasePrice <= 0 {
+		s.writeBookingError(w, fareError(pricing.ErrNotForSale, hold.DestinationID))
+		return
+	}
+
+	hold.LaunchDate = clock.Day(hold.LaunchDate)
+	hold.ExpiresAt = s.clock.Now().Add(s.cfg.Holds.TTL)
+	err = s.db.CreateHold(ctx, &hold, s.cfg.Pricing.Seats)
+	if errors.Is(err, database.ErrNoSeats) {
+		http.Error(w, "Not enough free seats on this flight.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error creating seat hold: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, hold)
+}
+
+// GetHoldHandler returns a seat hold with the seats booked from it. The
+// hold is named by its token, which only the customer who made it has.
+func (s *Server) GetHoldHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	hold, err := s.db.GetHold(r.Context(), token)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Seat hold not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error retrieving seat hold %s: %v", token, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, hold)
+}
+
+// ReleaseHoldHandler gives the seats left of a hold, named by its token,
+// back before it expires. Seats already booked stay with their bookings.
+func (s *Server) ReleaseHoldHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	hold, err := s.db.ReleaseHold(r.Context(), token)
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		http.Error(w, "Seat hold not found", http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrHoldClosed):
+		http.Error(w, fmt.Sprintf("Seat hold is %s and cannot be released.", hold.Status), http.StatusConflict)
+		return
+	case err != nil:
+		s.logger.Printf("Error releasing seat hold %s: %v", token, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, hold)
+}
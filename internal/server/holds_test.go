@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/payment"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateHoldHandler(t *testing.T) {
+	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		seats int
+		err   error
+		want  int
+		msg   string
+	}{
+		{"one seat by default", 0, nil, http.StatusCreated, ""},
+		{"several seats", 3, nil, http.StatusCreated, ""},
+		{"too many seats", 11, nil, http.StatusBadRequest, "Seats must be between 1 and 10.\n"},
+		{"sold out", 2, database.ErrNoSeats, http.StatusConflict, "Not enough free seats on this flight.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			conflicts := new(MockConflictProvider)
+			conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).Return([]conflict.Conflict(nil), nil)
+			want := max(tt.seats, 1)
+			if tt.want != http.StatusBadRequest {
+				db.On("CheckDestinationSchedule", int64(1), "test_launchpad", launchDate).Return(true, nil)
+				db.On("GetDestination", int64(1)).Return(mars, nil)
+				db.On("CreateHold", mock.MatchedBy(func(h *models.SeatHold) bool {
+					return h.Seats == want && h.ExpiresAt.Equal(bookingDay.Add(10*time.Minute))
+				}), 50).Run(func(args mock.Arguments) {
+					h := args.Get(0).(*models.SeatHold)
+					h.ID, h.Token, h.Status = 5, "H7MQ2XK9RT", models.HoldActive
+				}).Return(tt.err).Once()
+			}
+			handler := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)),
+				WithConflictProviders(conflicts)).RegisterRoutes()
+
+			body, err := json.Marshal(map[string]any{
+				"launchpad_id": "test_launchpad", "destination_id": 1, "launch_date": "2049-12-25T00:00:00Z", "seats": tt.seats,
+			})
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/holds", bytes.NewBuffer(body)))
+
+			assert.Equal(t, tt.want, rr.Code)
+			if tt.msg != "" {
+				assert.Equal(t, tt.msg, rr.Body.String())
+			}
+			if tt.want == http.StatusCreated {
+				var got models.SeatHold
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				assert.Equal(t, "H7MQ2XK9RT", got.Token)
+				assert.Equal(t, want, got.Seats)
+			}
+			db.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateBookingHandlerHold(t *testing.T) {
+	holdBody := func(t *testing.T) *bytes.Buffer {
+		body, err := json.Marshal(map[string]any{
+			"first_name": "Test", "last_name": "User", "email": "test@example.com",
+			"birthday": "1990-01-01T00:00:00Z", "launchpad_id": "test_launchpad", "destination_id": 1,
+			"launch_date": "2049-12-25T00:00:00Z", "payment_method": "tok_visa", "hold_token": "H7MQ2XK9RT",
+			// The ID of someone else's hold is not enough.
+			"hold_id": 6,
+		})
+		require.NoError(t, err)
+		return bytes.NewBuffer(body)
+	}
+	withHold := mock.MatchedBy(func(b *models.Booking) bool { return b.HoldToken == "H7MQ2XK9RT" && b.HoldID == nil })
+
+	t.Run("takes a held seat on a full flight", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+		conflicts := new(MockConflictProvider)
+		conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).Return([]conflict.Conflict(nil), nil)
+		db.On("CheckDestinationSchedule", int64(1), "test_launchpad", launchDate).Return(true, nil)
+		// 49 bookings and this passenger's held seat fill the flight.
+		db.On("GetDestination", int64(1)).Return(mars, nil)
+		db.On("GetBookingsOnLaunchpad", "test_launchpad", launchDate, launchDate).Return(make([]models.Booking, 49), nil)
+		db.On("CountHeldSeats", "test_launchpad", launchDate).Return(1, nil)
+		handler := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts),
+			WithPaymentProvider(payment.NewFake("whsec", clock.NewFake(bookingDay)))).RegisterRoutes()
+		expectPaidBooking(t, db, withHold)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", holdBody(t)))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("hold has expired", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		handler := paymentServer(db, payment.NewFake("whsec", clock.NewFake(bookingDay)))
+		db.On("CreateBooking", withHold, 50).Return(&models.HoldError{ID: 5, Reason: "has expired"}).Once()
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", holdBody(t)))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, "Seat hold has expired.\n", rr.Body.String())
+		db.AssertExpectations(t)
+	})
+}
+
+func TestGetHoldHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db)).RegisterRoutes()
+	db.On("GetHold", "H7MQ2XK9RT").Return(&models.SeatHold{ID: 5, Token: "H7MQ2XK9RT", Status: models.HoldActive}, nil).Once()
+	db.On("GetHold", "5").Return(nil, database.ErrNotFound).Once()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/holds/H7MQ2XK9RT", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// The ID of a hold does not name it.
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/holds/5", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	db.AssertExpectations(t)
+}
+
+func TestReleaseHoldHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db)).RegisterRoutes()
+	db.On("ReleaseHold", "H7MQ2XK9RT").Return(&models.SeatHold{ID: 5, Status: models.HoldReleased}, nil).Once()
+	db.On("ReleaseHold", "C4VN8PW3ZL").Return(&models.SeatHold{ID: 6, Status: models.HoldConverted}, database.ErrHoldClosed).Once()
+	db.On("ReleaseHold", "7").Return(nil, database.ErrNotFound).Once()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/holds/H7MQ2XK9RT", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/holds/C4VN8PW3ZL", nil))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "Seat hold is converted and cannot be released.\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/holds/7", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	db.AssertExpectations(t)
+}
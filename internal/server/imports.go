@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"space-booking/internal/bulk"
+	"space-booking/internal/clock"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/pricing"
+	"strconv"
+	"time"
+)
+
+// maxImportRows bounds the rows of an imported file and maxImportBytes its
+// size.
+const (
+	maxImportRows  = 5000
+	maxImportBytes = 10 << 20
+)
+
+// The ways an import commits its bookings.
+const (
+	// importAtomic creates every booking or, when any row is invalid, none.
+	importAtomic = "atomic"
+	// importBestEffort creates the valid bookings and reports the others.
+	importBestEffort = "best_effort"
+)
+
+// The outcomes of an imported row.
+const (
+	rowValid   = "valid"
+	rowInvalid = "invalid"
+	rowCreated = "created"
+	rowFailed  = "failed"
+)
+
+// importRow is the outcome of a row of an imported file.
+type importRow struct {
+	// Row counts the rows from 1, Line is where the row starts in the file.
+	Row     int             `json:"row"`
+	Line    int             `json:"line"`
+	Status  string          `json:"status"`
+	Error   string          `json:"error,omitempty"`
+	Booking *models.Booking `json:"booking,omitempty"`
+}
+
+// importReport is the response of ImportBookingsHandler.
+type importReport struct {
+	DryRun  bool        `json:"dry_run"`
+	Mode    string      `json:"mode"`
+	Total   int         `json:"total"`
+	Valid   int         `json:"valid"`
+	Invalid int         `json:"invalid"`
+	Created int         `json:"created"`
+	Rows    []importRow `json:"rows"`
+}
+
+// ImportBookingsHandler creates the bookings of a CSV or NDJSON file, as
+// told by the Content-Type header. Every row goes through the rules of
+// CreateBookingHandler and is priced at the current fare, with the
+// launchpad conflicts read once per launchpad for the whole file. The mode query parameter is atomic, the
+// default, or best_effort; with dry_run=true nothing is created. The
+// response reports the outcome of every row. The bookings are confirmed
+// without a payment, so the route is for operators only: agencies settle
+// them outside the system.
+func (s *Server) ImportBookingsHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := bulk.FormatOf(r.Header.Get("Content-Type"))
+	if !ok {
+		http.Error(w, "Send the bookings as text/csv or application/x-ndjson", http.StatusUnsupportedMediaType)
+		return
+	}
+	report := importReport{Mode: importAtomic}
+	if v := r.URL.Query().Get("mode"); v != "" {
+		if v != importAtomic && v != importBestEffort {
+			http.Error(w, "Unknown mode, expected atomic or best_effort", http.StatusBadRequest)
+			return
+		}
+		report.Mode = v
+	}
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid dry_run, expected true or false", http.StatusBadRequest)
+			return
+		}
+		report.DryRun = dryRun
+	}
+
+	rows, err := bulk.Read(http.MaxBytesReader(w, r.Body, maxImportBytes), format, maxImportRows)
+	var ferr *bulk.FileError
+	var merr *http.MaxBytesError
+	switch {
+	case errors.As(err, &ferr):
+		http.Error(w, "Invalid file: "+ferr.Error(), http.StatusBadRequest)
+		return
+	case errors.As(err, &merr):
+		http.Error(w, "The file is larger than "+strconv.Itoa(maxImportBytes>>20)+" MiB", http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		s.logger.Printf("Error reading imported bookings: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	case len(rows) == 0:
+		http.Error(w, "The file has no bookings", http.StatusBadRequest)
+		return
+	}
+
+	// Read the conflicts of every launchpad once, over the dates that can
+	// be booked at all.
+	dates := make(map[string][]time.Time)
+	for _, row := range rows {
+		if row.Err == nil && !row.Booking.LaunchDate.IsZero() && s.checkLaunchWindow(row.Booking.LaunchDate) == nil {
+			dates[row.Booking.LaunchpadID] = append(dates[row.Booking.LaunchpadID], row.Booking.LaunchDate)
+		}
+	}
+	conflicts, err := s.conflicts.Snapshot(r.Context(), dates)
+	if err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+
+	fares := newFareCache(s)
+	report.Total = len(rows)
+	report.Rows = make([]importRow, len(rows))
+	var valid []*models.Booking
+	for i := range rows {
+		row := &rows[i]
+		res := &report.Rows[i]
+		*res = importRow{Row: i + 1, Line: row.Line, Status: rowValid}
+
+		err := row.Err
+		if err == nil {
+			err = validateEmail(&row.Booking)
+		}
+		if err == nil {
+			err = s.checkBooking(r.Context(), &row.Booking, conflicts)
+		}
+		if err == nil {
+			err = fares.price(r.Context(), &row.Booking)
+		}
+		var verr *validationError
+		if err != nil && row.Err == nil && !errors.As(err, &verr) {
+			s.writeBookingError(w, err)
+			return
+		}
+		if err != nil {
+			res.Status = rowInvalid
+			res.Error = err.Error()
+			report.Invalid++
+			continue
+		}
+		row.Booking.Status = models.StatusConfirmed
+		res.Booking = &row.Booking
+		valid = append(valid, &row.Booking)
+		report.Valid++
+	}
+
+	switch {
+	case report.DryRun:
+		writeJSON(w, http.StatusOK, report)
+	case report.Mode == importAtomic:
+		if report.Invalid > 0 {
+			writeJSON(w, http.StatusUnprocessableEntity, report)
+			return
+		}
+		err := s.db.CreateBookings(r.Context(), valid, s.cfg.Pricing.Seats)
+		if errors.Is(err, database.ErrNoSeats) {
+			http.Error(w, "Not enough free seats for the bookings of the file.", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			s.logger.Printf("Error importing %d bookings: %v", len(valid), err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		for i := range report.Rows {
+			report.Rows[i].Status = rowCreated
+		}
+		report.Created = len(valid)
+		s.logger.Printf("Imported %d bookings", report.Created)
+		writeJSON(w, http.StatusCreated, report)
+	default:
+		for i := range report.Rows {
+			res := &report.Rows[i]
+			if res.Status != rowValid {
+				continue
+			}
+			err := s.db.CreateBooking(r.Context(), res.Booking, s.cfg.Pricing.Seats)
+			if errors.Is(err, database.ErrNoSeats) {
+				res.Status = rowFailed
+				res.Error = "Not enough free seats on this flight."
+				res.Booking = nil
+				continue
+			}
+			if err != nil {
+				s.logger.Printf("Error importing row %d: %v", res.Row, err)
+				res.Status = rowFailed
+				res.Error = "The booking could not be stored, please retry."
+				res.Booking = nil
+				continue
+			}
+			res.Status = rowCreated
+			report.Created++
+		}
+		s.logger.Printf("Imported %d of %d bookings", report.Created, report.Total)
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+// importFlight is a launchpad on a launch day.
+type importFlight struct {
+	launchpadID string
+	day         time.Time
+}
+
+// fareCache prices the bookings of an import at the current fares, reading
+// every destination and the seats sold on every flight once. The bookings
+// it prices take their seats.
+type fareCache struct {
+	s            *Server
+	destinations map[int64]*models.Destination
+	sold         map[importFlight]int
+}
+
+func newFareCache(s *Server) *fareCache {
+	return &fareCache{
+		s:            s,
+		destinations: make(map[int64]*models.Destination),
+		sold:         make(map[importFlight]int),
+	}
+}
+
+func (c *fareCache) price(ctx context.Context, booking *models.Booking) error {
+	destination, ok := c.destinations[booking.DestinationID]
+	if !ok {
+		d, err := c.s.db.GetDestination(ctx, booking.DestinationID)
+		if errors.Is(err, database.ErrNotFound) {
+			return &validationError{fmt.Sprintf("Destination %d does not exist.", booking.DestinationID)}
+		}
+		if err != nil {
+			return err
+		}
+		c.destinations[booking.DestinationID] = d
+		destination = d
+	}
+
+	flight := importFlight{launchpadID: booking.LaunchpadID, day: clock.Day(booking.LaunchDate)}
+	sold, ok := c.sold[flight]
+	if !ok {
+		bookings, err := c.s.db.GetBookingsOnLaunchpad(ctx, flight.launchpadID, flight.day, flight.day)
+		if err != nil {
+			return err
+		}
+		held, err := c.s.db.CountHeldSeats(ctx, flight.launchpadID, flight.day)
+		if err != nil {
+			return err
+		}
+		sold = len(bookings) + held
+	}
+
+	in := pricing.Input{
+		Destination: *destination,
+		LaunchDate:  booking.LaunchDate,
+		Birthday:    booking.Birthday,
+		SeatsSold:   sold,
+		Now:         c.s.clock.Now(),
+	}
+	if err := c.s.priceBooking(booking, "", in); err != nil {
+		c.sold[flight] = sold
+		return err
+	}
+	c.sold[flight] = sold + 1
+	return nil
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// importCSV has one valid row, one on a SpaceX launch and two malformed.
+const importCSV = `first_name,last_name,email,gender,birthday,launchpad_id,destination_id,launch_date
+Ada,Lovelace,ada@example.com,female,1990-01-01,pad_a,1,2049-12-24
+Alan,Turing,alan@example.com,male,1990-01-01,pad_a,1,2049-12-26
+Grace,Hopper,not-an-email,female,1990-01-01,pad_a,1,2049-12-25
+Linus,Torvalds,linus@example.com,male,1990-01-01,pad_a,x,2049-12-25
+`
+
+// importRequest posts a file as an operator.
+func importRequest(target, contentType, body string) *http.Request {
+	req := adminRequest(http.MethodPost, target, []byte(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+func TestImportBookingsHandler(t *testing.T) {
+	dec24 := time.Date(2049, time.December, 24, 0, 0, 0, 0, time.UTC)
+	dec26 := time.Date(2049, time.December, 26, 0, 0, 0, 0, time.UTC)
+	setup := func() (*MockDatabase, *MockConflictProvider) {
+		db := new(MockDatabase)
+		conflicts := new(MockConflictProvider)
+		// One lookup for the whole file
+		conflicts.On("Conflicts", "pad_a", dec24, dec26).
+			Return([]conflict.Conflict{{Provider: "spacex", LaunchpadID: "pad_a", Date: dec26, Reason: `SpaceX launch "Crew-99"`}}, nil).
+			Once()
+		db.On("CheckDestinationSchedule", int64(1), "pad_a", dec24).Return(true, nil).Once()
+		expectFare(db, "pad_a", dec24, 0)
+		return db, conflicts
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		want     int
+		created  int
+		statuses []string
+	}{
+		{"atomic rejects the file", "/bookings/import", http.StatusUnprocessableEntity, 0,
+			[]string{rowValid, rowInvalid, rowInvalid, rowInvalid}},
+		{"dry run", "/bookings/import?mode=best_effort&dry_run=true", http.StatusOK, 0,
+			[]string{rowValid, rowInvalid, rowInvalid, rowInvalid}},
+		{"best effort creates the valid rows", "/bookings/import?mode=best_effort", http.StatusOK, 1,
+			[]string{rowCreated, rowInvalid, rowInvalid, rowInvalid}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db, conflicts := setup()
+			if tt.created > 0 {
+				db.On("CreateBooking", mock.AnythingOfType("*models.Booking"), 50).Return(nil).Once()
+			}
+			handler := newServer(WithConfig(adminConfig()), WithDatabase(db), WithClock(clock.NewFake(bookingDay)),
+				WithConflictProviders(conflicts)).RegisterRoutes()
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, importRequest(tt.target, "text/csv", importCSV))
+
+			require.Equal(t, tt.want, rr.Code, rr.Body.String())
+			var report importReport
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+			assert.Equal(t, 4, report.Total)
+			assert.Equal(t, 1, report.Valid)
+			assert.Equal(t, 3, report.Invalid)
+			assert.Equal(t, tt.created, report.Created)
+			var statuses []string
+			for _, row := range report.Rows {
+				statuses = append(statuses, row.Status)
+			}
+			assert.Equal(t, tt.statuses, statuses)
+			assert.Equal(t, 3, report.Rows[1].Line)
+			assert.Equal(t, `Flight is cancelled due to scheduling conflicts: SpaceX launch "Crew-99" (reported by spacex).`, report.Rows[1].Error)
+			assert.Equal(t, `Email "not-an-email" is not a valid address.`, report.Rows[2].Error)
+			assert.Equal(t, `destination_id "x" is not a number`, report.Rows[3].Error)
+			db.AssertExpectations(t)
+			conflicts.AssertExpectations(t)
+		})
+	}
+}
+
+func TestImportBookingsHandlerAtomic(t *testing.T) {
+	resetVisitors()
+	dec24 := time.Date(2049, time.December, 24, 0, 0, 0, 0, time.UTC)
+	body := `{"first_name": "Ada", "last_name": "Lovelace", "email": "ada@example.com", "gender": "female", "birthday": "1990-01-01", "launchpad_id": "pad_a", "destination_id": 1, "launch_date": "2049-12-24"}
+
+{"first_name": "Alan", "last_name": "Turing", "email": "alan@example.com", "gender": "male", "birthday": "1990-01-01T00:00:00Z", "launchpad_id": "pad_a", "destination_id": 1, "launch_date": "2049-12-24T00:00:00Z"}
+`
+	db := new(MockDatabase)
+	conflicts := new(MockConflictProvider)
+	conflicts.On("Conflicts", "pad_a", dec24, dec24).Return([]conflict.Conflict(nil), nil).Once()
+	db.On("CheckDestinationSchedule", int64(1), "pad_a", dec24).Return(true, nil).Twice()
+	// Read once for both rows, which take the last two seats
+	db.On("GetDestination", int64(1)).Return(mars, nil).Once()
+	db.On("GetBookingsOnLaunchpad", "pad_a", dec24, dec24).Return(make([]models.Booking, 48), nil).Once()
+	db.On("CountHeldSeats", "pad_a", dec24).Return(0, nil).Once()
+	db.On("CreateBookings", mock.MatchedBy(func(bookings []*models.Booking) bool {
+		return len(bookings) == 2 && bookings[1].FirstName == "Alan" && bookings[1].Status == models.StatusConfirmed &&
+			bookings[0].Price == 148000000 && bookings[1].Price == 149000000
+	}), 50).Return(nil).Once()
+
+	handler := newServer(WithConfig(adminConfig()), WithDatabase(db), WithClock(clock.NewFake(bookingDay)),
+		WithConflictProviders(conflicts)).RegisterRoutes()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, importRequest("/bookings/import", "application/x-ndjson", body))
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var report importReport
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 3, report.Rows[1].Line)
+	db.AssertExpectations(t)
+	conflicts.AssertExpectations(t)
+}
+
+func TestImportBookingsHandlerRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		want        int
+		msg         string
+	}{
+		{"not an operator", "/bookings/import", "text/csv", importCSV, http.StatusUnauthorized, "Unauthorized\n"},
+		{"unsupported type", "/bookings/import", "application/json", "[]", http.StatusUnsupportedMediaType,
+			"Send the bookings as text/csv or application/x-ndjson\n"},
+		{"unknown mode", "/bookings/import?mode=some", "text/csv", importCSV, http.StatusBadRequest,
+			"Unknown mode, expected atomic or best_effort\n"},
+		{"missing column", "/bookings/import", "text/csv", "first_name,last_name\nAda,Lovelace\n", http.StatusBadRequest,
+			"Invalid file: line 1: column \"email\" is missing\n"},
+		{"no rows", "/bookings/import", "text/csv", strings.Split(importCSV, "\n")[0] + "\n", http.StatusBadRequest,
+			"The file has no bookings\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			handler := newServer(WithConfig(adminConfig()), WithDatabase(db), WithClock(clock.NewFake(bookingDay))).RegisterRoutes()
+			req := importRequest(tt.target, tt.contentType, tt.body)
+			if tt.want == http.StatusUnauthorized {
+				req.Header.Del("Authorization")
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.want, rr.Code)
+			assert.Equal(t, tt.msg, rr.Body.String())
+			db.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"space-booking/internal/clock"
+	"space-booking/internal/manifest"
+	"strings"
+	"time"
+)
+
+// GetManifestHandler returns the passengers flying from the launchpad query
+// parameter on the date query parameter, grouped by destination. The
+// format query parameter, or else the Accept header, selects JSON, CSV or
+// a printable HTML page.
+func (s *Server) GetManifestHandler(w http.ResponseWriter, r *http.Request) {
+	launchpadID := r.URL.Query().Get("launchpad")
+	if launchpadID == "" {
+		http.Error(w, "The launchpad query parameter is required", http.StatusBadRequest)
+		return
+	}
+	day, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	format := manifestFormat(r)
+	if format == "" {
+		http.Error(w, "Unknown format, expected json, csv or html", http.StatusBadRequest)
+		return
+	}
+
+	m, err := manifest.Load(r.Context(), s.db, launchpadID, day, s.clock.Now())
+	if err != nil {
+		s.logger.Printf("Error building the manifest of %s on %s: %v", launchpadID, day.Format("2006-01-02"), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	name := fmt.Sprintf("manifest-%s-%s", launchpadID, clock.Day(day).Format("2006-01-02"))
+	var buf bytes.Buffer
+	switch format {
+	case "csv":
+		err = manifest.WriteCSV(&buf, m)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, name))
+	case "html":
+		err = manifest.WriteHTML(&buf, m)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	default:
+		writeJSON(w, http.StatusOK, m)
+		return
+	}
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		s.logger.Printf("Error writing the manifest of %s: %v", launchpadID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+// manifestFormat returns json, csv or html as asked for by the request, or
+// "" for a format that is not offered.
+func manifestFormat(r *http.Request) string {
+	if v := r.URL.Query().Get("format"); v != "" {
+		switch v {
+		case "json", "csv", "html":
+			return v
+		}
+		return ""
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return "csv"
+	case strings.Contains(accept, "text/html"):
+		return "html"
+	}
+	return "json"
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetManifestHandler(t *testing.T) {
+	day := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	manifestDB := func() *MockDatabase {
+		db := new(MockDatabase)
+		b := gateBooking(models.StatusCheckedIn)
+		db.On("GetBookingsOnLaunchpad", "pad_a", day, day).Return([]models.Booking{*b}, nil).Once()
+		db.On("GetDestinationName", b.DestinationID).Return("", database.ErrNotFound).Once()
+		return db
+	}
+
+	t.Run("json", func(t *testing.T) {
+		resetVisitors()
+		db := manifestDB()
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/manifests?launchpad=pad_a&date=2049-12-25", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"passengers":1`)
+		assert.Contains(t, rr.Body.String(), `"code":"K7QX2MWP9D"`)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("csv from the Accept header", func(t *testing.T) {
+		resetVisitors()
+		db := manifestDB()
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+		req := adminRequest(http.MethodGet, "/manifests?launchpad=pad_a&date=2049-12-25", nil)
+		req.Header.Set("Accept", "text/csv")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="manifest-pad_a-2049-12-25.csv"`, rr.Header().Get("Content-Disposition"))
+		assert.Len(t, strings.Split(strings.TrimSpace(rr.Body.String()), "\n"), 2)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("rejected requests", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			target string
+			admin  bool
+			want   int
+		}{
+			{"not an operator", "/manifests?launchpad=pad_a&date=2049-12-25", false, http.StatusUnauthorized},
+			{"no launchpad", "/manifests?date=2049-12-25", true, http.StatusBadRequest},
+			{"invalid date", "/manifests?launchpad=pad_a&date=25-12-2049", true, http.StatusBadRequest},
+			{"unknown format", "/manifests?launchpad=pad_a&date=2049-12-25&format=pdf", true, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resetVisitors()
+				db := new(MockDatabase)
+				handler := newServer(WithConfig(adminConfig()), WithDatabase(db)).RegisterRoutes()
+				req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+				if tt.admin {
+					req = adminRequest(http.MethodGet, tt.target, nil)
+				}
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				assert.Equal(t, tt.want, rr.Code)
+				db.AssertExpectations(t)
+			})
+		}
+	})
+}
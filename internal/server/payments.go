@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/payment"
+	"strconv"
+)
+
+// maxPaymentEvent caps the body of a payment event.
+const maxPaymentEvent = 64 << 10
+
+// bookingPayment is a new booking with the payment made for it.
+type bookingPayment struct {
+	models.Booking
+	Payment *models.Payment `json:"payment,omitempty"`
+}
+
+// pay takes the fare of a pending booking and writes the outcome: 201
+// Created once the payment is captured and the booking confirmed, 202
+// Accepted while the provider has yet to answer, 402 Payment Required when
+// it declined and 502 Bad Gateway when it could not be reached. A booking
+// whose payment did not go through is cancelled, releasing its seat.
+func (s *Server) pay(w http.ResponseWriter, r *http.Request, booking *models.Booking, method string) {
+	ctx := r.Context()
+	p := &models.Payment{
+		BookingID: booking.ID,
+		Provider:  s.payments.Name(),
+		Amount:    booking.Price,
+		Currency:  booking.Currency,
+	}
+	if err := s.db.CreatePayment(ctx, p); err != nil {
+		s.logger.Printf("Error creating the payment of booking %d: %v", booking.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	auth, err := s.payments.Authorize(ctx, payment.Request{
+		Reference: p.Reference(),
+		Amount:    p.Amount,
+		Currency:  p.Currency,
+		Method:    method,
+	})
+	if err != nil {
+		s.logger.Printf("Error authorizing payment %d: %v", p.ID, err)
+		s.failPayment(ctx, p.ID, "", "Payment provider is unavailable.")
+		http.Error(w, "Payment provider is temporarily unavailable, please retry later.", http.StatusBadGateway)
+		return
+	}
+
+	p.ProviderRef = auth.ID
+	switch auth.Status {
+	case payment.Authorized:
+		confirmed, err := s.capture(ctx, p.ID, auth.ID)
+		if err != nil {
+			// The capture is retried in the background; the booking stays
+			// pending until it is settled.
+			s.logger.Printf("Error capturing payment %d: %v", p.ID, err)
+			http.Error(w, "Payment could not be completed, please retry later.", http.StatusBadGateway)
+			return
+		}
+		p.Status = models.PaymentCaptured
+		p.CapturedAt = confirmed.ConfirmedAt
+		writeJSON(w, http.StatusCreated, bookingPayment{Booking: *confirmed, Payment: p})
+	case payment.Declined:
+		reason := auth.Reason
+		if reason == "" {
+			reason = "Payment was declined."
+		}
+		s.failPayment(ctx, p.ID, auth.ID, reason)
+		http.Error(w, fmt.Sprintf("Payment was declined: %s", reason), http.StatusPaymentRequired)
+	default:
+		writeJSON(w, http.StatusAccepted, bookingPayment{Booking: *booking, Payment: p})
+	}
+}
+
+// capture takes the authorized payment and confirms its booking.
+func (s *Server) capture(ctx context.Context, id int64, providerRef string) (*models.Booking, error) {
+	return payment.NewCapturer(s.db, s.payments, s.clock, s.logger).Capture(ctx, id, providerRef)
+}
+
+// failPayment gives up a payment that did not go through and cancels its
+// booking. An error is only logged: the booking expires anyway.
+func (s *Server) failPayment(ctx context.Context, id int64, providerRef, reason string) {
+	if _, err := s.db.FailPayment(ctx, id, providerRef, reason); err != nil {
+		s.logger.Printf("Error failing payment %d: %v", id, err)
+	}
+}
+
+// PaymentWebhookHandler receives the outcome of the authorizations the
+// provider answered later. An authorized payment is captured and confirms
+// its booking; a declined one cancels it. Payments that were settled, and
+// bookings that expired in the meantime, are acknowledged without a change,
+// so that the provider stops sending the event.
+func (s *Server) PaymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPaymentEvent))
+	if err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	ev, err := s.payments.ParseEvent(r.Header, body)
+	if err != nil {
+		s.logger.Printf("Rejected a payment event: %v", err)
+		http.Error(w, "Invalid payment event", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(ev.Reference, 10, 64)
+	if err != nil {
+		http.Error(w, "Payment not found", http.StatusNotFound)
+		return
+	}
+
+	var booking *models.Booking
+	if ev.Status == payment.Authorized {
+		booking, err = s.capture(r.Context(), id, ev.PaymentID)
+	} else {
+		reason := ev.Reason
+		if reason == "" {
+			reason = "Payment was declined."
+		}
+		booking, err = s.db.FailPayment(r.Context(), id, ev.PaymentID, reason)
+	}
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		http.Error(w, "Payment not found", http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrPaymentClosed):
+		s.logger.Printf("Payment %d is closed, its %s event changes nothing", id, ev.Status)
+	case err != nil:
+		s.logger.Printf("Error settling payment %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	default:
+		s.logger.Printf("Payment %d is %s, booking %d is now %s", id, ev.Status, booking.ID, booking.Status)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPaymentsHandler returns the payments of a booking with their
+// refunds.
+func (s *Server) ListPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.db.GetBooking(r.Context(), id); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "Booking not found", http.StatusNotFound)
+			return
+		}
+		s.logger.Printf("Error retrieving booking %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	payments, err := s.db.GetPayments(r.Context(), id)
+	if err != nil {
+		s.logger.Printf("Error retrieving the payments of booking %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if payments == nil {
+		payments = []models.Payment{}
+	}
+	writeJSON(w, http.StatusOK, payments)
+}
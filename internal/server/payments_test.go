@@ -0,0 +1,248 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"space-booking/internal/cancellation"
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/payment"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// expectPaidBooking expects the booking matching match to be created as
+// pending, as booking 7, and confirmed once its payment 3 is captured.
+func expectPaidBooking(t *testing.T, db *MockDatabase, match any) {
+	var booking *models.Booking
+	confirmed := &models.Booking{}
+	db.On("CreateBooking", match, 50).Run(func(args mock.Arguments) {
+		booking = args.Get(0).(*models.Booking)
+		assert.Equal(t, models.StatusPending, booking.Status)
+		booking.ID = 7
+	}).Return(nil).Once()
+	db.On("CreatePayment", mock.AnythingOfType("*models.Payment")).Run(func(args mock.Arguments) {
+		p := args.Get(0).(*models.Payment)
+		assert.Equal(t, booking.Price, p.Amount)
+		p.ID = 3
+	}).Return(nil).Once()
+	capturing := &models.Payment{ID: 3, Status: models.PaymentCapturing}
+	db.On("StartCapture", int64(3), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		capturing.ProviderRef = args.String(1)
+		capturing.Amount, capturing.Currency = booking.Price, booking.Currency
+	}).Return(capturing, nil).Once()
+	db.On("ConfirmPayment", int64(3)).Run(func(args mock.Arguments) {
+		*confirmed = *booking
+		confirmed.Status = models.StatusConfirmed
+	}).Return(confirmed, nil).Once()
+}
+
+// paymentServer serves bookings launching on December 25, 2049 at the
+// base fare.
+func paymentServer(db *MockDatabase, provider payment.Provider) http.Handler {
+	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	conflicts := new(MockConflictProvider)
+	conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).Return([]conflict.Conflict(nil), nil)
+	db.On("CheckDestinationSchedule", int64(1), "test_launchpad", launchDate).Return(true, nil)
+	expectFare(db, "test_launchpad", launchDate, 0)
+	return newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts),
+		WithPaymentProvider(provider)).RegisterRoutes()
+}
+
+func bookingBody(t *testing.T, method string) *bytes.Buffer {
+	body, err := json.Marshal(map[string]any{
+		"first_name": "Test", "last_name": "User", "email": "test@example.com",
+		"birthday": "1990-01-01T00:00:00Z", "launchpad_id": "test_launchpad", "destination_id": 1,
+		"launch_date": "2049-12-25T00:00:00Z", "payment_method": method,
+	})
+	require.NoError(t, err)
+	return bytes.NewBuffer(body)
+}
+
+func TestCreateBookingHandlerPayment(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		want   int
+		fail   string
+		msg    string
+	}{
+		{"declined", payment.FakeDeclined, http.StatusPaymentRequired, "Card declined.",
+			"Payment was declined: Card declined.\n"},
+		{"provider down", payment.FakeUnavailable, http.StatusBadGateway, "Payment provider is unavailable.",
+			"Payment provider is temporarily unavailable, please retry later.\n"},
+		{"pending", payment.FakeAsync, http.StatusAccepted, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			handler := paymentServer(db, payment.NewFake("whsec", clock.NewFake(bookingDay)))
+			db.On("CreateBooking", mock.AnythingOfType("*models.Booking"), 50).Run(func(args mock.Arguments) {
+				args.Get(0).(*models.Booking).ID = 7
+			}).Return(nil).Once()
+			db.On("CreatePayment", mock.AnythingOfType("*models.Payment")).Run(func(args mock.Arguments) {
+				args.Get(0).(*models.Payment).ID = 3
+			}).Return(nil).Once()
+			if tt.fail != "" {
+				db.On("FailPayment", int64(3), mock.Anything, tt.fail).
+					Return(&models.Booking{ID: 7, Status: models.StatusCancelled}, nil).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", bookingBody(t, tt.method)))
+
+			assert.Equal(t, tt.want, rr.Code)
+			if tt.msg != "" {
+				assert.Equal(t, tt.msg, rr.Body.String())
+			}
+			if tt.want == http.StatusAccepted {
+				var got bookingPayment
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				assert.Equal(t, models.StatusPending, got.Status)
+				require.NotNil(t, got.Payment)
+				assert.Equal(t, int64(3), got.Payment.ID)
+				assert.Equal(t, int64(100000000), got.Payment.Amount)
+			}
+			db.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateBookingHandlerFlightFull(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := paymentServer(db, payment.NewFake("whsec", clock.NewFake(bookingDay)))
+	// The last seat went to another booking after the fare was read.
+	db.On("CreateBooking", mock.AnythingOfType("*models.Booking"), 50).Return(database.ErrNoSeats).Once()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", bookingBody(t, "tok_visa")))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "Not enough free seats on this flight.\n", rr.Body.String())
+	db.AssertNotCalled(t, "CreatePayment", mock.Anything)
+	db.AssertExpectations(t)
+}
+
+func TestPaymentWebhookHandler(t *testing.T) {
+	clk := clock.NewFake(bookingDay)
+	provider := payment.NewFake("whsec", clk)
+	auth, err := provider.Authorize(context.Background(), payment.Request{Reference: "3", Amount: 100000000, Method: payment.FakeAsync})
+	require.NoError(t, err)
+	event := func(status payment.Status, reference string) (http.Header, []byte) {
+		header, body, err := provider.Event(payment.Event{PaymentID: auth.ID, Reference: reference, Status: status})
+		require.NoError(t, err)
+		return header, body
+	}
+
+	tests := []struct {
+		name   string
+		status payment.Status
+		ref    string
+		tamper bool
+		setup  func(db *MockDatabase)
+		want   int
+	}{
+		{"authorized", payment.Authorized, "3", false, func(db *MockDatabase) {
+			db.On("StartCapture", int64(3), auth.ID, bookingDay.Add(30*time.Second)).
+				Return(&models.Payment{ID: 3, ProviderRef: auth.ID, Amount: 100000000, Status: models.PaymentCapturing}, nil).Once()
+			db.On("ConfirmPayment", int64(3)).Return(&models.Booking{ID: 7, Status: models.StatusConfirmed}, nil).Once()
+		}, http.StatusNoContent},
+		{"capture failed", payment.Authorized, "3", false, func(db *MockDatabase) {
+			// Authorized under a reference the provider does not know.
+			db.On("StartCapture", int64(3), auth.ID, mock.Anything).
+				Return(&models.Payment{ID: 3, ProviderRef: "fake_pay_unknown", Amount: 100000000, Status: models.PaymentCapturing}, nil).Once()
+			db.On("RecordCaptureAttempt", mock.MatchedBy(func(p *models.Payment) bool {
+				return p.Status == models.PaymentCapturing && p.CaptureAttempts == 1 && p.NextCaptureAt != nil
+			})).Return(nil).Once()
+		}, http.StatusInternalServerError},
+		{"declined", payment.Declined, "3", false, func(db *MockDatabase) {
+			db.On("FailPayment", int64(3), auth.ID, "Payment was declined.").
+				Return(&models.Booking{ID: 7, Status: models.StatusCancelled}, nil).Once()
+		}, http.StatusNoContent},
+		{"booking expired", payment.Authorized, "3", false, func(db *MockDatabase) {
+			db.On("StartCapture", int64(3), auth.ID, mock.Anything).Return(nil, database.ErrPaymentClosed).Once()
+		}, http.StatusNoContent},
+		{"unknown payment", payment.Declined, "4", false, func(db *MockDatabase) {
+			db.On("FailPayment", int64(4), auth.ID, "Payment was declined.").Return(nil, database.ErrNotFound).Once()
+		}, http.StatusNotFound},
+		{"forged", payment.Authorized, "3", true, func(db *MockDatabase) {}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			tt.setup(db)
+			handler := newServer(WithDatabase(db), WithClock(clk), WithPaymentProvider(provider)).RegisterRoutes()
+
+			header, body := event(tt.status, tt.ref)
+			if tt.tamper {
+				body = bytes.Replace(body, []byte(`"3"`), []byte(`"4"`), 1)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/payments/webhook", bytes.NewReader(body))
+			req.Header = header
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.want, rr.Code)
+			db.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminCancelBookingRefunds(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	cancelledAt := bookingDay
+	db.On("GetBooking", 7).Return(&models.Booking{ID: 7, Status: models.StatusConfirmed, LaunchDate: bookingDay}, nil)
+	db.On("CancelBooking", 7, refundTerms(&models.Booking{Status: models.StatusConfirmed, LaunchDate: bookingDay},
+		models.RefundTerms{Share: 1, Reason: cancellation.OperatorReason})).
+		Return(&models.Booking{ID: 7, Status: models.StatusCancelled, CancelledAt: &cancelledAt}, nil).Twice()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodDelete, "/bookings/7", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPut, "/admin/bookings/7/status", []byte(`{"status":"cancelled"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	db.AssertExpectations(t)
+}
+
+func TestListPaymentsHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	db.On("GetBooking", 7).Return(&models.Booking{ID: 7, Status: models.StatusCancelled}, nil)
+	db.On("GetPayments", 7).Return([]models.Payment{{
+		ID: 3, BookingID: 7, Provider: "fake", Amount: 100000000, Currency: "USD", Status: models.PaymentCaptured,
+		Refunds: []models.Refund{{ID: 1, PaymentID: 3, Amount: 100000000, Status: models.RefundPending}},
+	}}, nil)
+	db.On("GetBooking", 8).Return(nil, database.ErrNotFound)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/bookings/7/payments", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var payments []models.Payment
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &payments))
+	require.Len(t, payments, 1)
+	assert.Equal(t, int64(100000000), payments[0].Refunds[0].Amount)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/bookings/8/payments", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"time"
+)
+
+// promoCodePattern is what a promo code may look like once normalized.
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// promoRequest is the body of the create and update handlers.
+type promoRequest struct {
+	// Code cannot be changed once the promo code is created.
+	Code           string     `json:"code"`
+	Kind           string     `json:"kind"`
+	Value          int64      `json:"value"`
+	Currency       string     `json:"currency"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	DestinationIDs []int64    `json:"destination_ids"`
+	MaxRedemptions int        `json:"max_redemptions"`
+	// Active defaults to true.
+	Active *bool `json:"active"`
+}
+
+// ListPromoCodesHandler lists the promo codes.
+func (s *Server) ListPromoCodesHandler(w http.ResponseWriter, r *http.Request) {
+	codes, err := s.db.ListPromoCodes(r.Context())
+	if err != nil {
+		s.logger.Printf("Error retrieving promo codes: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if codes == nil {
+		codes = []models.PromoCode{}
+	}
+	writeJSON(w, http.StatusOK, codes)
+}
+
+// GetPromoCodeHandler returns a single promo code.
+func (s *Server) GetPromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid promo code ID", http.StatusBadRequest)
+		return
+	}
+	code, err := s.db.GetPromoCode(r.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Promo code not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error retrieving promo code %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, code)
+}
+
+// CreatePromoCodeHandler creates a promo code. Codes are unique in any
+// case.
+func (s *Server) CreatePromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodePromo(w, r)
+	if !ok {
+		return
+	}
+	code := req.promoCode()
+	if !promoCodePattern.MatchString(code.Code) {
+		http.Error(w, "Code must be 3 to 32 letters, digits, hyphens or underscores.", http.StatusBadRequest)
+		return
+	}
+
+	err := s.db.CreatePromoCode(r.Context(), code)
+	if errors.Is(err, database.ErrPromoCodeTaken) {
+		http.Error(w, fmt.Sprintf("Promo code %s already exists.", code.Code), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error creating promo code: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, code)
+}
+
+// UpdatePromoCodeHandler changes the terms of a promo code. Its code
+// cannot be changed, and is ignored in the body.
+func (s *Server) UpdatePromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid promo code ID", http.StatusBadRequest)
+		return
+	}
+	req, ok := s.decodePromo(w, r)
+	if !ok {
+		return
+	}
+	code := req.promoCode()
+	code.ID = id
+
+	err := s.db.UpdatePromoCode(r.Context(), code)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Promo code not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error updating promo code %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, code)
+}
+
+// DeletePromoCodeHandler deletes a promo code that was never redeemed.
+// One that was is deactivated instead, so that its redemptions stay
+// reported.
+func (s *Server) DeletePromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid promo code ID", http.StatusBadRequest)
+		return
+	}
+	err := s.db.DeletePromoCode(r.Context(), id)
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		http.Error(w, "Promo code not found", http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrPromoCodeRedeemed):
+		http.Error(w, "Promo code was redeemed, deactivate it instead.", http.StatusConflict)
+		return
+	case err != nil:
+		s.logger.Printf("Error deleting promo code %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPromoRedemptionsHandler returns the redemptions of a promo code with
+// the status of their bookings.
+func (s *Server) ListPromoRedemptionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid promo code ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.db.GetPromoCode(r.Context(), id); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "Promo code not found", http.StatusNotFound)
+			return
+		}
+		s.logger.Printf("Error retrieving promo code %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	redemptions, err := s.db.ListPromoRedemptions(r.Context(), id)
+	if err != nil {
+		s.logger.Printf("Error retrieving redemptions of promo code %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if redemptions == nil {
+		redemptions = []models.PromoRedemption{}
+	}
+	writeJSON(w, http.StatusOK, redemptions)
+}
+
+// PromoReportHandler reports the redemptions, discounts and revenue of
+// every promo code, counting the redemptions made from the from query
+// parameter until the to one, RFC 3339 times that are both optional.
+func (s *Server) PromoReportHandler(w http.ResponseWriter, r *http.Request) {
+	var from, to time.Time
+	for _, p := range []struct {
+		param string
+		dst   *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v := r.URL.Query().Get(p.param)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid %s, expected an RFC 3339 time", p.param), http.StatusBadRequest)
+			return
+		}
+		*p.dst = parsed
+	}
+
+	report, err := s.db.GetPromoReport(r.Context(), from, to)
+	if err != nil {
+		s.logger.Printf("Error reporting promo codes: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		report = []models.PromoReport{}
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// decodePromo reads and validates a promo code from the request body. It
+// writes the error response itself and reports whether decoding
+// succeeded.
+func (s *Server) decodePromo(w http.ResponseWriter, r *http.Request) (*promoRequest, bool) {
+	var req promoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Printf("Invalid promo code data: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// A fixed discount is in the currency of the fares; a percent one has
+	// none.
+	if req.Kind == models.PromoPercent {
+		req.Currency = ""
+	} else if req.Currency == "" {
+		req.Currency = s.cfg.Pricing.Currency
+	}
+	msg := ""
+	switch {
+	case req.Kind != models.PromoPercent && req.Kind != models.PromoFixed:
+		msg = fmt.Sprintf("Kind must be %s or %s.", models.PromoPercent, models.PromoFixed)
+	case req.Kind == models.PromoPercent && (req.Value < 1 || req.Value > 100):
+		msg = "Value of a percent code must be from 1 to 100."
+	case req.Kind == models.PromoFixed && req.Value < 1:
+		msg = "Value of a fixed code must be positive."
+	case req.Kind == models.PromoFixed && req.Currency != s.cfg.Pricing.Currency:
+		msg = fmt.Sprintf("Currency must be %s, that of the fares.", s.cfg.Pricing.Currency)
+	case req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidUntil.After(*req.ValidFrom):
+		msg = "Valid until must be after valid from."
+	case req.MaxRedemptions < 0:
+		msg = "Max redemptions must not be negative."
+	}
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// promoCode returns the promo code described by the request.
+func (req *promoRequest) promoCode() *models.PromoCode {
+	destinationIDs := req.DestinationIDs
+	if destinationIDs == nil {
+		destinationIDs = []int64{}
+	}
+	return &models.PromoCode{
+		Code:           models.NormalizePromoCode(req.Code),
+		Kind:           req.Kind,
+		Value:          req.Value,
+		Currency:       req.Currency,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+		DestinationIDs: destinationIDs,
+		MaxRedemptions: req.MaxRedemptions,
+		Active:         req.Active == nil || *req.Active,
+	}
+}
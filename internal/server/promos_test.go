@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/payment"
+	"space-booking/internal/pricing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// promoBookingBody is bookingBody with a promo code.
+func promoBookingBody(t *testing.T, method, code string) *bytes.Buffer {
+	var fields map[string]any
+	require.NoError(t, json.NewDecoder(bookingBody(t, method)).Decode(&fields))
+	fields["promo_code"] = code
+	body, err := json.Marshal(fields)
+	require.NoError(t, err)
+	return bytes.NewBuffer(body)
+}
+
+func TestCreateQuoteHandlerPromo(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	conflicts := new(MockConflictProvider)
+	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).Return([]conflict.Conflict(nil), nil)
+	db.On("CheckDestinationSchedule", int64(1), "test_launchpad", launchDate).Return(true, nil)
+	expectFare(db, "test_launchpad", launchDate, 0)
+	db.On("GetPromoCodeByCode", "MARS10").
+		Return(&models.PromoCode{ID: 4, Code: "MARS10", Kind: models.PromoPercent, Value: 10, Active: true}, nil)
+	db.On("GetPromoCodeByCode", "VENUS").Return(nil, database.ErrNotFound)
+
+	signer, err := pricing.GenerateSigner()
+	require.NoError(t, err)
+	handler := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts),
+		WithQuoteSigner(signer)).RegisterRoutes()
+	quote := func(code string) *httptest.ResponseRecorder {
+		body := `{"launchpad_id": "test_launchpad", "destination_id": 1, "launch_date": "2049-12-25T00:00:00Z",
+			"birthday": "1990-01-01T00:00:00Z", "promo_code": "` + code + `"}`
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes", bytes.NewBufferString(body)))
+		return rr
+	}
+
+	rr := quote(" mars10 ")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var got models.Quote
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, int64(90000000), got.Price)
+	assert.Equal(t, int64(10000000), got.Discount)
+	assert.Equal(t, "MARS10", got.PromoCode)
+	terms, err := signer.Verify(got.ID, bookingDay)
+	require.NoError(t, err)
+	assert.Equal(t, "MARS10", terms.PromoCode, "Expected the promo code to be signed with the quote")
+
+	rr = quote("venus")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Promo code VENUS does not exist.\n", rr.Body.String())
+}
+
+func TestCreateBookingHandlerPromo(t *testing.T) {
+	promo := &models.PromoCode{ID: 4, Code: "MARS10", Kind: models.PromoPercent, Value: 10, Active: true}
+	free := &models.PromoCode{ID: 5, Code: "CREW", Kind: models.PromoPercent, Value: 100, Active: true}
+	expired := time.Date(2049, time.November, 1, 0, 0, 0, 0, time.UTC)
+	late := &models.PromoCode{ID: 6, Code: "EARLY", Kind: models.PromoFixed, Value: 500, Currency: "USD",
+		ValidUntil: &expired, Active: true}
+
+	t.Run("discounted", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		handler := paymentServer(db, payment.NewFake("whsec", clock.NewFake(bookingDay)))
+		db.On("GetPromoCodeByCode", "MARS10").Return(promo, nil)
+		expectPaidBooking(t, db, mock.MatchedBy(func(b *models.Booking) bool {
+			return b.PromoCode == "MARS10" && b.Discount == 10000000 && b.Price == 90000000
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", promoBookingBody(t, "tok_visa", "mars10")))
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		db.AssertExpectations(t)
+	})
+
+	t.Run("free", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		handler := paymentServer(db, payment.NewFake("whsec", clock.NewFake(bookingDay)))
+		db.On("GetPromoCodeByCode", "CREW").Return(free, nil)
+		db.On("CreateBooking", mock.MatchedBy(func(b *models.Booking) bool {
+			return b.Status == models.StatusConfirmed && b.Price == 0 && b.Discount == 100000000
+		}), 50).Return(nil).Once()
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", promoBookingBody(t, "", "crew")))
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var got bookingPayment
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.Nil(t, got.Payment, "Expected nothing to be paid")
+		db.AssertExpectations(t)
+	})
+
+	t.Run("discount without a promo code", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		handler := paymentServer(db, payment.NewFake("whsec", clock.NewFake(bookingDay)))
+		expectPaidBooking(t, db, mock.MatchedBy(func(b *models.Booking) bool {
+			return b.Discount == 0 && b.Price == 100000000
+		}))
+		body, err := json.Marshal(map[string]any{
+			"first_name": "Test", "last_name": "User", "email": "test@example.com",
+			"birthday": "1990-01-01T00:00:00Z", "launchpad_id": "test_launchpad", "destination_id": 1,
+			"launch_date": "2049-12-25T00:00:00Z", "payment_method": "tok_visa", "discount_cents": 99000000,
+		})
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(body)))
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		db.AssertExpectations(t)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		handler := paymentServer(db, payment.NewFake("whsec", clock.NewFake(bookingDay)))
+		db.On("GetPromoCodeByCode", "EARLY").Return(late, nil)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", promoBookingBody(t, "tok_visa", "early")))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "Promo code EARLY has expired.\n", rr.Body.String())
+		db.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything)
+	})
+
+	t.Run("used by the passenger", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		handler := paymentServer(db, payment.NewFake("whsec", clock.NewFake(bookingDay)))
+		db.On("GetPromoCodeByCode", "MARS10").Return(promo, nil)
+		db.On("CreateBooking", mock.AnythingOfType("*models.Booking"), 50).
+			Return(&models.PromoError{Code: "MARS10", Reason: "was already used by this passenger"}).Once()
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", promoBookingBody(t, "tok_visa", "MARS10")))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "Promo code MARS10 was already used by this passenger.\n", rr.Body.String())
+		db.AssertNotCalled(t, "CreatePayment", mock.Anything)
+	})
+}
+
+func TestCreatePromoCodeHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	db.On("CreatePromoCode", mock.MatchedBy(func(p *models.PromoCode) bool { return p.Code == "LAUNCH-50" })).
+		Run(func(args mock.Arguments) {
+			args.Get(0).(*models.PromoCode).ID = 4
+		}).Return(nil).Once()
+	db.On("CreatePromoCode", mock.MatchedBy(func(p *models.PromoCode) bool { return p.Code == "MARS10" })).
+		Return(database.ErrPromoCodeTaken).Once()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/promo-codes",
+		[]byte(`{"code":"launch-50","kind":"fixed","value":5000000,"max_redemptions":100}`)))
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var got models.PromoCode
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, 4, got.ID)
+	assert.Equal(t, "USD", got.Currency, "Expected a fixed code in the currency of the fares")
+	assert.True(t, got.Active, "Expected new promo codes to be active")
+	assert.Equal(t, []int64{}, got.DestinationIDs)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/promo-codes", []byte(`{"code":"MARS10","kind":"percent","value":10}`)))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	for _, body := range []string{
+		`{"code":"M!","kind":"percent","value":10}`,
+		`{"code":"MARS10","kind":"percent","value":110}`,
+		`{"code":"MARS10","kind":"fixed","value":500,"currency":"EUR"}`,
+		`{"code":"MARS10","kind":"bogo","value":1}`,
+		`{"code":"MARS10","kind":"percent","value":10,"valid_from":"2049-12-01T00:00:00Z","valid_until":"2049-11-01T00:00:00Z"}`,
+	} {
+		resetVisitors()
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/promo-codes", []byte(body)))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+	db.AssertExpectations(t)
+}
+
+func TestDeletePromoCodeHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	db.On("DeletePromoCode", 4).Return(nil)
+	db.On("DeletePromoCode", 5).Return(database.ErrPromoCodeRedeemed)
+	db.On("DeletePromoCode", 6).Return(database.ErrNotFound)
+
+	for id, want := range map[string]int{"4": http.StatusNoContent, "5": http.StatusConflict, "6": http.StatusNotFound} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, adminRequest(http.MethodDelete, "/admin/promo-codes/"+id, nil))
+		assert.Equal(t, want, rr.Code, id)
+	}
+}
+
+func TestPromoReportHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	from := time.Date(2049, time.November, 1, 0, 0, 0, 0, time.UTC)
+	db.On("GetPromoReport", from, time.Time{}).Return([]models.PromoReport{{
+		PromoCodeID: 4, Code: "MARS10", Kind: models.PromoPercent, Value: 10, Redemptions: 2,
+		Discount: 20000000, Revenue: 180000000, Currency: "USD",
+	}}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/promo-codes/report?from=2049-11-01T00:00:00Z", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var report []models.PromoReport
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	require.Len(t, report, 1)
+	assert.Equal(t, int64(180000000), report[0].Revenue)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/promo-codes/report?to=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
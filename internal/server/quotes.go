@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"space-booking/internal/clock"
+	"space-booking/internal/config"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/pricing"
+	"time"
+)
+
+// errSoldOut rejects a booking on a flight without a free seat.
+var errSoldOut = &validationError{"Flight is sold out."}
+
+// quoteRequest is the body of CreateQuoteHandler: the flight, the
+// birthday of the passenger, which sets their age band, and optionally a
+// promo code.
+type quoteRequest struct {
+	LaunchpadID   string    `json:"launchpad_id"`
+	DestinationID int64     `json:"destination_id"`
+	LaunchDate    time.Time `json:"launch_date"`
+	Birthday      time.Time `json:"birthday"`
+	PromoCode     string    `json:"promo_code"`
+}
+
+// CreateQuoteHandler prices a flight for a passenger, less the discount of
+// the promo code if one is given. The flight is validated like a booking,
+// and the quote can be booked at its price until it expires, as long as
+// its promo code can still be redeemed then.
+func (s *Server) CreateQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	var req quoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	booking := models.Booking{
+		LaunchpadID:   req.LaunchpadID,
+		DestinationID: req.DestinationID,
+		LaunchDate:    req.LaunchDate,
+		Birthday:      req.Birthday,
+		PromoCode:     models.NormalizePromoCode(req.PromoCode),
+	}
+	if err := s.validateBooking(r.Context(), &booking); err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	in, err := s.fareInput(r.Context(), &booking)
+	if err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	price, breakdown, err := s.pricing().Price(in)
+	if err != nil {
+		s.writeBookingError(w, fareError(err, booking.DestinationID))
+		return
+	}
+	booking.Price, booking.Currency = price, s.cfg.Pricing.Currency
+	if err := s.applyPromo(r.Context(), &booking); err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+
+	now := s.clock.Now()
+	quote := models.Quote{
+		LaunchpadID:   booking.LaunchpadID,
+		DestinationID: booking.DestinationID,
+		LaunchDate:    clock.Day(booking.LaunchDate),
+		Birthday:      clock.Day(booking.Birthday),
+		Price:         booking.Price,
+		Currency:      booking.Currency,
+		PromoCode:     booking.PromoCode,
+		Discount:      booking.Discount,
+		Breakdown:     breakdown,
+		IssuedAt:      now,
+		ExpiresAt:     now.Add(s.cfg.Pricing.QuoteTTL),
+	}
+	if err := s.quotes.Sign(&quote); err != nil {
+		s.logger.Printf("Error signing quote: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, quote)
+}
+
+// pricing returns the fare rules of the configuration.
+func (s *Server) pricing() pricing.Rules {
+	tiers := func(ts []config.Tier) []pricing.Tier {
+		out := make([]pricing.Tier, len(ts))
+		for i, t := range ts {
+			out[i] = pricing.Tier{From: t.From, Factor: t.Factor}
+		}
+		return out
+	}
+	p := s.cfg.Pricing
+	return pricing.Rules{
+		Currency:      p.Currency,
+		Seats:         p.Seats,
+		LoadSurcharge: p.LoadSurcharge,
+		Advance:       tiers(p.Advance),
+		AgeBands:      tiers(p.AgeBands),
+	}
+}
+
+// fareInput reads what the fare of a booking depends on: its destination
+// and the seats sold on its flight, held seats included. A booking taking a
+// seat from a hold counts the other seats only, so that its own seat is
+// never sold out under it.
+func (s *Server) fareInput(ctx context.Context, booking *models.Booking) (pricing.Input, error) {
+	in := pricing.Input{LaunchDate: booking.LaunchDate, Birthday: booking.Birthday, Now: s.clock.Now()}
+	destination, err := s.db.GetDestination(ctx, booking.DestinationID)
+	if errors.Is(err, database.ErrNotFound) {
+		return in, &validationError{fmt.Sprintf("Destination %d does not exist.", booking.DestinationID)}
+	}
+	if err != nil {
+		return in, err
+	}
+	in.Destination = *destination
+	day := clock.Day(booking.LaunchDate)
+	sold, err := s.db.GetBookingsOnLaunchpad(ctx, booking.LaunchpadID, day, day)
+	if err != nil {
+		return in, err
+	}
+	held, err := s.db.CountHeldSeats(ctx, booking.LaunchpadID, day)
+	if err != nil {
+		return in, err
+	}
+	in.SeatsSold = len(sold) + held
+	if booking.HoldToken != "" {
+		in.SeatsSold = min(in.SeatsSold-1, s.cfg.Pricing.Seats-1)
+	}
+	return in, nil
+}
+
+// priceBooking sets the price of a validated booking: that of the quote
+// when a quote ID is given, the current fare otherwise. A quote holds the
+// price, not a seat, and brings its promo code and discount along; see
+// applyPromo for a booking with a promo code but no quote. Any discount the
+// client sent is dropped.
+func (s *Server) priceBooking(booking *models.Booking, quoteID string, in pricing.Input) error {
+	if quoteID == "" {
+		price, _, err := s.pricing().Price(in)
+		if err != nil {
+			return fareError(err, booking.DestinationID)
+		}
+		booking.Price, booking.Currency, booking.Discount = price, s.cfg.Pricing.Currency, 0
+		return nil
+	}
+
+	if in.SeatsSold >= s.cfg.Pricing.Seats {
+		return errSoldOut
+	}
+	terms, err := s.quotes.Verify(quoteID, s.clock.Now())
+	switch {
+	case errors.Is(err, pricing.ErrQuoteExpired):
+		return &validationError{"Quote has expired, please request a new one."}
+	case err != nil:
+		return &validationError{"Quote is not valid."}
+	case !terms.Covers(booking):
+		return &validationError{"Quote is for another flight or passenger."}
+	case booking.PromoCode != "" && booking.PromoCode != terms.PromoCode:
+		return &validationError{"Quote was made for another promo code."}
+	}
+	booking.Price, booking.Currency = terms.Price, terms.Currency
+	booking.PromoCode, booking.Discount = terms.PromoCode, terms.Discount
+	return nil
+}
+
+// applyPromo takes the discount of the booking's promo code, if any, off
+// its price. It returns a *models.PromoError when the code cannot be
+// redeemed for the booking; the one-per-passenger rule and the cap are
+// checked again when the code is redeemed with the booking.
+func (s *Server) applyPromo(ctx context.Context, booking *models.Booking) error {
+	if booking.PromoCode == "" {
+		return nil
+	}
+	promo, err := s.db.GetPromoCodeByCode(ctx, booking.PromoCode)
+	if errors.Is(err, database.ErrNotFound) {
+		return &models.PromoError{Code: booking.PromoCode, Reason: "does not exist"}
+	}
+	if err != nil {
+		return err
+	}
+	if err := promo.Check(booking.DestinationID, s.clock.Now()); err != nil {
+		return err
+	}
+	booking.Discount = promo.Discount(booking.Price, booking.Currency)
+	booking.Price -= booking.Discount
+	return nil
+}
+
+// fareError turns the errors of the fare rules into validation errors.
+func fareError(err error, destinationID int64) error {
+	switch {
+	case errors.Is(err, pricing.ErrSoldOut):
+		return errSoldOut
+	case errors.Is(err, pricing.ErrNotForSale):
+		return &validationError{fmt.Sprintf("Destination %d is not for sale.", destinationID)}
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/models"
+	"space-booking/internal/pricing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mars is for sale at a million dollars.
+var mars = &models.Destination{ID: 1, Name: "Mars", BasePrice: 100000000}
+
+// expectFare serves what the fare of a booking to mars depends on.
+func expectFare(db *MockDatabase, launchpadID string, launchDate time.Time, sold int) {
+	day := clock.Day(launchDate)
+	db.On("GetDestination", int64(1)).Return(mars, nil)
+	db.On("GetBookingsOnLaunchpad", launchpadID, day, day).Return(make([]models.Booking, sold), nil)
+	db.On("CountHeldSeats", launchpadID, day).Return(0, nil)
+}
+
+func TestCreateQuoteHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	conflicts := new(MockConflictProvider)
+	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).Return([]conflict.Conflict(nil), nil)
+	db.On("CheckDestinationSchedule", int64(1), "test_launchpad", launchDate).Return(true, nil)
+	expectFare(db, "test_launchpad", launchDate, 25)
+
+	signer, err := pricing.GenerateSigner()
+	require.NoError(t, err)
+	handler := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts),
+		WithQuoteSigner(signer)).RegisterRoutes()
+	body := `{"launchpad_id": "test_launchpad", "destination_id": 1, "launch_date": "2049-12-25T00:00:00Z", "birthday": "2040-06-01T00:00:00Z"}`
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/quotes", bytes.NewBufferString(body)))
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var quote models.Quote
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &quote))
+	// 24 days ahead, half full and a child: 1 × 1.25 × 0.75
+	assert.Equal(t, int64(93750000), quote.Price)
+	assert.Equal(t, "USD", quote.Currency)
+	assert.Equal(t, 9, quote.Breakdown.Age)
+	assert.Equal(t, 1.25, quote.Breakdown.LoadFactor)
+	assert.Equal(t, bookingDay.Add(15*time.Minute), quote.ExpiresAt)
+
+	terms, err := signer.Verify(quote.ID, bookingDay)
+	require.NoError(t, err)
+	assert.Equal(t, quote.Price, terms.Price)
+	db.AssertExpectations(t)
+}
+
+func TestCreateBookingHandlerQuote(t *testing.T) {
+	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	signer, err := pricing.GenerateSigner()
+	require.NoError(t, err)
+	quoted := func(mod func(q *models.Quote)) string {
+		q := &models.Quote{
+			LaunchpadID: "test_launchpad", DestinationID: 1, LaunchDate: launchDate,
+			Birthday: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Price:    90000000, Currency: "USD", ExpiresAt: bookingDay.Add(time.Minute),
+		}
+		if mod != nil {
+			mod(q)
+		}
+		require.NoError(t, signer.Sign(q))
+		return q.ID
+	}
+
+	tests := []struct {
+		name    string
+		quoteID string
+		sold    int
+		want    int
+		msg     string
+	}{
+		{"booked at the quoted price", quoted(nil), 0, http.StatusCreated, ""},
+		{"expired", quoted(func(q *models.Quote) { q.ExpiresAt = bookingDay }), 0, http.StatusBadRequest,
+			"Quote has expired, please request a new one.\n"},
+		{"another passenger", quoted(func(q *models.Quote) { q.Birthday = q.Birthday.AddDate(0, 0, 1) }), 0, http.StatusBadRequest,
+			"Quote is for another flight or passenger.\n"},
+		{"forged", quoted(nil) + "x", 0, http.StatusBadRequest, "Quote is not valid.\n"},
+		{"sold out", quoted(nil), 50, http.StatusBadRequest, "Flight is sold out.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			conflicts := new(MockConflictProvider)
+			conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).Return([]conflict.Conflict(nil), nil)
+			db.On("CheckDestinationSchedule", int64(1), "test_launchpad", launchDate).Return(true, nil)
+			expectFare(db, "test_launchpad", launchDate, tt.sold)
+			if tt.want == http.StatusCreated {
+				expectPaidBooking(t, db, mock.MatchedBy(func(b *models.Booking) bool {
+					return b.Price == 90000000 && b.Currency == "USD"
+				}))
+			}
+
+			handler := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts),
+				WithQuoteSigner(signer)).RegisterRoutes()
+			body, err := json.Marshal(map[string]any{
+				"first_name": "Test", "last_name": "User", "email": "test@example.com",
+				"birthday": "1990-01-01T00:00:00Z", "launchpad_id": "test_launchpad", "destination_id": 1,
+				"launch_date": "2049-12-25T00:00:00Z", "quote_id": tt.quoteID,
+			})
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(body)))
+
+			assert.Equal(t, tt.want, rr.Code)
+			if tt.msg != "" {
+				assert.Equal(t, tt.msg, rr.Body.String())
+			}
+			db.AssertExpectations(t)
+		})
+	}
+}
@@ -1,12 +1,25 @@
 package server
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"net/mail"
+	"space-booking/internal/actor"
+	"space-booking/internal/bulk"
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/database"
 	"space-booking/internal/models"
+	"space-booking/internal/spacex"
+	"space-booking/internal/suggest"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -16,17 +29,133 @@ import (
 // RegisterRoutes sets up the router with all endpoints.
 func (s *Server) RegisterRoutes() http.Handler {
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(rateLimitMiddleware) // Apply rate limiting middleware
+	r.Use(s.identify)
 	r.Get("/health", s.healthHandler)
 
 	// Endpoints for bookings
 	r.Post("/bookings", s.CreateBookingHandler)
+	r.With(s.requireAdmin).Post("/bookings/import", s.ImportBookingsHandler)
 	r.Get("/bookings", s.GetAllBookingsHandler)
+	r.With(s.requireAdmin).Get("/bookings/export", s.ExportBookingsHandler)
+	r.Get("/bookings/{id}", s.GetBookingHandler)
+	r.Delete("/bookings/{id}", s.CancelBookingHandler)
+	r.Get("/bookings/{id}/cancellation-quote", s.CancellationQuoteHandler)
+	r.Post("/bookings/{id}/rebook", s.RebookBookingHandler)
+	r.Get("/bookings/{id}/history", s.GetBookingHistoryHandler)
+	r.Get("/bookings/{id}/ticket", s.GetTicketHandler)
+	r.Post("/bookings/{id}/checkin", s.CheckInHandler)
+	r.Get("/tickets/public-key", s.TicketPublicKeyHandler)
+	r.Get("/suggestions", s.SuggestionsHandler)
+	r.Post("/quotes", s.CreateQuoteHandler)
+	r.Post("/holds", s.CreateHoldHandler)
+	r.Get("/holds/{token}", s.GetHoldHandler)
+	r.Delete("/holds/{token}", s.ReleaseHoldHandler)
+	r.Post("/waitlist", s.JoinWaitlistHandler)
+	r.Get("/waitlist/{id}", s.GetWaitlistEntryHandler)
+	r.Delete("/waitlist/{id}", s.LeaveWaitlistHandler)
+	r.Post("/waitlist/{id}/confirm", s.ConfirmWaitlistHandler)
+	r.Post("/payments/webhook", s.PaymentWebhookHandler)
+	r.Get("/events", s.EventsHandler)
+
+	r.With(s.requireAdmin).Get("/manifests", s.GetManifestHandler)
+
+	// Endpoints for operators
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(s.requireAdmin)
+
+		r.Get("/blackouts", s.ListBlackoutsHandler)
+		r.Post("/blackouts", s.CreateBlackoutHandler)
+		r.Get("/blackouts/{id}", s.GetBlackoutHandler)
+		r.Put("/blackouts/{id}", s.UpdateBlackoutHandler)
+		r.Delete("/blackouts/{id}", s.DeleteBlackoutHandler)
+
+		r.Put("/bookings/{id}/status", s.UpdateBookingStatusHandler)
+		r.Get("/bookings/{id}/payments", s.ListPaymentsHandler)
+
+		r.Post("/boarding", s.BoardingHandler)
+		r.Get("/boarding", s.ListBoardedHandler)
+
+		r.Get("/webhooks", s.ListWebhooksHandler)
+		r.Post("/webhooks", s.CreateWebhookHandler)
+		r.Get("/webhooks/{id}", s.GetWebhookHandler)
+		r.Put("/webhooks/{id}", s.UpdateWebhookHandler)
+		r.Delete("/webhooks/{id}", s.DeleteWebhookHandler)
+		r.Get("/webhooks/{id}/deliveries", s.ListWebhookDeliveriesHandler)
+
+		r.Get("/promo-codes", s.ListPromoCodesHandler)
+		r.Post("/promo-codes", s.CreatePromoCodeHandler)
+		r.Get("/promo-codes/report", s.PromoReportHandler)
+		r.Get("/promo-codes/{id}", s.GetPromoCodeHandler)
+		r.Put("/promo-codes/{id}", s.UpdatePromoCodeHandler)
+		r.Delete("/promo-codes/{id}", s.DeletePromoCodeHandler)
+		r.Get("/promo-codes/{id}/redemptions", s.ListPromoRedemptionsHandler)
+
+		r.Get("/waitlist", s.ListWaitlistHandler)
+	})
 
 	return r
 }
 
+// clientIDHeader is the header callers name themselves with for the audit
+// log.
+const clientIDHeader = "X-Client-ID"
+
+// identify attaches the actor of the request to its context: the operator
+// when the admin token is presented, the client named in the X-Client-ID
+// header otherwise, or an anonymous caller.
+func (s *Server) identify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := actor.Actor{ID: actor.Anonymous, RequestID: middleware.GetReqID(r.Context())}
+		if s.isAdmin(r) {
+			a.ID = "admin"
+		} else if client := strings.TrimSpace(r.Header.Get(clientIDHeader)); client != "" {
+			a.ID = "client:" + client
+		}
+		next.ServeHTTP(w, r.WithContext(actor.With(r.Context(), a)))
+	})
+}
+
+// isAdmin reports whether the request bears the admin token.
+func (s *Server) isAdmin(r *http.Request) bool {
+	if s.cfg.AdminToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AdminToken)) == 1
+}
+
+// requireAdmin lets only requests bearing the admin token through.
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.AdminToken == "" {
+			http.Error(w, "Admin API is disabled", http.StatusForbidden)
+			return
+		}
+		if !s.isAdmin(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON writes v as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// idParam parses the {id} URL parameter.
+func idParam(r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	return id, err == nil && id > 0
+}
+
 // healthHandler provides health information.
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	jsonResp, _ := json.Marshal(s.db.Health())
@@ -34,83 +163,344 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonResp)
 }
 
-// CreateBookingHandler handles booking creation.
+// createBookingRequest is the body of CreateBookingHandler: the booking,
+// optionally the quote it is booked at, and how it is paid.
+type createBookingRequest struct {
+	models.Booking
+	QuoteID string `json:"quote_id"`
+	// HoldToken names the seat hold the booking takes its seat from.
+	HoldToken string `json:"hold_token"`
+	// PaymentMethod is the payment provider's token, e.g. of a card.
+	PaymentMethod string `json:"payment_method"`
+}
+
+// CreateBookingHandler handles booking creation. The booking is priced at
+// its quote, or at the current fare less the discount of its promo code
+// without one, and holds its seat as pending until the fare is paid, see
+// pay. A booking left with nothing to pay is confirmed at once. A booking
+// with a hold_token takes its seat from that seat hold.
 func (s *Server) CreateBookingHandler(w http.ResponseWriter, r *http.Request) {
-	var booking models.Booking
-	if err := json.NewDecoder(r.Body).Decode(&booking); err != nil {
-		log.Printf("Invalid booking data: %v", err)
+	var req createBookingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Printf("Invalid booking data: %v", err)
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	booking := req.Booking
+	// Only the waitlist holds a pending booking for longer, a seat hold is
+	// only taken with its token, and only a rebooking replaces a booking.
+	booking.HoldUntil = nil
+	booking.HoldID, booking.HoldToken = nil, req.HoldToken
+	booking.RebookedFrom = nil
 
 	// Validate booking
-	valid, err := s.validateBooking(&booking)
-	if err != nil {
-		log.Printf("Error validating booking: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	if err := validateEmail(&booking); err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	if err := s.validateBooking(r.Context(), &booking); err != nil {
+		s.writeBookingError(w, err)
 		return
 	}
-	if !valid {
-		http.Error(w, "Flight is cancelled due to scheduling conflicts.", http.StatusBadRequest)
+	booking.PromoCode = models.NormalizePromoCode(booking.PromoCode)
+	in, err := s.fareInput(r.Context(), &booking)
+	if err == nil {
+		err = s.priceBooking(&booking, req.QuoteID, in)
+	}
+	if err == nil && req.QuoteID == "" {
+		err = s.applyPromo(r.Context(), &booking)
+	}
+	if err != nil {
+		s.writeBookingError(w, err)
 		return
 	}
 
-	// Create booking in the database
-	err = s.db.CreateBooking(&booking)
+	// Create booking in the database, confirmed at once when the promo
+	// code leaves nothing to pay
+	booking.Status = models.StatusPending
+	if booking.Price == 0 {
+		booking.Status = models.StatusConfirmed
+	}
+	err = s.db.CreateBooking(r.Context(), &booking, s.cfg.Pricing.Seats)
+	var perr *models.PromoError
+	var herr *models.HoldError
+	if errors.As(err, &perr) || errors.As(err, &herr) || errors.Is(err, database.ErrNoSeats) {
+		s.writeBookingError(w, err)
+		return
+	}
 	if err != nil {
-		log.Printf("Error creating booking: %v", err)
+		s.logger.Printf("Error creating booking: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	// Return the created booking
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(booking)
+	if booking.Status == models.StatusConfirmed {
+		writeJSON(w, http.StatusCreated, bookingPayment{Booking: booking})
+		return
+	}
+	s.pay(w, r, &booking, req.PaymentMethod)
 }
 
-// GetAllBookingsHandler retrieves all bookings.
+// GetAllBookingsHandler retrieves the bookings matching the filters in the
+// query, see bookingFilter. It answers JSON unless the Accept header asks
+// for CSV or NDJSON, which are streamed like ExportBookingsHandler and
+// require the admin token as it does. Callers other than operators only get
+// the summaries, without passengers or codes.
 func (s *Server) GetAllBookingsHandler(w http.ResponseWriter, r *http.Request) {
-	bookings, err := s.db.GetAllBookings()
+	filter, err := bookingFilter(r)
 	if err != nil {
-		log.Printf("Error retrieving bookings: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	media := negotiate(r.Header.Get("Accept"), mediaJSON, mediaCSV, mediaNDJSON)
+	if media != mediaJSON && media != "" && !s.isAdmin(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch media {
+	case mediaCSV:
+		s.streamBookings(w, r, filter, bulk.CSV, "")
+		return
+	case mediaNDJSON:
+		s.streamBookings(w, r, filter, bulk.NDJSON, "")
+		return
+	}
+
+	bookings, err := s.db.GetBookings(r.Context(), filter)
+	if err != nil {
+		s.logger.Printf("Error retrieving bookings: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	// Return bookings as JSON
 	w.Header().Set("Content-Type", "application/json")
+	if !s.isAdmin(r) {
+		summaries := make([]models.BookingSummary, len(bookings))
+		for i := range bookings {
+			summaries[i] = bookings[i].Summary()
+		}
+		json.NewEncoder(w).Encode(summaries)
+		return
+	}
 	json.NewEncoder(w).Encode(bookings)
 }
 
-// validateBooking checks if the booking is valid.
-func (s *Server) validateBooking(booking *models.Booking) (bool, error) {
+// GetBookingHandler retrieves a single booking by its code, see
+// lookupBooking.
+func (s *Server) GetBookingHandler(w http.ResponseWriter, r *http.Request) {
+	booking, ok := s.lookupBooking(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, booking)
+}
+
+// GetBookingHistoryHandler returns the audit log of a booking.
+func (s *Server) GetBookingHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	booking, ok := s.lookupBooking(w, r)
+	if !ok {
+		return
+	}
+	events, err := s.db.GetBookingEvents(r.Context(), booking.ID)
+	if err != nil {
+		s.logger.Printf("Error retrieving history of booking %d: %v", booking.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []models.BookingEvent{}
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// CancelBookingHandler cancels a booking. The booking is kept with its
+// history rather than deleted. The fare is refunded by the cancellation
+// policy, see cancelBooking; operators cancel on the company's behalf.
+func (s *Server) CancelBookingHandler(w http.ResponseWriter, r *http.Request) {
+	booking, ok := s.lookupBooking(w, r)
+	if !ok {
+		return
+	}
+	s.cancelBooking(w, r, booking.ID, s.isAdmin(r))
+}
+
+// bookingStatusRequest is the body of UpdateBookingStatusHandler.
+type bookingStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// UpdateBookingStatusHandler lets operators move a booking to any status
+// the state machine allows, e.g. to mark it flown.
+func (s *Server) UpdateBookingStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return
+	}
+	var req bookingStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	status, err := models.ParseBookingStatus(req.Status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if status == models.StatusCancelled {
+		s.cancelBooking(w, r, id, true)
+		return
+	}
+	s.changeBookingStatus(w, r, id, status, req.Reason)
+}
+
+// changeBookingStatus moves the booking to status and writes the updated
+// booking, or 409 Conflict when the booking cannot move there.
+func (s *Server) changeBookingStatus(w http.ResponseWriter, r *http.Request, id int, status models.BookingStatus, reason string) {
+	booking, err := s.db.UpdateBookingStatus(r.Context(), id, status, reason)
+	s.writeStatusChange(w, id, status, booking, err)
+}
+
+// writeStatusChange writes the outcome of moving a booking to status.
+func (s *Server) writeStatusChange(w http.ResponseWriter, id int, status models.BookingStatus, booking *models.Booking, err error) {
+	var terr *models.TransitionError
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		http.Error(w, "Booking not found", http.StatusNotFound)
+		return
+	case errors.As(err, &terr):
+		http.Error(w, fmt.Sprintf("Booking is %s and cannot be %s.", terr.From, terr.To), http.StatusConflict)
+		return
+	case err != nil:
+		s.logger.Printf("Error moving booking %d to %s: %v", id, status, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Printf("Booking %d is now %s", id, status)
+	writeJSON(w, http.StatusOK, booking)
+}
+
+// validationError reports a booking that breaks a business rule. Its
+// message is safe to return to the client.
+type validationError struct {
+	msg string
+}
+
+func (e *validationError) Error() string {
+	return e.msg
+}
+
+// errSchedulingConflict rejects a booking whose launchpad or destination
+// is not available on the launch date.
+var errSchedulingConflict = &validationError{"Flight is cancelled due to scheduling conflicts."}
+
+// writeBookingError answers a request whose flight could not be validated
+// or searched: 400 for a broken rule, 503 while the launch schedule is
+// unreachable and 500 otherwise.
+func (s *Server) writeBookingError(w http.ResponseWriter, err error) {
+	var verr *validationError
+	var uerr *suggest.UnknownDestinationError
+	var perr *models.PromoError
+	var herr *models.HoldError
+	switch {
+	case errors.As(err, &verr):
+		http.Error(w, verr.Error(), http.StatusBadRequest)
+	case errors.As(err, &perr):
+		http.Error(w, perr.Error(), http.StatusBadRequest)
+	case errors.As(err, &herr):
+		http.Error(w, herr.Error(), http.StatusConflict)
+	case errors.Is(err, database.ErrNoSeats):
+		http.Error(w, "Not enough free seats on this flight.", http.StatusConflict)
+	case errors.As(err, &uerr):
+		http.Error(w, fmt.Sprintf("Destination %d does not exist.", uerr.ID), http.StatusBadRequest)
+	case errors.Is(err, spacex.ErrUnavailable):
+		s.logger.Printf("Launch schedule unavailable: %v", err)
+		http.Error(w, "Launch schedule is temporarily unavailable, please retry later.", http.StatusServiceUnavailable)
+	default:
+		s.logger.Printf("Error validating booking: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// validateEmail requires the contact address of a new booking and keeps
+// only the address when a display name came with it.
+func validateEmail(booking *models.Booking) error {
+	if strings.TrimSpace(booking.Email) == "" {
+		return &validationError{"Email must be provided."}
+	}
+	addr, err := mail.ParseAddress(booking.Email)
+	if err != nil {
+		return &validationError{fmt.Sprintf("Email %q is not a valid address.", booking.Email)}
+	}
+	booking.Email = addr.Address
+	return nil
+}
+
+// validateBooking checks if the booking is valid. It returns a
+// *validationError when the booking breaks a rule and any other error when
+// the rules could not be evaluated.
+func (s *Server) validateBooking(ctx context.Context, booking *models.Booking) error {
+	return s.checkBooking(ctx, booking, s.conflicts)
+}
+
+// checkBooking is validateBooking with the launchpad conflicts looked up
+// in conflicts.
+func (s *Server) checkBooking(ctx context.Context, booking *models.Booking, conflicts conflict.Checker) error {
 	launchDate := booking.LaunchDate
 	birthday := booking.Birthday
 
 	// For example, check if the dates are zero values
 	if launchDate.IsZero() || birthday.IsZero() {
-		return false, fmt.Errorf("launch date and birthday must be provided")
+		return &validationError{"Launch date and birthday must be provided."}
+	}
+	return s.checkFlight(ctx, booking.LaunchpadID, booking.DestinationID, launchDate, conflicts)
+}
+
+// checkFlight checks that the destination is flown from the launchpad on
+// the launch date, within the booking window and without conflicts.
+func (s *Server) checkFlight(ctx context.Context, launchpadID string, destinationID int64, launchDate time.Time, conflicts conflict.Checker) error {
+	if err := s.checkLaunchWindow(launchDate); err != nil {
+		return err
 	}
 
 	// Call validation functions
-	isAvailable, err := s.db.CheckLaunchpadAvailability(booking.LaunchpadID, launchDate)
+	c, err := conflicts.Check(ctx, launchpadID, launchDate)
 	if err != nil {
-		return false, err
+		return err
 	}
-	if !isAvailable {
-		return false, nil
+	if c != nil {
+		return &validationError{fmt.Sprintf(
+			"Flight is cancelled due to scheduling conflicts: %s (reported by %s).", c.Reason, c.Provider)}
 	}
 
-	isDestinationValid, err := s.db.CheckDestinationSchedule(booking.DestinationID, booking.LaunchpadID, launchDate)
+	isDestinationValid, err := s.db.CheckDestinationSchedule(ctx, destinationID, launchpadID, launchDate)
 	if err != nil {
-		return false, err
+		return err
 	}
 	if !isDestinationValid {
-		return false, nil
+		return errSchedulingConflict
 	}
 
-	return true, nil
+	return nil
+}
+
+// checkLaunchWindow rejects launch dates in the past or beyond the booking
+// horizon, both measured in calendar days from today.
+func (s *Server) checkLaunchWindow(launchDate time.Time) error {
+	today := clock.Day(s.clock.Now())
+	launchDay := clock.Day(launchDate)
+
+	if launchDay.Before(today) {
+		return &validationError{"Launch date is in the past."}
+	}
+	horizon := s.cfg.Booking.HorizonDays
+	if launchDay.After(today.AddDate(0, 0, horizon)) {
+		return &validationError{fmt.Sprintf("Launch date is more than %d days ahead.", horizon)}
+	}
+	return nil
 }
 
 var (
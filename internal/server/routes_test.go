@@ -2,10 +2,18 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"space-booking/internal/actor"
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/database"
 	"space-booking/internal/models"
+	"space-booking/internal/payment"
+	"space-booking/internal/spacex"
 	"testing"
 	"time"
 
@@ -25,38 +33,406 @@ func (m *MockDatabase) Health() map[string]string {
 }
 
 func (m *MockDatabase) Close() error {
-	return nil
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockDatabase) CreateBooking(ctx context.Context, booking *models.Booking, capacity int) error {
+	args := m.Called(booking, capacity)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) CreateBookings(ctx context.Context, bookings []*models.Booking, capacity int) error {
+	args := m.Called(bookings, capacity)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) GetBookings(ctx context.Context, filter models.BookingFilter) ([]models.Booking, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]models.Booking), args.Error(1)
+}
+
+func (m *MockDatabase) ExportBookings(ctx context.Context, filter models.BookingFilter, fn func(*models.Booking) error) error {
+	args := m.Called(filter)
+	for _, b := range args.Get(0).([]models.Booking) {
+		if err := fn(&b); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockDatabase) GetBooking(ctx context.Context, id int) (*models.Booking, error) {
+	args := m.Called(id)
+	booking, _ := args.Get(0).(*models.Booking)
+	return booking, args.Error(1)
+}
+
+func (m *MockDatabase) GetBookingByCode(ctx context.Context, code string) (*models.Booking, error) {
+	args := m.Called(code)
+	booking, _ := args.Get(0).(*models.Booking)
+	return booking, args.Error(1)
+}
+
+func (m *MockDatabase) UpdateBookingStatus(ctx context.Context, id int, status models.BookingStatus, reason string) (*models.Booking, error) {
+	args := m.Called(id, status, reason)
+	booking, _ := args.Get(0).(*models.Booking)
+	return booking, args.Error(1)
+}
+
+func (m *MockDatabase) CancelBooking(ctx context.Context, id int, terms func(*models.Booking) models.RefundTerms) (*models.Booking, error) {
+	args := m.Called(id, terms)
+	booking, _ := args.Get(0).(*models.Booking)
+	return booking, args.Error(1)
+}
+
+func (m *MockDatabase) DisruptBooking(ctx context.Context, id int, reason, source string) (*models.Booking, error) {
+	args := m.Called(id, reason, source)
+	booking, _ := args.Get(0).(*models.Booking)
+	return booking, args.Error(1)
+}
+
+func (m *MockDatabase) ExpirePendingBookings(ctx context.Context, before time.Time) (int, error) {
+	args := m.Called(before)
+	return args.Int(0), args.Error(1)
 }
 
-func (m *MockDatabase) CreateBooking(booking *models.Booking) error {
-	args := m.Called(booking)
+func (m *MockDatabase) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	args := m.Called(payment)
 	return args.Error(0)
 }
 
-func (m *MockDatabase) GetAllBookings() ([]models.Booking, error) {
+func (m *MockDatabase) GetPayments(ctx context.Context, bookingID int) ([]models.Payment, error) {
+	args := m.Called(bookingID)
+	return args.Get(0).([]models.Payment), args.Error(1)
+}
+
+func (m *MockDatabase) StartCapture(ctx context.Context, id int64, providerRef string, retryAt time.Time) (*models.Payment, error) {
+	args := m.Called(id, providerRef, retryAt)
+	payment, _ := args.Get(0).(*models.Payment)
+	return payment, args.Error(1)
+}
+
+func (m *MockDatabase) ConfirmPayment(ctx context.Context, id int64) (*models.Booking, error) {
+	args := m.Called(id)
+	booking, _ := args.Get(0).(*models.Booking)
+	return booking, args.Error(1)
+}
+
+func (m *MockDatabase) ClaimCaptures(ctx context.Context, limit int, lease time.Duration) ([]models.Payment, error) {
+	args := m.Called(limit, lease)
+	return args.Get(0).([]models.Payment), args.Error(1)
+}
+
+func (m *MockDatabase) RecordCaptureAttempt(ctx context.Context, payment *models.Payment) error {
+	args := m.Called(payment)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) FailPayment(ctx context.Context, id int64, providerRef, reason string) (*models.Booking, error) {
+	args := m.Called(id, providerRef, reason)
+	booking, _ := args.Get(0).(*models.Booking)
+	return booking, args.Error(1)
+}
+
+func (m *MockDatabase) ClaimRefunds(ctx context.Context, limit int, lease time.Duration) ([]models.DueRefund, error) {
+	args := m.Called(limit, lease)
+	return args.Get(0).([]models.DueRefund), args.Error(1)
+}
+
+func (m *MockDatabase) RecordRefundAttempt(ctx context.Context, refund *models.Refund) error {
+	args := m.Called(refund)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) RebookBooking(ctx context.Context, id int, replacement *models.Booking, capacity int) error {
+	args := m.Called(id, replacement, capacity)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) GetBookingEvents(ctx context.Context, bookingID int) ([]models.BookingEvent, error) {
+	args := m.Called(bookingID)
+	return args.Get(0).([]models.BookingEvent), args.Error(1)
+}
+
+func (m *MockDatabase) GetDestinationIDs(ctx context.Context) ([]int64, error) {
 	args := m.Called()
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockDatabase) GetDestinationName(ctx context.Context, id int64) (string, error) {
+	args := m.Called(id)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDatabase) GetUpcomingBookings(ctx context.Context, from time.Time) ([]models.Booking, error) {
+	args := m.Called(from)
+	return args.Get(0).([]models.Booking), args.Error(1)
+}
+
+func (m *MockDatabase) GetBookingsOnLaunchpad(ctx context.Context, launchpadID string, from, to time.Time) ([]models.Booking, error) {
+	args := m.Called(launchpadID, from, to)
 	return args.Get(0).([]models.Booking), args.Error(1)
 }
 
-func (m *MockDatabase) CheckLaunchpadAvailability(launchpadID string, launchDate time.Time) (bool, error) {
+func (m *MockDatabase) GetBlackouts(ctx context.Context, launchpadID string, from, to time.Time) ([]models.Blackout, error) {
+	args := m.Called(launchpadID, from, to)
+	return args.Get(0).([]models.Blackout), args.Error(1)
+}
+
+func (m *MockDatabase) ListBlackouts(ctx context.Context, launchpadID string) ([]models.Blackout, error) {
+	args := m.Called(launchpadID)
+	return args.Get(0).([]models.Blackout), args.Error(1)
+}
+
+func (m *MockDatabase) GetBlackout(ctx context.Context, id int) (*models.Blackout, error) {
+	args := m.Called(id)
+	blackout, _ := args.Get(0).(*models.Blackout)
+	return blackout, args.Error(1)
+}
+
+func (m *MockDatabase) CreateBlackout(ctx context.Context, blackout *models.Blackout) error {
+	args := m.Called(blackout)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) UpdateBlackout(ctx context.Context, blackout *models.Blackout) error {
+	args := m.Called(blackout)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) DeleteBlackout(ctx context.Context, id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	args := m.Called()
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockDatabase) GetWebhook(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	args := m.Called(id)
+	webhook, _ := args.Get(0).(*models.WebhookSubscription)
+	return webhook, args.Error(1)
+}
+
+func (m *MockDatabase) CreateWebhook(ctx context.Context, webhook *models.WebhookSubscription) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) UpdateWebhook(ctx context.Context, webhook *models.WebhookSubscription) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) DeleteWebhook(ctx context.Context, id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) ListWebhookDeliveries(ctx context.Context, subscriptionID int, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(subscriptionID, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockDatabase) ListPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	args := m.Called()
+	return args.Get(0).([]models.PromoCode), args.Error(1)
+}
+
+func (m *MockDatabase) GetPromoCode(ctx context.Context, id int) (*models.PromoCode, error) {
+	args := m.Called(id)
+	code, _ := args.Get(0).(*models.PromoCode)
+	return code, args.Error(1)
+}
+
+func (m *MockDatabase) GetPromoCodeByCode(ctx context.Context, code string) (*models.PromoCode, error) {
+	args := m.Called(code)
+	promo, _ := args.Get(0).(*models.PromoCode)
+	return promo, args.Error(1)
+}
+
+func (m *MockDatabase) CreatePromoCode(ctx context.Context, code *models.PromoCode) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) UpdatePromoCode(ctx context.Context, code *models.PromoCode) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) DeletePromoCode(ctx context.Context, id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) ListPromoRedemptions(ctx context.Context, promoCodeID int) ([]models.PromoRedemption, error) {
+	args := m.Called(promoCodeID)
+	return args.Get(0).([]models.PromoRedemption), args.Error(1)
+}
+
+func (m *MockDatabase) GetPromoReport(ctx context.Context, from, to time.Time) ([]models.PromoReport, error) {
+	args := m.Called(from, to)
+	return args.Get(0).([]models.PromoReport), args.Error(1)
+}
+
+func (m *MockDatabase) JoinWaitlist(ctx context.Context, entry *models.WaitlistEntry) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) GetWaitlistEntry(ctx context.Context, id int) (*models.WaitlistEntry, error) {
+	args := m.Called(id)
+	entry, _ := args.Get(0).(*models.WaitlistEntry)
+	return entry, args.Error(1)
+}
+
+func (m *MockDatabase) GetWaitlistEntryByCode(ctx context.Context, code string) (*models.WaitlistEntry, error) {
+	args := m.Called(code)
+	entry, _ := args.Get(0).(*models.WaitlistEntry)
+	return entry, args.Error(1)
+}
+
+func (m *MockDatabase) ListWaitlist(ctx context.Context, launchpadID string, launchDate time.Time) ([]models.WaitlistEntry, error) {
 	args := m.Called(launchpadID, launchDate)
+	return args.Get(0).([]models.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockDatabase) LeaveWaitlist(ctx context.Context, id int) (*models.WaitlistEntry, error) {
+	args := m.Called(id)
+	entry, _ := args.Get(0).(*models.WaitlistEntry)
+	return entry, args.Error(1)
+}
+
+func (m *MockDatabase) GetWaitlistFlights(ctx context.Context, from time.Time) ([]models.WaitlistFlight, error) {
+	args := m.Called(from)
+	return args.Get(0).([]models.WaitlistFlight), args.Error(1)
+}
+
+func (m *MockDatabase) ExpireWaitlist(ctx context.Context, before time.Time) (int, error) {
+	args := m.Called(before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDatabase) PromoteWaitlist(ctx context.Context, flight models.WaitlistFlight, seats int, holdUntil time.Time, price func(booking *models.Booking, sold int) error) ([]models.WaitlistEntry, error) {
+	args := m.Called(flight, seats, holdUntil, price)
+	return args.Get(0).([]models.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockDatabase) CreateHold(ctx context.Context, hold *models.SeatHold, capacity int) error {
+	args := m.Called(hold, capacity)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) GetHold(ctx context.Context, token string) (*models.SeatHold, error) {
+	args := m.Called(token)
+	hold, _ := args.Get(0).(*models.SeatHold)
+	return hold, args.Error(1)
+}
+
+func (m *MockDatabase) ReleaseHold(ctx context.Context, token string) (*models.SeatHold, error) {
+	args := m.Called(token)
+	hold, _ := args.Get(0).(*models.SeatHold)
+	return hold, args.Error(1)
+}
+
+func (m *MockDatabase) CountHeldSeats(ctx context.Context, launchpadID string, launchDate time.Time) (int, error) {
+	args := m.Called(launchpadID, launchDate)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDatabase) ExpireHolds(ctx context.Context) (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDatabase) FanOutOutbox(ctx context.Context, limit int) (int, error) {
+	args := m.Called(limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDatabase) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error) {
+	args := m.Called(limit, lease)
+	return args.Get(0).([]models.DueDelivery), args.Error(1)
+}
+
+func (m *MockDatabase) RecordWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) ClaimNotifications(ctx context.Context, limit int, lease time.Duration) ([]models.DueNotification, error) {
+	args := m.Called(limit, lease)
+	return args.Get(0).([]models.DueNotification), args.Error(1)
+}
+
+func (m *MockDatabase) RecordNotificationAttempt(ctx context.Context, notification *models.Notification) error {
+	args := m.Called(notification)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) GetDestination(ctx context.Context, id int64) (*models.Destination, error) {
+	args := m.Called(id)
+	d, _ := args.Get(0).(*models.Destination)
+	return d, args.Error(1)
+}
+
+func (m *MockDatabase) PublishManifest(ctx context.Context, manifest *models.Manifest) (bool, error) {
+	args := m.Called(manifest)
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockDatabase) CheckDestinationSchedule(destinationID int64, launchpadID string, launchDate time.Time) (bool, error) {
+func (m *MockDatabase) GetOutboxMessages(ctx context.Context, filter models.OutboxFilter) ([]models.OutboxMessage, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]models.OutboxMessage), args.Error(1)
+}
+
+func (m *MockDatabase) LatestOutboxPosition(ctx context.Context) (models.OutboxPosition, error) {
+	args := m.Called()
+	return args.Get(0).(models.OutboxPosition), args.Error(1)
+}
+
+func (m *MockDatabase) ListenOutbox(ctx context.Context, notify func()) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockDatabase) CheckDestinationSchedule(ctx context.Context, destinationID int64, launchpadID string, launchDate time.Time) (bool, error) {
 	args := m.Called(destinationID, launchpadID, launchDate)
 	return args.Bool(0), args.Error(1)
 }
 
+// bookingDay is "today" for the handler tests.
+var bookingDay = time.Date(2049, time.December, 1, 9, 30, 0, 0, time.UTC)
+
+// MockConflictProvider is a mock implementation of the conflict.Provider interface
+type MockConflictProvider struct {
+	mock.Mock
+}
+
+func (m *MockConflictProvider) Name() string {
+	return "mock"
+}
+
+func (m *MockConflictProvider) Conflicts(ctx context.Context, launchpadID string, from, to time.Time) ([]conflict.Conflict, error) {
+	args := m.Called(launchpadID, from, to)
+	return args.Get(0).([]conflict.Conflict), args.Error(1)
+}
+
 func TestCreateBookingHandler(t *testing.T) {
 	// Setup
 	db := new(MockDatabase)
-	s := &Server{db: db}
+	conflicts := new(MockConflictProvider)
+	s := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts))
 
 	// Prepare test data
 	bookingData := models.Booking{
 		FirstName:     "Test",
 		LastName:      "User",
+		Email:         "test@example.com",
 		Gender:        "Non-binary",
 		Birthday:      time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 		LaunchpadID:   "test_launchpad",
@@ -69,9 +445,10 @@ func TestCreateBookingHandler(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Mock database methods
-	db.On("CheckLaunchpadAvailability", bookingData.LaunchpadID, bookingData.LaunchDate).Return(true, nil)
+	conflicts.On("Conflicts", bookingData.LaunchpadID, bookingData.LaunchDate, bookingData.LaunchDate).Return([]conflict.Conflict(nil), nil)
 	db.On("CheckDestinationSchedule", bookingData.DestinationID, bookingData.LaunchpadID, bookingData.LaunchDate).Return(true, nil)
-	db.On("CreateBooking", mock.AnythingOfType("*models.Booking")).Return(nil)
+	expectFare(db, bookingData.LaunchpadID, bookingData.LaunchDate, 0)
+	expectPaidBooking(t, db, mock.AnythingOfType("*models.Booking"))
 
 	// Create a request to pass to our handler
 	req, err := http.NewRequest("POST", "/bookings", bytes.NewBuffer(jsonData))
@@ -94,15 +471,194 @@ func TestCreateBookingHandler(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, bookingData.FirstName, responseBooking.FirstName)
 	assert.Equal(t, bookingData.LastName, responseBooking.LastName)
+	assert.Equal(t, int64(100000000), responseBooking.Price, "Expected the current fare")
+	assert.Equal(t, "USD", responseBooking.Currency)
+	assert.Equal(t, models.StatusConfirmed, responseBooking.Status)
 
 	// Ensure that the mocked methods were called
 	db.AssertExpectations(t)
+	conflicts.AssertExpectations(t)
+}
+
+func TestCreateBookingHandlerRebookedFrom(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := paymentServer(db, payment.NewFake("whsec", clock.NewFake(bookingDay)))
+	expectPaidBooking(t, db, mock.MatchedBy(func(b *models.Booking) bool { return b.RebookedFrom == nil }))
+	body, err := json.Marshal(map[string]any{
+		"first_name": "Test", "last_name": "User", "email": "test@example.com",
+		"birthday": "1990-01-01T00:00:00Z", "launchpad_id": "test_launchpad", "destination_id": 1,
+		"launch_date": "2049-12-25T00:00:00Z", "payment_method": "tok_visa", "rebooked_from": 3,
+	})
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(body)))
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.NotContains(t, rr.Body.String(), "rebooked_from")
+	db.AssertExpectations(t)
+}
+
+func TestCreateBookingHandlerEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		want  string
+	}{
+		{"missing", "", "Email must be provided.\n"},
+		{"malformed", "test-at-example.com", "Email \"test-at-example.com\" is not a valid address.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(MockDatabase)
+			s := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)))
+
+			jsonData, err := json.Marshal(models.Booking{
+				FirstName:     "Test",
+				LastName:      "User",
+				Email:         tt.email,
+				Birthday:      time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+				LaunchpadID:   "test_launchpad",
+				DestinationID: 1,
+				LaunchDate:    time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC),
+			})
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			s.CreateBookingHandler(rr, httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewReader(jsonData)))
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, tt.want, rr.Body.String())
+			db.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateBookingHandlerConflict(t *testing.T) {
+	db := new(MockDatabase)
+	conflicts := new(MockConflictProvider)
+	s := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts))
+
+	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).Return([]conflict.Conflict{
+		{Provider: "range-safety", LaunchpadID: "test_launchpad", Date: launchDate, Reason: "Range closed"},
+	}, nil)
+
+	jsonData, err := json.Marshal(models.Booking{
+		FirstName:     "Test",
+		LastName:      "User",
+		Email:         "test@example.com",
+		Birthday:      time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+		LaunchpadID:   "test_launchpad",
+		DestinationID: 1,
+		LaunchDate:    launchDate,
+	})
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	s.CreateBookingHandler(rr, httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(jsonData)))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Range closed (reported by range-safety)")
+	db.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything)
+}
+
+func TestCreateBookingHandlerLaunchWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		launchDate time.Time
+		message    string
+	}{
+		{"past", bookingDay.AddDate(0, 0, -1), "Launch date is in the past."},
+		{"beyond horizon", bookingDay.AddDate(0, 0, 366), "Launch date is more than 365 days ahead."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(MockDatabase)
+			conflicts := new(MockConflictProvider)
+			s := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts))
+
+			jsonData, err := json.Marshal(models.Booking{
+				FirstName:     "Test",
+				LastName:      "User",
+				Email:         "test@example.com",
+				Birthday:      time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+				LaunchpadID:   "test_launchpad",
+				DestinationID: 1,
+				LaunchDate:    tt.launchDate,
+			})
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			s.CreateBookingHandler(rr, httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(jsonData)))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.message)
+			// The date is rejected before any availability lookup
+			conflicts.AssertNotCalled(t, "Conflicts", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateBookingHandlerLaunchToday(t *testing.T) {
+	db := new(MockDatabase)
+	conflicts := new(MockConflictProvider)
+	s := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts))
+
+	launchDate := time.Date(2049, time.December, 1, 0, 0, 0, 0, time.UTC)
+	conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).Return([]conflict.Conflict(nil), nil)
+	db.On("CheckDestinationSchedule", int64(1), "test_launchpad", launchDate).Return(true, nil)
+	expectFare(db, "test_launchpad", launchDate, 0)
+	expectPaidBooking(t, db, mock.AnythingOfType("*models.Booking"))
+
+	jsonData, err := json.Marshal(models.Booking{
+		FirstName:     "Test",
+		LastName:      "User",
+		Email:         "test@example.com",
+		Birthday:      time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+		LaunchpadID:   "test_launchpad",
+		DestinationID: 1,
+		LaunchDate:    launchDate,
+	})
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	s.CreateBookingHandler(rr, httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(jsonData)))
+
+	assert.Equal(t, http.StatusCreated, rr.Code, "Expected a launch later today to be bookable")
+	db.AssertExpectations(t)
+}
+
+func TestCreateBookingHandlerSpaceXUnavailable(t *testing.T) {
+	db := new(MockDatabase)
+	conflicts := new(MockConflictProvider)
+	s := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts))
+
+	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).
+		Return([]conflict.Conflict(nil), fmt.Errorf("%w: circuit breaker is open", spacex.ErrUnavailable))
+
+	jsonData, err := json.Marshal(models.Booking{
+		FirstName:     "Test",
+		LastName:      "User",
+		Email:         "test@example.com",
+		Birthday:      time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+		LaunchpadID:   "test_launchpad",
+		DestinationID: 1,
+		LaunchDate:    launchDate,
+	})
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	s.CreateBookingHandler(rr, httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(jsonData)))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	db.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything)
 }
 
 func TestGetAllBookingsHandler(t *testing.T) {
 	// Setup
 	db := new(MockDatabase)
-	s := &Server{db: db}
+	s := newServer(WithDatabase(db))
 
 	// Prepare test data
 	bookings := []models.Booking{
@@ -119,7 +675,7 @@ func TestGetAllBookingsHandler(t *testing.T) {
 	}
 
 	// Mock database method
-	db.On("GetAllBookings").Return(bookings, nil)
+	db.On("GetBookings", models.BookingFilter{}).Return(bookings, nil)
 
 	// Create a request to pass to our handler
 	req, err := http.NewRequest("GET", "/bookings", nil)
@@ -135,17 +691,137 @@ func TestGetAllBookingsHandler(t *testing.T) {
 	// Check the status code is what we expect
 	assert.Equal(t, http.StatusOK, rr.Code, "Expected status code 200 OK")
 
-	// Check the response body
-	var responseBookings []models.Booking
+	// Check the response body: anyone sees the flight, nobody the passenger
+	var responseBookings []map[string]any
 	err = json.Unmarshal(rr.Body.Bytes(), &responseBookings)
 	assert.NoError(t, err)
 	assert.Equal(t, len(bookings), len(responseBookings))
-	assert.Equal(t, bookings[0].FirstName, responseBookings[0].FirstName)
+	assert.Equal(t, bookings[0].LaunchpadID, responseBookings[0]["launchpad_id"])
+	assert.NotContains(t, responseBookings[0], "first_name")
+	assert.NotContains(t, responseBookings[0], "code")
 
 	// Ensure that the mocked methods were called
 	db.AssertExpectations(t)
 }
 
+func TestGetAllBookingsHandlerStatusFilter(t *testing.T) {
+	db := new(MockDatabase)
+	s := newServer(WithDatabase(db))
+
+	db.On("GetBookings", models.BookingFilter{
+		Statuses: []models.BookingStatus{models.StatusConfirmed, models.StatusDisrupted},
+	}).Return([]models.Booking{}, nil)
+
+	rr := httptest.NewRecorder()
+	s.GetAllBookingsHandler(rr, httptest.NewRequest(http.MethodGet, "/bookings?status=confirmed,disrupted", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	db.AssertExpectations(t)
+
+	rr = httptest.NewRecorder()
+	s.GetAllBookingsHandler(rr, httptest.NewRequest(http.MethodGet, "/bookings?status=boarding", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `unknown booking status "boarding"`)
+}
+
+func TestCancelBookingHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db)).RegisterRoutes()
+
+	cancelledAt := bookingDay
+	db.On("GetBookingByCode", "K7QX2MWP9D").Return(&models.Booking{ID: 7, Code: "K7QX2MWP9D", Status: models.StatusConfirmed}, nil)
+	db.On("GetBookingByCode", "M4RB8ZT2LC").Return(&models.Booking{ID: 8, Code: "M4RB8ZT2LC", Status: models.StatusFlown}, nil)
+	db.On("GetBookingByCode", "NOPE").Return(nil, database.ErrNotFound)
+	db.On("CancelBooking", 7, mock.Anything).
+		Return(&models.Booking{ID: 7, Status: models.StatusCancelled, CancelledAt: &cancelledAt}, nil)
+	db.On("CancelBooking", 8, mock.Anything).
+		Return(nil, &models.TransitionError{From: models.StatusFlown, To: models.StatusCancelled})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/bookings/K7QX2MWP9D", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var booking models.Booking
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &booking))
+	assert.Equal(t, models.StatusCancelled, booking.Status)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/bookings/M4RB8ZT2LC", nil))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Booking is flown and cannot be cancelled.")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/bookings/NOPE", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestUpdateBookingStatusHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	flownAt := bookingDay
+	db.On("UpdateBookingStatus", 7, models.StatusFlown, "").
+		Return(&models.Booking{ID: 7, Status: models.StatusFlown, FlownAt: &flownAt}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPut, "/admin/bookings/7/status", []byte(`{"status":"flown"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPut, "/admin/bookings/7/status", []byte(`{"status":"lost"}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	db.AssertExpectations(t)
+}
+
+func TestIdentify(t *testing.T) {
+	s := newServer(WithConfig(adminConfig()))
+
+	var got actor.Actor
+	handler := s.identify(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = actor.From(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/bookings/7", nil)
+	req.Header.Set("X-Client-ID", "agency-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "client:agency-42", got.ID)
+
+	req.Header.Set("Authorization", "Bearer s3cret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "admin", got.ID, "Expected the admin token to win over the client header")
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bookings", nil))
+	assert.Equal(t, actor.Anonymous, got.ID)
+}
+
+func TestGetBookingHistoryHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db)).RegisterRoutes()
+
+	db.On("GetBookingByCode", "K7QX2MWP9D").Return(&models.Booking{ID: 7, Code: "K7QX2MWP9D", Status: models.StatusCancelled}, nil)
+	db.On("GetBookingEvents", 7).Return([]models.BookingEvent{
+		{ID: 1, BookingID: 7, Type: models.EventCreated, Actor: "client:agency-42", After: json.RawMessage(`{"status":"confirmed"}`)},
+		{ID: 2, BookingID: 7, Type: models.EventCancelled, Actor: "admin", Before: json.RawMessage(`{"status":"confirmed"}`), After: json.RawMessage(`{"status":"cancelled"}`)},
+	}, nil)
+	db.On("GetBookingByCode", "NOPE").Return(nil, database.ErrNotFound)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/K7QX2MWP9D/history", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var events []models.BookingEvent
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &events))
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventCancelled, events[1].Type)
+	assert.Equal(t, "admin", events[1].Actor)
+	assert.JSONEq(t, `{"status":"cancelled"}`, string(events[1].After))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/NOPE/history", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
 // Reset the visitors map before each test to avoid interference between tests.
 func resetVisitors() {
 	mu.Lock()
@@ -1,39 +1,306 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"strconv"
+	"sync"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
-
+	"space-booking/internal/clock"
+	"space-booking/internal/config"
+	"space-booking/internal/conflict"
 	"space-booking/internal/database"
+	"space-booking/internal/events"
+	"space-booking/internal/notify"
+	"space-booking/internal/payment"
+	"space-booking/internal/pricing"
+	"space-booking/internal/spacex"
+	"space-booking/internal/suggest"
+	"space-booking/internal/ticket"
 )
 
 type Server struct {
-	port int
+	cfg       *config.Config
+	db        database.Service
+	logger    *log.Logger
+	clock     clock.Clock
+	conflicts *conflict.Aggregator
+
+	// spaceX backs the SpaceX conflict provider when NewServer builds the
+	// default providers, and lists the launchpads by default.
+	spaceX *spacex.Client
+	// launchpads are searched for alternative flights; none are without.
+	launchpads suggest.Launchpads
+	// hub wakes the event streams up.
+	hub *events.Hub
+	// mailer sends the notifications; NewServer builds it from the
+	// configuration.
+	mailer notify.Mailer
+	// tickets signs the QR codes on the tickets.
+	tickets *ticket.Signer
+	// quotes signs the fare quotes.
+	quotes *pricing.Signer
+	// payments takes the fares and gives them back.
+	payments payment.Provider
+}
+
+// Option configures a Server built by NewServer.
+type Option func(*Server)
+
+// WithConfig sets the configuration. It is required unless a database
+// service is given with WithDatabase.
+func WithConfig(cfg *config.Config) Option {
+	return func(s *Server) { s.cfg = cfg }
+}
+
+// WithDatabase sets the database service instead of opening one from the
+// configuration.
+func WithDatabase(db database.Service) Option {
+	return func(s *Server) { s.db = db }
+}
+
+// WithLogger sets the logger used by the handlers. It defaults to the
+// standard logger.
+func WithLogger(logger *log.Logger) Option {
+	return func(s *Server) { s.logger = logger }
+}
+
+// WithClock sets the clock that date-dependent rules are evaluated
+// against. It defaults to the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(s *Server) { s.clock = c }
+}
+
+// WithSpaceXClient sets the SpaceX API client behind the default SpaceX
+// conflict provider instead of building one from the configuration.
+func WithSpaceXClient(client *spacex.Client) Option {
+	return func(s *Server) { s.spaceX = client }
+}
+
+// WithLaunchpads sets where alternative launchpads are listed from instead
+// of the SpaceX API.
+func WithLaunchpads(launchpads suggest.Launchpads) Option {
+	return func(s *Server) { s.launchpads = launchpads }
+}
 
-	db database.Service
+// WithMailer sets the mailer sending the notifications instead of the one
+// configured.
+func WithMailer(mailer notify.Mailer) Option {
+	return func(s *Server) { s.mailer = mailer }
 }
 
-func NewServer() *http.Server {
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
-	NewServer := &Server{
-		port: port,
+// WithTicketSigner sets the key signing the tickets instead of the one
+// configured.
+func WithTicketSigner(signer *ticket.Signer) Option {
+	return func(s *Server) { s.tickets = signer }
+}
+
+// WithQuoteSigner sets the key signing the quotes instead of the one
+// configured.
+func WithQuoteSigner(signer *pricing.Signer) Option {
+	return func(s *Server) { s.quotes = signer }
+}
+
+// WithPaymentProvider sets the payment provider instead of the one
+// configured.
+func WithPaymentProvider(provider payment.Provider) Option {
+	return func(s *Server) { s.payments = provider }
+}
+
+// WithConflictProviders replaces the default launchpad conflict providers.
+func WithConflictProviders(providers ...conflict.Provider) Option {
+	return func(s *Server) { s.conflicts = conflict.NewAggregator(providers...) }
+}
+
+// setDefaults fills in whatever the options left unset.
+func (s *Server) setDefaults() {
+	if s.cfg == nil {
+		s.cfg = config.Default()
+	}
+	if s.logger == nil {
+		s.logger = log.Default()
+	}
+	if s.clock == nil {
+		s.clock = clock.Real()
+	}
+	if s.hub == nil {
+		s.hub = events.NewHub()
+	}
+}
+
+// NewServer builds the HTTP server from the given options and starts the
+// background jobs. The returned closer stops the jobs and releases the
+// database service; it must be called after the HTTP server has been shut
+// down.
+func NewServer(opts ...Option) (*http.Server, func() error, error) {
+	s := &Server{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.db == nil && s.cfg == nil {
+		return nil, nil, errors.New("server: a configuration or a database service is required")
+	}
+	s.setDefaults()
+
+	// A database opened here is closed again when the server cannot be
+	// built; an injected one is left to the caller.
+	opened := false
+	fail := func(err error) (*http.Server, func() error, error) {
+		if opened {
+			s.db.Close()
+		}
+		return nil, nil, err
+	}
+	if s.db == nil {
+		db, err := database.New(s.cfg, database.WithClock(s.clock))
+		if err != nil {
+			return nil, nil, fmt.Errorf("open database: %w", err)
+		}
+		s.db = db
+		opened = true
+	}
+
+	if s.spaceX == nil {
+		s.spaceX = spacex.New(s.cfg.SpaceX.APIURL,
+			spacex.WithTimeout(s.cfg.SpaceX.Timeout),
+			spacex.WithRetries(s.cfg.SpaceX.Retries),
+			spacex.WithBreaker(s.cfg.SpaceX.BreakerThreshold, s.cfg.SpaceX.BreakerCooldown),
+			spacex.WithCache(s.cfg.SpaceX.CacheTTL),
+			spacex.WithClock(s.clock),
+		)
+	}
+	if s.launchpads == nil {
+		s.launchpads = s.spaceX
+	}
+	if s.conflicts == nil {
+		providers, err := s.defaultConflictProviders()
+		if err != nil {
+			return fail(err)
+		}
+		s.conflicts = conflict.NewAggregator(providers...)
+	}
+
+	if s.mailer == nil {
+		mailer, err := s.defaultMailer()
+		if err != nil {
+			return fail(err)
+		}
+		s.mailer = mailer
+	}
+
+	if s.tickets == nil {
+		signer, err := s.defaultTicketSigner()
+		if err != nil {
+			return fail(err)
+		}
+		s.tickets = signer
+	}
+
+	if s.quotes == nil {
+		signer, err := s.defaultQuoteSigner()
+		if err != nil {
+			return fail(err)
+		}
+		s.quotes = signer
+	}
 
-		db: database.New(),
+	if s.payments == nil {
+		provider, err := s.defaultPaymentProvider()
+		if err != nil {
+			return fail(err)
+		}
+		s.payments = provider
 	}
 
 	// Declare Server config
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", NewServer.port),
-		Handler:      NewServer.RegisterRoutes(),
+		Addr:         s.cfg.Addr(),
+		Handler:      s.RegisterRoutes(),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
 
-	return server
+	// Background jobs run until the closer is called
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	s.startWorkers(ctx, &wg)
+
+	closer := func() error {
+		cancel()
+		wg.Wait()
+		return s.db.Close()
+	}
+	return server, closer, nil
+}
+
+// defaultMailer returns the mailer selected in the configuration.
+func (s *Server) defaultMailer() (notify.Mailer, error) {
+	mail := s.cfg.Mail
+	switch mail.Mailer {
+	case config.MailerFile:
+		return notify.NewFileMailer(mail.Dir, mail.From)
+	case config.MailerSMTP:
+		return notify.NewSMTPMailer(mail.SMTP.Host, mail.SMTP.Port, mail.SMTP.Username, mail.SMTP.Password, mail.From), nil
+	default:
+		return notify.NewLogMailer(s.logger), nil
+	}
+}
+
+// defaultTicketSigner returns the configured ticket key, or a random one.
+func (s *Server) defaultTicketSigner() (*ticket.Signer, error) {
+	if key := s.cfg.Tickets.SigningKey; key != "" {
+		return ticket.NewSigner(key)
+	}
+	s.logger.Printf("TICKET_SIGNING_KEY is not set, tickets are signed with a random key until the next restart")
+	return ticket.GenerateSigner()
+}
+
+// defaultQuoteSigner returns the configured quote key, or a random one.
+func (s *Server) defaultQuoteSigner() (*pricing.Signer, error) {
+	if key := s.cfg.Pricing.QuoteSigningKey; key != "" {
+		return pricing.NewSigner(key)
+	}
+	s.logger.Printf("QUOTE_SIGNING_KEY is not set, quotes are signed with a random key until the next restart")
+	return pricing.GenerateSigner()
+}
+
+// defaultPaymentProvider returns the configured payment provider.
+func (s *Server) defaultPaymentProvider() (payment.Provider, error) {
+	secret := s.cfg.Payments.WebhookSecret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(b)
+		s.logger.Printf("PAYMENT_WEBHOOK_SECRET is not set, payment events are verified with a random secret until the next restart")
+	}
+	switch s.cfg.Payments.Provider {
+	case config.PaymentFake:
+		return payment.NewFake(secret, s.clock), nil
+	}
+	return nil, fmt.Errorf("unknown payment provider %q", s.cfg.Payments.Provider)
+}
+
+// defaultConflictProviders returns the SpaceX schedule, the database
+// blackouts and the configured closure files.
+func (s *Server) defaultConflictProviders() ([]conflict.Provider, error) {
+	providers := []conflict.Provider{
+		conflict.NewSpaceX(s.spaceX, s.cfg.SpaceX.FailurePolicy == config.FailOpen, s.logger),
+		conflict.NewBlackouts(s.db),
+	}
+	for _, path := range s.cfg.Conflicts.Files {
+		file, err := conflict.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, file)
+	}
+	return providers, nil
 }
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"space-booking/internal/config"
+	"space-booking/internal/conflict"
+	"space-booking/internal/payment"
+	"space-booking/internal/pricing"
+	"space-booking/internal/ticket"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer applies the options over the defaults without opening anything
+// or starting the background jobs, for the handler tests.
+func newServer(opts ...Option) *Server {
+	s := &Server{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.setDefaults()
+	if s.conflicts == nil {
+		s.conflicts = conflict.NewAggregator()
+	}
+	if s.tickets == nil {
+		s.tickets, _ = ticket.GenerateSigner()
+	}
+	if s.quotes == nil {
+		s.quotes, _ = pricing.GenerateSigner()
+	}
+	if s.payments == nil {
+		s.payments = payment.NewFake(s.cfg.Payments.WebhookSecret, s.clock)
+	}
+	return s
+}
+
+func TestNewServerRequiresConfigOrDatabase(t *testing.T) {
+	_, _, err := NewServer()
+	assert.Error(t, err)
+}
+
+func TestNewServerWithInjectedDatabase(t *testing.T) {
+	db := new(MockDatabase)
+	db.On("Close").Return(nil).Once()
+
+	cfg := config.Default()
+	cfg.Port = 9999
+	cfg.Reconcile.Interval = 0
+	cfg.Webhooks.Interval = 0
+	cfg.Events.PollInterval = 0
+	cfg.Mail.Interval = 0
+	cfg.Manifests.Interval = 0
+	cfg.Payments.Interval = 0
+	cfg.Waitlist.Interval = 0
+	cfg.Holds.Interval = 0
+
+	srv, closeServer, err := NewServer(WithConfig(cfg), WithDatabase(db))
+	require.NoError(t, err)
+	assert.Equal(t, ":9999", srv.Addr)
+
+	// The handler is wired to the injected database
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"up"}`, rr.Body.String())
+
+	// The closer releases the database service
+	require.NoError(t, closeServer())
+	db.AssertExpectations(t)
+}
+
+func TestNewServerKeepsInjectedDatabaseOnError(t *testing.T) {
+	db := new(MockDatabase)
+	cfg := config.Default()
+	cfg.Tickets.SigningKey = "not a key"
+
+	_, _, err := NewServer(WithConfig(cfg), WithDatabase(db))
+	require.Error(t, err)
+	db.AssertNotCalled(t, "Close")
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"space-booking/internal/clock"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/suggest"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultSuggestions is how many flights are suggested unless the
+	// limit query parameter says otherwise.
+	defaultSuggestions = 5
+	// maxSuggestions caps the limit query parameter.
+	maxSuggestions = 50
+)
+
+// SuggestionsHandler lists the next days a destination can be booked from
+// a launchpad, after the optional after date, and the earliest day on each
+// other launchpad.
+func (s *Server) SuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	launchpadID := q.Get("launchpad")
+	if launchpadID == "" {
+		http.Error(w, "Query parameter launchpad is required", http.StatusBadRequest)
+		return
+	}
+	destinationID, err := strconv.ParseInt(q.Get("destination"), 10, 64)
+	if err != nil {
+		http.Error(w, "Query parameter destination must be a destination ID", http.StatusBadRequest)
+		return
+	}
+	var after time.Time
+	if v := q.Get("after"); v != "" {
+		if after, err = time.Parse(time.DateOnly, v); err != nil {
+			http.Error(w, "Query parameter after must be a date like 2049-12-25", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := defaultSuggestions
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxSuggestions {
+			http.Error(w, fmt.Sprintf("Query parameter limit must be from 1 to %d", maxSuggestions), http.StatusBadRequest)
+			return
+		}
+	}
+
+	query := s.suggestionQuery(launchpadID, destinationID, limit)
+	if !after.IsZero() && !after.Before(query.From) {
+		query.From = after.AddDate(0, 0, 1)
+	}
+
+	suggestions, err := s.suggestions().Find(r.Context(), query)
+	if err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, suggestions)
+}
+
+// rebookRequest is the optional body of RebookBookingHandler.
+type rebookRequest struct {
+	LaunchpadID string    `json:"launchpad_id"`
+	LaunchDate  time.Time `json:"launch_date"`
+}
+
+// RebookBookingHandler replaces a booking with one for the same passenger
+// and destination on another flight. The flight is taken from the body
+// when given, and is the next one suggested for the launchpad otherwise.
+func (s *Server) RebookBookingHandler(w http.ResponseWriter, r *http.Request) {
+	var req rebookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	booking, ok := s.lookupBooking(w, r)
+	if !ok {
+		return
+	}
+	id := booking.ID
+	if !booking.Status.CanTransitionTo(models.StatusRebooked) {
+		http.Error(w, fmt.Sprintf("Booking is %s and cannot be rebooked.", booking.Status), http.StatusConflict)
+		return
+	}
+
+	replacement := models.Booking{
+		FirstName:     booking.FirstName,
+		LastName:      booking.LastName,
+		Email:         booking.Email,
+		Gender:        booking.Gender,
+		Birthday:      booking.Birthday,
+		LaunchpadID:   booking.LaunchpadID,
+		DestinationID: booking.DestinationID,
+		LaunchDate:    req.LaunchDate,
+		// The passenger keeps the fare they paid.
+		Price:    booking.Price,
+		Currency: booking.Currency,
+	}
+	if req.LaunchpadID != "" {
+		replacement.LaunchpadID = req.LaunchpadID
+	}
+
+	if replacement.LaunchDate.IsZero() {
+		query := s.suggestionQuery(replacement.LaunchpadID, replacement.DestinationID, 1)
+		suggestions, err := s.suggestions().Find(r.Context(), query)
+		if err != nil {
+			s.writeBookingError(w, err)
+			return
+		}
+		if len(suggestions.Flights) == 0 {
+			http.Error(w, "No flight to the destination can be booked from this launchpad.", http.StatusConflict)
+			return
+		}
+		replacement.LaunchDate = suggestions.Flights[0].LaunchDate
+	} else if err := s.validateBooking(r.Context(), &replacement); err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+
+	err := s.db.RebookBooking(r.Context(), id, &replacement, s.cfg.Pricing.Seats)
+	var terr *models.TransitionError
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		http.Error(w, "Booking not found", http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrNoSeats):
+		http.Error(w, "Not enough free seats on this flight.", http.StatusConflict)
+		return
+	case errors.As(err, &terr):
+		http.Error(w, fmt.Sprintf("Booking is %s and cannot be rebooked.", terr.From), http.StatusConflict)
+		return
+	case err != nil:
+		s.logger.Printf("Error rebooking booking %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Printf("Booking %d rebooked as %d", id, replacement.ID)
+	writeJSON(w, http.StatusCreated, replacement)
+}
+
+// suggestions returns the finder behind the suggestion endpoints.
+func (s *Server) suggestions() *suggest.Finder {
+	return suggest.New(s.db, s.conflicts, s.launchpads, s.logger)
+}
+
+// suggestionQuery searches the whole booking window, from today to the
+// booking horizon.
+func (s *Server) suggestionQuery(launchpadID string, destinationID int64, limit int) suggest.Query {
+	today := clock.Day(s.clock.Now())
+	return suggest.Query{
+		LaunchpadID:   launchpadID,
+		DestinationID: destinationID,
+		From:          today,
+		To:            today.AddDate(0, 0, s.cfg.Booking.HorizonDays),
+		Limit:         limit,
+	}
+}
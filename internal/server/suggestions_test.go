@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/models"
+	"space-booking/internal/suggest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var destinationIDs = []int64{1, 2, 3, 4, 5, 6, 7}
+
+func TestSuggestionsHandler(t *testing.T) {
+	db := new(MockDatabase)
+	conflicts := new(MockConflictProvider)
+	s := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts))
+
+	// December 25, 2049 is a Saturday, destination 6 flies on Saturdays
+	after := time.Date(2049, time.December, 20, 0, 0, 0, 0, time.UTC)
+	blocked := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	db.On("GetDestinationIDs").Return(destinationIDs, nil)
+	conflicts.On("Conflicts", "test_launchpad", mock.Anything, mock.Anything).Return([]conflict.Conflict{
+		{Provider: "spacex", LaunchpadID: "test_launchpad", Date: blocked, Reason: "Starlink"},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	s.SuggestionsHandler(rr, httptest.NewRequest(http.MethodGet,
+		"/suggestions?launchpad=test_launchpad&destination=6&after=2049-12-20&limit=2", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var got suggest.Suggestions
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	require.Len(t, got.Flights, 2)
+	assert.Equal(t, after.AddDate(0, 0, 12), got.Flights[0].LaunchDate, "Expected the blocked Saturday to be skipped")
+	assert.Equal(t, after.AddDate(0, 0, 19), got.Flights[1].LaunchDate)
+	assert.Empty(t, got.Alternatives)
+	conflicts.AssertCalled(t, "Conflicts", "test_launchpad", after.AddDate(0, 0, 1), after.AddDate(0, 0, 31))
+
+	rr = httptest.NewRecorder()
+	s.SuggestionsHandler(rr, httptest.NewRequest(http.MethodGet, "/suggestions?launchpad=test_launchpad", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestRebookBookingHandler(t *testing.T) {
+	db := new(MockDatabase)
+	conflicts := new(MockConflictProvider)
+	s := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)), WithConflictProviders(conflicts))
+	handler := s.RegisterRoutes()
+	resetVisitors()
+
+	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	db.On("GetBookingByCode", "K7QX2MWP9D").Return(&models.Booking{
+		ID:            7,
+		Code:          "K7QX2MWP9D",
+		FirstName:     "Test",
+		LastName:      "User",
+		Birthday:      time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+		LaunchpadID:   "test_launchpad",
+		DestinationID: 6,
+		LaunchDate:    launchDate,
+		Status:        models.StatusDisrupted,
+	}, nil)
+	db.On("GetDestinationIDs").Return(destinationIDs, nil)
+	conflicts.On("Conflicts", "test_launchpad", mock.Anything, mock.Anything).Return([]conflict.Conflict{
+		{Provider: "spacex", LaunchpadID: "test_launchpad", Date: launchDate, Reason: "Starlink"},
+	}, nil)
+	db.On("RebookBooking", 7, mock.AnythingOfType("*models.Booking"), 50).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Booking).ID = 8
+	}).Return(nil)
+
+	// Without a body the booking moves to the next suggested flight
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings/K7QX2MWP9D/rebook", nil))
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var got models.Booking
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, 8, got.ID)
+	assert.Equal(t, "Test", got.FirstName)
+	assert.Equal(t, int64(6), got.DestinationID)
+	assert.Equal(t, time.Date(2049, time.December, 4, 0, 0, 0, 0, time.UTC), got.LaunchDate)
+
+	// A requested flight is validated like a new booking
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings/K7QX2MWP9D/rebook",
+		strings.NewReader(`{"launch_date":"2049-12-25T00:00:00Z"}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Starlink (reported by spacex)")
+	db.AssertNumberOfCalls(t, "RebookBooking", 1)
+}
+
+func TestRebookBookingHandlerFinalStatus(t *testing.T) {
+	db := new(MockDatabase)
+	s := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)))
+	handler := s.RegisterRoutes()
+	resetVisitors()
+
+	db.On("GetBookingByCode", "K7QX2MWP9D").Return(&models.Booking{ID: 7, Code: "K7QX2MWP9D", Status: models.StatusFlown}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bookings/K7QX2MWP9D/rebook", nil))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	db.AssertNotCalled(t, "RebookBooking", mock.Anything, mock.Anything, mock.Anything)
+}
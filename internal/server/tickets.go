@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/ticket"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ticketStatuses are the statuses of bookings that have a ticket.
+var ticketStatuses = []models.BookingStatus{models.StatusConfirmed, models.StatusCheckedIn, models.StatusBoarded}
+
+// qrSize is the width and height of the QR code image in pixels.
+const qrSize = 512
+
+// GetTicketHandler returns the ticket of a confirmed booking as a PDF, or
+// its QR code alone as a PNG with format=png.
+func (s *Server) GetTicketHandler(w http.ResponseWriter, r *http.Request) {
+	booking, ok := s.lookupBooking(w, r)
+	if !ok {
+		return
+	}
+	if !slices.Contains(ticketStatuses, booking.Status) {
+		http.Error(w, fmt.Sprintf("Booking is %s and has no ticket.", booking.Status), http.StatusConflict)
+		return
+	}
+
+	token, err := s.tickets.Sign(ticket.NewClaims(*booking))
+	if err != nil {
+		s.logger.Printf("Error signing ticket of booking %d: %v", booking.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "png":
+		qr, err := ticket.QRCode(token, qrSize)
+		if err != nil {
+			s.logger.Printf("Error drawing QR code of booking %d: %v", booking.ID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(qr)
+	case "", "pdf":
+		destination, err := s.db.GetDestinationName(r.Context(), booking.DestinationID)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			s.logger.Printf("Error retrieving destination %d: %v", booking.DestinationID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		var pdf bytes.Buffer
+		err = ticket.WritePDF(&pdf, ticket.Ticket{Booking: *booking, DestinationName: destination, Token: token})
+		if err != nil {
+			s.logger.Printf("Error rendering ticket of booking %d: %v", booking.ID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="ticket-%s.pdf"`, booking.Code))
+		w.Write(pdf.Bytes())
+	default:
+		http.Error(w, fmt.Sprintf("Unknown format %q, expected pdf or png", format), http.StatusBadRequest)
+	}
+}
+
+// lookupBooking finds the booking named by the {id} URL parameter, which
+// is its code; operators may use the ID as well. It answers the request
+// itself when the booking cannot be found.
+func (s *Server) lookupBooking(w http.ResponseWriter, r *http.Request) (*models.Booking, bool) {
+	ref := chi.URLParam(r, "id")
+	var booking *models.Booking
+	var err error
+	if id, convErr := strconv.Atoi(ref); convErr == nil && s.isAdmin(r) {
+		booking, err = s.db.GetBooking(r.Context(), id)
+	} else {
+		booking, err = s.db.GetBookingByCode(r.Context(), ref)
+	}
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Booking not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		s.logger.Printf("Error retrieving booking %s: %v", ref, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return booking, true
+}
+
+// ticketKey is the body of TicketPublicKeyHandler.
+type ticketKey struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+}
+
+// TicketPublicKeyHandler returns the key the QR codes on the tickets are
+// verified with, for the gate scanners to keep offline.
+func (s *Server) TicketPublicKeyHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ticketKey{
+		Algorithm: "Ed25519",
+		PublicKey: base64.StdEncoding.EncodeToString(s.tickets.PublicKey()),
+	})
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/ticket"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ticketBooking(status models.BookingStatus) *models.Booking {
+	return &models.Booking{
+		ID:            7,
+		Code:          "K7QX2MWP9D",
+		FirstName:     "Test",
+		LastName:      "User",
+		LaunchpadID:   "test_launchpad",
+		DestinationID: 1,
+		LaunchDate:    time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC),
+		Status:        status,
+	}
+}
+
+func TestGetTicketHandler(t *testing.T) {
+	signer, err := ticket.GenerateSigner()
+	require.NoError(t, err)
+
+	t.Run("PDF by code", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		db.On("GetBookingByCode", "k7qx2mwp9d").Return(ticketBooking(models.StatusConfirmed), nil).Once()
+		db.On("GetDestinationName", int64(1)).Return("Mars", nil).Once()
+
+		handler := newServer(WithDatabase(db), WithTicketSigner(signer)).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/k7qx2mwp9d/ticket", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+		assert.Equal(t, `inline; filename="ticket-K7QX2MWP9D.pdf"`, rr.Header().Get("Content-Disposition"))
+		assert.True(t, bytes.HasPrefix(rr.Body.Bytes(), []byte("%PDF-")))
+		db.AssertExpectations(t)
+	})
+
+	t.Run("QR code by ID for operators", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		db.On("GetBooking", 7).Return(ticketBooking(models.StatusConfirmed), nil).Once()
+
+		handler := newServer(WithConfig(adminConfig()), WithDatabase(db), WithTicketSigner(signer)).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/bookings/7/ticket?format=png", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+		_, err := png.Decode(rr.Body)
+		assert.NoError(t, err)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("ID is not a public reference", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		db.On("GetBookingByCode", "7").Return(nil, database.ErrNotFound).Once()
+
+		handler := newServer(WithDatabase(db), WithTicketSigner(signer)).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/7/ticket", nil))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("no ticket for a cancelled booking", func(t *testing.T) {
+		resetVisitors()
+		db := new(MockDatabase)
+		db.On("GetBookingByCode", "K7QX2MWP9D").Return(ticketBooking(models.StatusCancelled), nil).Once()
+
+		handler := newServer(WithDatabase(db), WithTicketSigner(signer)).RegisterRoutes()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/K7QX2MWP9D/ticket", nil))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, "Booking is cancelled and has no ticket.\n", rr.Body.String())
+		db.AssertExpectations(t)
+	})
+}
+
+func TestTicketPublicKeyHandler(t *testing.T) {
+	resetVisitors()
+	signer, err := ticket.GenerateSigner()
+	require.NoError(t, err)
+
+	handler := newServer(WithDatabase(new(MockDatabase)), WithTicketSigner(signer)).RegisterRoutes()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/tickets/public-key", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var key ticketKey
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &key))
+	assert.Equal(t, "Ed25519", key.Algorithm)
+	raw, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	require.NoError(t, err)
+
+	// Tokens signed by the server verify against the published key
+	token, err := signer.Sign(ticket.NewClaims(*ticketBooking(models.StatusConfirmed)))
+	require.NoError(t, err)
+	_, err = ticket.Verify(raw, token)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"space-booking/internal/clock"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/pricing"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// confirmRequest is the body of ConfirmWaitlistHandler.
+type confirmRequest struct {
+	// PaymentMethod is the payment provider's token, e.g. of a card.
+	PaymentMethod string `json:"payment_method"`
+}
+
+// JoinWaitlistHandler puts a passenger on the waitlist of a flight that is
+// sold out or blocked by a launchpad conflict. The flight is validated like
+// a booking otherwise: a flight that can be booked answers 409 Conflict,
+// and one that will never fly, such as a destination not flown that day,
+// 400 Bad Request.
+func (s *Server) JoinWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	var entry models.WaitlistEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		s.logger.Printf("Invalid waitlist data: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	booking := entry.Booking(time.Time{})
+	if err := validateEmail(booking); err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	entry.Email = booking.Email
+	if entry.LaunchDate.IsZero() || entry.Birthday.IsZero() {
+		s.writeBookingError(w, &validationError{"Launch date and birthday must be provided."})
+		return
+	}
+	if err := s.checkLaunchWindow(entry.LaunchDate); err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	entry.LaunchDate, entry.Birthday = clock.Day(entry.LaunchDate), clock.Day(entry.Birthday)
+
+	ctx := r.Context()
+	scheduled, err := s.db.CheckDestinationSchedule(ctx, entry.DestinationID, entry.LaunchpadID, entry.LaunchDate)
+	if err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	if !scheduled {
+		s.writeBookingError(w, errSchedulingConflict)
+		return
+	}
+	c, err := s.conflicts.Check(ctx, entry.LaunchpadID, entry.LaunchDate)
+	if err != nil {
+		s.writeBookingError(w, err)
+		return
+	}
+	if c == nil {
+		// Not blocked, so it has to be sold out.
+		in, err := s.fareInput(ctx, booking)
+		if err != nil {
+			s.writeBookingError(w, err)
+			return
+		}
+		if in.Destination.BasePrice <= 0 {
+			s.writeBookingError(w, fareError(pricing.ErrNotForSale, entry.DestinationID))
+			return
+		}
+		if in.SeatsSold < s.cfg.Pricing.Seats {
+			http.Error(w, "Flight has free seats, book it instead.", http.StatusConflict)
+			return
+		}
+	}
+
+	err = s.db.JoinWaitlist(ctx, &entry)
+	if errors.Is(err, database.ErrAlreadyWaiting) {
+		http.Error(w, "Passenger is already waiting for this flight.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error joining the waitlist: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.writeWaitlistEntry(w, r, http.StatusCreated, &entry)
+}
+
+// GetWaitlistEntryHandler returns a waitlist entry with its position in
+// the queue, or when the seat offered is held until.
+func (s *Server) GetWaitlistEntryHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.lookupWaitlistEntry(w, r)
+	if !ok {
+		return
+	}
+	s.writeWaitlistEntry(w, r, http.StatusOK, entry)
+}
+
+// LeaveWaitlistHandler takes a waiting passenger off the waitlist. A seat
+// already offered is given up by cancelling its booking instead.
+func (s *Server) LeaveWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.lookupWaitlistEntry(w, r)
+	if !ok {
+		return
+	}
+	entry, err := s.db.LeaveWaitlist(r.Context(), entry.ID)
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		http.Error(w, "Waitlist entry not found", http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrNotWaiting):
+		http.Error(w, fmt.Sprintf("Waitlist entry is %s and cannot be left.", entry.Status), http.StatusConflict)
+		return
+	case err != nil:
+		s.logger.Printf("Error leaving waitlist entry %s: %v", chi.URLParam(r, "id"), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.writeWaitlistEntry(w, r, http.StatusOK, entry)
+}
+
+// ConfirmWaitlistHandler pays for the seat offered to a promoted entry,
+// which confirms its booking, and answers like CreateBookingHandler. The
+// hold has to be still on.
+func (s *Server) ConfirmWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	var req confirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	entry, ok := s.lookupWaitlistEntry(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if entry.BookingID == nil {
+		http.Error(w, fmt.Sprintf("Waitlist entry is %s and has no seat to confirm.", entry.Status), http.StatusConflict)
+		return
+	}
+	booking, err := s.db.GetBooking(ctx, *entry.BookingID)
+	if err != nil {
+		s.logger.Printf("Error retrieving booking %d: %v", *entry.BookingID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if booking.Status != models.StatusPending {
+		http.Error(w, fmt.Sprintf("Booking is %s and cannot be paid.", booking.Status), http.StatusConflict)
+		return
+	}
+	if booking.HoldUntil != nil && !s.clock.Now().Before(*booking.HoldUntil) {
+		http.Error(w, "Seat is no longer held, the hold has ended.", http.StatusConflict)
+		return
+	}
+	payments, err := s.db.GetPayments(ctx, booking.ID)
+	if err != nil {
+		s.logger.Printf("Error retrieving the payments of booking %d: %v", booking.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	for _, p := range payments {
+		if p.Status == models.PaymentPending {
+			http.Error(w, "Booking is already being paid.", http.StatusConflict)
+			return
+		}
+	}
+	s.pay(w, r, booking, req.PaymentMethod)
+}
+
+// lookupWaitlistEntry finds the waitlist entry named by the {id} URL
+// parameter, which is its code; operators may use the ID as well. It
+// answers the request itself when the entry cannot be found.
+func (s *Server) lookupWaitlistEntry(w http.ResponseWriter, r *http.Request) (*models.WaitlistEntry, bool) {
+	ref := chi.URLParam(r, "id")
+	var entry *models.WaitlistEntry
+	var err error
+	if id, convErr := strconv.Atoi(ref); convErr == nil && s.isAdmin(r) {
+		entry, err = s.db.GetWaitlistEntry(r.Context(), id)
+	} else {
+		entry, err = s.db.GetWaitlistEntryByCode(r.Context(), ref)
+	}
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Waitlist entry not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		s.logger.Printf("Error retrieving waitlist entry %s: %v", ref, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return entry, true
+}
+
+// writeWaitlistEntry answers with the entry to operators, and only with its
+// summary to anyone else, who may just have come by the code.
+func (s *Server) writeWaitlistEntry(w http.ResponseWriter, r *http.Request, status int, entry *models.WaitlistEntry) {
+	if s.isAdmin(r) {
+		writeJSON(w, status, entry)
+		return
+	}
+	writeJSON(w, status, entry.Summary())
+}
+
+// ListWaitlistHandler lists the waitlist entries, optionally of one
+// launchpad and launch date.
+func (s *Server) ListWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	var day time.Time
+	if v := r.URL.Query().Get("date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		day = d
+	}
+	entries, err := s.db.ListWaitlist(r.Context(), r.URL.Query().Get("launchpad"), day)
+	if err != nil {
+		s.logger.Printf("Error retrieving the waitlist: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []models.WaitlistEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/payment"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func waitlistBody(t *testing.T) *bytes.Buffer {
+	body, err := json.Marshal(map[string]any{
+		"first_name": "Test", "last_name": "User", "email": "Test User <test@example.com>",
+		"birthday": "1990-01-01T00:00:00Z", "launchpad_id": "test_launchpad", "destination_id": 1,
+		"launch_date": "2049-12-25T00:00:00Z",
+	})
+	require.NoError(t, err)
+	return bytes.NewBuffer(body)
+}
+
+func TestJoinWaitlistHandler(t *testing.T) {
+	launchDate := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		sold    int
+		blocked bool
+		join    error
+		want    int
+		msg     string
+	}{
+		{"sold out", 50, false, nil, http.StatusCreated, ""},
+		{"blocked", 0, true, nil, http.StatusCreated, ""},
+		{"free seats", 10, false, nil, http.StatusConflict, "Flight has free seats, book it instead.\n"},
+		{"already waiting", 50, false, database.ErrAlreadyWaiting, http.StatusConflict,
+			"Passenger is already waiting for this flight.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			conflicts := new(MockConflictProvider)
+			var found []conflict.Conflict
+			if tt.blocked {
+				found = []conflict.Conflict{{Provider: "blackouts", LaunchpadID: "test_launchpad", Date: launchDate, Reason: "Maintenance"}}
+			}
+			conflicts.On("Conflicts", "test_launchpad", launchDate, launchDate).Return(found, nil)
+			db.On("CheckDestinationSchedule", int64(1), "test_launchpad", launchDate).Return(true, nil)
+			if !tt.blocked {
+				expectFare(db, "test_launchpad", launchDate, tt.sold)
+			}
+			if tt.want == http.StatusCreated || tt.join != nil {
+				db.On("JoinWaitlist", mock.MatchedBy(func(e *models.WaitlistEntry) bool {
+					return e.Email == "test@example.com" && e.LaunchDate.Equal(launchDate)
+				})).Run(func(args mock.Arguments) {
+					e := args.Get(0).(*models.WaitlistEntry)
+					e.ID, e.Code, e.Status, e.Position = 4, "W3LT7QX2MP", models.WaitlistWaiting, 2
+				}).Return(tt.join).Once()
+			}
+			handler := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)),
+				WithConflictProviders(conflicts)).RegisterRoutes()
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/waitlist", waitlistBody(t)))
+
+			assert.Equal(t, tt.want, rr.Code)
+			if tt.msg != "" {
+				assert.Equal(t, tt.msg, rr.Body.String())
+			}
+			if tt.want == http.StatusCreated {
+				var got models.WaitlistSummary
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				assert.Equal(t, "W3LT7QX2MP", got.Code)
+				assert.Equal(t, 2, got.Position)
+				assert.NotContains(t, rr.Body.String(), "test@example.com")
+			}
+			db.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetWaitlistEntryHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+	entry := &models.WaitlistEntry{
+		ID: 4, Code: "W3LT7QX2MP", FirstName: "Test", LastName: "User", Email: "test@example.com",
+		Status: models.WaitlistWaiting, Position: 2,
+	}
+	db.On("GetWaitlistEntryByCode", "w3lt7qx2mp").Return(entry, nil).Once()
+	db.On("GetWaitlistEntryByCode", "4").Return(nil, database.ErrNotFound).Once()
+	db.On("GetWaitlistEntry", 4).Return(entry, nil).Once()
+
+	// The code shows the entry without the passenger.
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/waitlist/w3lt7qx2mp", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"position":2`)
+	assert.NotContains(t, rr.Body.String(), "test@example.com")
+	assert.NotContains(t, rr.Body.String(), "Test")
+
+	// IDs are for operators only.
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/waitlist/4", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/waitlist/4", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "test@example.com")
+	db.AssertExpectations(t)
+}
+
+func TestLeaveWaitlistHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db)).RegisterRoutes()
+	db.On("GetWaitlistEntryByCode", "W3LT7QX2MP").Return(&models.WaitlistEntry{ID: 4, Status: models.WaitlistWaiting}, nil).Once()
+	db.On("GetWaitlistEntryByCode", "P8NZ4KR6HD").Return(&models.WaitlistEntry{ID: 5, Status: models.WaitlistPromoted}, nil).Once()
+	db.On("LeaveWaitlist", 4).Return(&models.WaitlistEntry{ID: 4, Status: models.WaitlistLeft}, nil).Once()
+	db.On("LeaveWaitlist", 5).Return(&models.WaitlistEntry{ID: 5, Status: models.WaitlistPromoted}, database.ErrNotWaiting).Once()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/waitlist/W3LT7QX2MP", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/waitlist/P8NZ4KR6HD", nil))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "Waitlist entry is promoted and cannot be left.\n", rr.Body.String())
+	db.AssertExpectations(t)
+}
+
+func TestConfirmWaitlistHandler(t *testing.T) {
+	bookingID := 7
+	held := bookingDay.Add(time.Hour)
+	ended := bookingDay.Add(-time.Hour)
+	tests := []struct {
+		name      string
+		holdUntil time.Time
+		status    models.BookingStatus
+		want      int
+		msg       string
+	}{
+		{"held", held, models.StatusPending, http.StatusCreated, ""},
+		{"hold ended", ended, models.StatusPending, http.StatusConflict, "Seat is no longer held, the hold has ended.\n"},
+		{"cancelled", held, models.StatusCancelled, http.StatusConflict, "Booking is cancelled and cannot be paid.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVisitors()
+			db := new(MockDatabase)
+			handler := newServer(WithDatabase(db), WithClock(clock.NewFake(bookingDay)),
+				WithPaymentProvider(payment.NewFake("whsec", clock.NewFake(bookingDay)))).RegisterRoutes()
+			db.On("GetWaitlistEntryByCode", "W3LT7QX2MP").Return(&models.WaitlistEntry{
+				ID: 4, Code: "W3LT7QX2MP", Status: models.WaitlistPromoted, BookingID: &bookingID, HoldUntil: &tt.holdUntil,
+			}, nil)
+			booking := &models.Booking{ID: bookingID, Status: tt.status, Price: 100000000, Currency: "USD", HoldUntil: &tt.holdUntil}
+			db.On("GetBooking", bookingID).Return(booking, nil)
+			if tt.want == http.StatusCreated {
+				db.On("GetPayments", bookingID).Return([]models.Payment(nil), nil)
+				db.On("CreatePayment", mock.AnythingOfType("*models.Payment")).Run(func(args mock.Arguments) {
+					p := args.Get(0).(*models.Payment)
+					assert.Equal(t, booking.Price, p.Amount)
+					p.ID = 3
+				}).Return(nil).Once()
+				confirmed := *booking
+				confirmed.Status = models.StatusConfirmed
+				capturing := &models.Payment{ID: 3, Amount: booking.Price, Currency: booking.Currency, Status: models.PaymentCapturing}
+				db.On("StartCapture", int64(3), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					capturing.ProviderRef = args.String(1)
+				}).Return(capturing, nil).Once()
+				db.On("ConfirmPayment", int64(3)).Return(&confirmed, nil).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/waitlist/W3LT7QX2MP/confirm",
+				bytes.NewBufferString(`{"payment_method":"tok_visa"}`)))
+
+			assert.Equal(t, tt.want, rr.Code)
+			if tt.msg != "" {
+				assert.Equal(t, tt.msg, rr.Body.String())
+			}
+			db.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListWaitlistHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+	day := time.Date(2049, time.December, 25, 0, 0, 0, 0, time.UTC)
+	db.On("ListWaitlist", "test_launchpad", day).Return([]models.WaitlistEntry{{ID: 4}}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/waitlist?launchpad=test_launchpad&date=2049-12-25", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/waitlist?date=25.12.2049", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	db.AssertExpectations(t)
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/webhook"
+	"strconv"
+)
+
+// maxDeliveries caps the delivery log returned at once.
+const maxDeliveries = 200
+
+// webhookRequest is the body of the create and update handlers.
+type webhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Active defaults to true.
+	Active *bool `json:"active"`
+	// Secret is generated when left empty; it cannot be changed later.
+	Secret string `json:"secret"`
+}
+
+// ListWebhooksHandler lists the webhook subscriptions, without secrets.
+func (s *Server) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.db.ListWebhooks(r.Context())
+	if err != nil {
+		s.logger.Printf("Error retrieving webhooks: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if webhooks == nil {
+		webhooks = []models.WebhookSubscription{}
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	writeJSON(w, http.StatusOK, webhooks)
+}
+
+// GetWebhookHandler returns a single subscription, without its secret.
+func (s *Server) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+	webhook, err := s.db.GetWebhook(r.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error retrieving webhook %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	webhook.Secret = ""
+	writeJSON(w, http.StatusOK, webhook)
+}
+
+// CreateWebhookHandler subscribes an endpoint to booking changes. The
+// response is the only one that shows the signing secret.
+func (s *Server) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeWebhook(w, r)
+	if !ok {
+		return
+	}
+	subscription := req.subscription()
+	if subscription.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			s.logger.Printf("Error generating webhook secret: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		subscription.Secret = secret
+	}
+
+	if err := s.db.CreateWebhook(r.Context(), subscription); err != nil {
+		s.logger.Printf("Error creating webhook: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, subscription)
+}
+
+// UpdateWebhookHandler changes the URL, event types or active flag of a
+// subscription.
+func (s *Server) UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+	req, ok := s.decodeWebhook(w, r)
+	if !ok {
+		return
+	}
+	subscription := req.subscription()
+	subscription.ID = id
+	subscription.Secret = ""
+
+	err := s.db.UpdateWebhook(r.Context(), subscription)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error updating webhook %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// DeleteWebhookHandler unsubscribes an endpoint.
+func (s *Server) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+	err := s.db.DeleteWebhook(r.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error deleting webhook %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveriesHandler returns the latest deliveries to a
+// subscription, newest first, up to the limit query parameter.
+func (s *Server) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r)
+	if !ok {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveries {
+			http.Error(w, fmt.Sprintf("Query parameter limit must be from 1 to %d", maxDeliveries), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	if _, err := s.db.GetWebhook(r.Context(), id); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		s.logger.Printf("Error retrieving webhook %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	deliveries, err := s.db.ListWebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		s.logger.Printf("Error retrieving deliveries of webhook %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// decodeWebhook reads and validates a subscription from the request body.
+// It writes the error response itself and reports whether decoding
+// succeeded.
+func (s *Server) decodeWebhook(w http.ResponseWriter, r *http.Request) (*webhookRequest, bool) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Printf("Invalid webhook data: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return nil, false
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "URL must be an http(s) URL.", http.StatusBadRequest)
+		return nil, false
+	}
+	for _, t := range req.EventTypes {
+		if !slices.Contains(models.WebhookEventTypes, t) {
+			http.Error(w, fmt.Sprintf("Unknown event type %q, expected one of %v.", t, models.WebhookEventTypes), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	return &req, true
+}
+
+// subscription returns the subscription described by the request.
+func (req *webhookRequest) subscription() *models.WebhookSubscription {
+	active := req.Active == nil || *req.Active
+	eventTypes := req.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return &models.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: eventTypes,
+		Active:     active,
+	}
+}
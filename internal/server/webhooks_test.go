@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"space-booking/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWebhookHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	db.On("CreateWebhook", mock.AnythingOfType("*models.WebhookSubscription")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.WebhookSubscription).ID = 4
+	}).Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/webhooks",
+		[]byte(`{"url":"https://crm.example.com/hooks","event_types":["booking.created","booking.cancelled"]}`)))
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var got models.WebhookSubscription
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, 4, got.ID)
+	assert.True(t, got.Active, "Expected new subscriptions to be active")
+	assert.True(t, strings.HasPrefix(got.Secret, "whsec_"), "Expected a generated secret")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/webhooks",
+		[]byte(`{"url":"https://crm.example.com/hooks","event_types":["booking.lost"]}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/webhooks", []byte(`{"url":"ftp://crm.example.com"}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	db.AssertNumberOfCalls(t, "CreateWebhook", 1)
+}
+
+func TestListWebhooksHandlerHidesSecrets(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	db.On("ListWebhooks").Return([]models.WebhookSubscription{
+		{ID: 4, URL: "https://crm.example.com/hooks", Secret: "whsec_hidden", EventTypes: []string{}, Active: true},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/webhooks", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "whsec_hidden")
+}
+
+func TestListWebhookDeliveriesHandler(t *testing.T) {
+	resetVisitors()
+	db := new(MockDatabase)
+	handler := newServer(WithDatabase(db), WithConfig(adminConfig())).RegisterRoutes()
+
+	code := http.StatusServiceUnavailable
+	db.On("GetWebhook", 4).Return(&models.WebhookSubscription{ID: 4}, nil)
+	db.On("ListWebhookDeliveries", 4, 10).Return([]models.WebhookDelivery{
+		{ID: 9, SubscriptionID: 4, OutboxID: 12, EventType: "booking.cancelled", Status: models.DeliveryPending, Attempts: 1, LastStatusCode: &code},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/webhooks/4/deliveries?limit=10", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var got []models.WebhookDelivery
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, http.StatusServiceUnavailable, *got[0].LastStatusCode)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"space-booking/internal/events"
+	"space-booking/internal/manifest"
+	"space-booking/internal/notify"
+	"space-booking/internal/payment"
+	"space-booking/internal/reconcile"
+	"space-booking/internal/waitlist"
+	"space-booking/internal/webhook"
+)
+
+// startWorkers starts the background jobs enabled in the configuration.
+// They stop when ctx is cancelled; wg is done once all have returned.
+func (s *Server) startWorkers(ctx context.Context, wg *sync.WaitGroup) {
+	if interval := s.cfg.Reconcile.Interval; interval > 0 {
+		r := reconcile.New(s.db, s.conflicts, s.clock, s.logger)
+		s.every(ctx, wg, "reconciler", interval, func(ctx context.Context) error {
+			_, err := r.RunOnce(ctx)
+			return err
+		})
+	}
+
+	if interval := s.cfg.Webhooks.Interval; interval > 0 {
+		d := webhook.New(s.db, s.clock, s.logger,
+			webhook.WithHTTPClient(&http.Client{Timeout: s.cfg.Webhooks.Timeout}),
+			webhook.WithMaxAttempts(s.cfg.Webhooks.MaxAttempts),
+		)
+		s.every(ctx, wg, "webhooks", interval, func(ctx context.Context) error {
+			_, err := d.RunOnce(ctx)
+			return err
+		})
+	}
+
+	if interval := s.cfg.Manifests.Interval; interval > 0 {
+		p := manifest.NewPublisher(s.db, s.clock, s.cfg.Manifests.Lead, s.logger)
+		s.every(ctx, wg, "manifests", interval, func(ctx context.Context) error {
+			_, err := p.RunOnce(ctx)
+			return err
+		})
+	}
+
+	if interval := s.cfg.Mail.Interval; interval > 0 && s.mailer != nil {
+		n := notify.New(s.db, s.mailer, s.clock, s.logger, notify.WithMaxAttempts(s.cfg.Mail.MaxAttempts))
+		s.every(ctx, wg, "notifications", interval, func(ctx context.Context) error {
+			_, err := n.RunOnce(ctx)
+			return err
+		})
+	}
+
+	if interval := s.cfg.Payments.Interval; interval > 0 {
+		ttl := s.cfg.Payments.PendingTTL
+		s.every(ctx, wg, "payment expiry", interval, func(ctx context.Context) error {
+			n, err := s.db.ExpirePendingBookings(ctx, s.clock.Now().Add(-ttl))
+			if n > 0 {
+				s.logger.Printf("Expired %d unpaid bookings", n)
+			}
+			return err
+		})
+
+		c := payment.NewCapturer(s.db, s.payments, s.clock, s.logger)
+		s.every(ctx, wg, "captures", interval, func(ctx context.Context) error {
+			_, err := c.RunOnce(ctx)
+			return err
+		})
+
+		r := payment.NewRefunder(s.db, s.payments, s.clock, s.logger,
+			payment.WithMaxAttempts(s.cfg.Payments.RefundMaxAttempts))
+		s.every(ctx, wg, "refunds", interval, func(ctx context.Context) error {
+			_, err := r.RunOnce(ctx)
+			return err
+		})
+	}
+
+	if interval := s.cfg.Holds.Interval; interval > 0 {
+		s.every(ctx, wg, "hold expiry", interval, func(ctx context.Context) error {
+			n, err := s.db.ExpireHolds(ctx)
+			if n > 0 {
+				s.logger.Printf("Expired %d seat holds", n)
+			}
+			return err
+		})
+	}
+
+	if interval := s.cfg.Waitlist.Interval; interval > 0 {
+		p := waitlist.New(s.db, s.conflicts, s.pricing(), s.cfg.Waitlist.Hold, s.clock, s.logger)
+		s.every(ctx, wg, "waitlist", interval, func(ctx context.Context) error {
+			_, err := p.RunOnce(ctx)
+			return err
+		})
+	}
+
+	if interval := s.cfg.Events.PollInterval; interval > 0 {
+		p := events.NewPoller(s.db, s.hub)
+		s.every(ctx, wg, "events poller", interval, p.RunOnce)
+	}
+	if s.cfg.Events.Listen {
+		// Listening only returns when the connection fails; it is resumed
+		// on the next tick.
+		s.every(ctx, wg, "events listener", 5*time.Second, func(ctx context.Context) error {
+			return s.db.ListenOutbox(ctx, s.hub.Notify)
+		})
+	}
+}
+
+// every runs job in its own goroutine, once right away and then once per
+// interval, until ctx is cancelled. Errors are logged and the job is tried
+// again on the next tick.
+func (s *Server) every(ctx context.Context, wg *sync.WaitGroup, name string, interval time.Duration, job func(context.Context) error) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := job(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Printf("Background job %s failed: %v", name, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
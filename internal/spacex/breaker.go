@@ -0,0 +1,72 @@
+package spacex
+
+import (
+	"sync"
+	"time"
+
+	"space-booking/internal/clock"
+)
+
+// breaker is a consecutive-failure circuit breaker. After threshold failed
+// calls in a row it opens and rejects calls until cooldown has passed, then
+// lets a single trial call through: its success closes the breaker again,
+// its failure reopens it for another cooldown.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	clock     clock.Clock
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// allow reports whether a call may go ahead.
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.trial || b.clock.Now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// record feeds the outcome of an allowed call back into the breaker.
+func (b *breaker) record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = b.clock.Now()
+	}
+}
+
+// release hands back an allowed call that ended without an outcome, such
+// as one its caller cancelled, counting it neither way.
+func (b *breaker) release() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
@@ -0,0 +1,57 @@
+package spacex
+
+import (
+	"sync"
+	"time"
+
+	"space-booking/internal/clock"
+)
+
+// cache keeps query results for a fixed time. Expired entries are dropped
+// when they are next looked up or when a new entry is stored.
+type cache struct {
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	launches  []Launch
+	expiresAt time.Time
+}
+
+func (c *cache) get(key string) ([]Launch, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.clock.Now().Before(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.launches, true
+}
+
+func (c *cache) put(key string, launches []Launch) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	for k, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{launches: launches, expiresAt: now.Add(c.ttl)}
+}
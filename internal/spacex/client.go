@@ -0,0 +1,317 @@
+package spacex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"space-booking/internal/clock"
+)
+
+// ErrUnavailable is returned when the SpaceX API could not be reached, kept
+// failing after all retries, or the circuit breaker is open.
+var ErrUnavailable = errors.New("spacex: api unavailable")
+
+// ErrNotFound is returned when the requested resource does not exist.
+var ErrNotFound = errors.New("spacex: not found")
+
+// StatusError is returned for responses with an unexpected status code.
+type StatusError struct {
+	Code int
+	URL  string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("spacex: %s returned status %d", e.URL, e.Code)
+}
+
+// Client is a client for the SpaceX API. It retries transient failures
+// with exponential backoff and stops calling the API for a while when it
+// keeps failing. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	breaker    *breaker
+	cache      *cache
+}
+
+// Option configures a Client built by New.
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client. Its Timeout bounds every
+// single attempt. A nil client leaves the default in place.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithTimeout bounds every single attempt made with the default HTTP client.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.httpClient = &http.Client{Timeout: d} }
+}
+
+// WithRetries sets how many times a failed request is retried.
+func WithRetries(n int) Option {
+	return func(c *Client) { c.retries = n }
+}
+
+// WithBackoff sets the delay before the first retry and the cap it doubles
+// up to.
+func WithBackoff(min, max time.Duration) Option {
+	return func(c *Client) {
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+// WithBreaker opens the circuit after threshold consecutive failed calls
+// and keeps it open for cooldown. A threshold of zero disables it.
+func WithBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.breaker.threshold = threshold
+		c.breaker.cooldown = cooldown
+	}
+}
+
+// WithCache keeps query results for ttl. A ttl of zero disables caching.
+func WithCache(ttl time.Duration) Option {
+	return func(c *Client) { c.cache.ttl = ttl }
+}
+
+// WithClock sets the clock the circuit breaker and the cache measure time
+// with.
+func WithClock(clk clock.Clock) Option {
+	return func(c *Client) {
+		c.breaker.clock = clk
+		c.cache.clock = clk
+	}
+}
+
+// New returns a client for the API at baseURL, e.g. https://api.spacexdata.com.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		retries:    2,
+		minBackoff: 200 * time.Millisecond,
+		maxBackoff: 2 * time.Second,
+		breaker: &breaker{
+			threshold: 5,
+			cooldown:  30 * time.Second,
+			clock:     clock.Real(),
+		},
+		cache: &cache{
+			clock:   clock.Real(),
+			entries: make(map[string]cacheEntry),
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Launches returns every launch known to the API.
+func (c *Client) Launches(ctx context.Context) ([]Launch, error) {
+	var launches []Launch
+	if err := c.get(ctx, "/v5/launches", &launches); err != nil {
+		return nil, err
+	}
+	return launches, nil
+}
+
+// Launchpads returns every launchpad known to the API.
+func (c *Client) Launchpads(ctx context.Context) ([]Launchpad, error) {
+	var pads []Launchpad
+	if err := c.get(ctx, "/v4/launchpads", &pads); err != nil {
+		return nil, err
+	}
+	return pads, nil
+}
+
+// Launchpad returns a single launchpad, or ErrNotFound.
+func (c *Client) Launchpad(ctx context.Context, id string) (*Launchpad, error) {
+	var pad Launchpad
+	if err := c.get(ctx, "/v4/launchpads/"+url.PathEscape(id), &pad); err != nil {
+		return nil, err
+	}
+	return &pad, nil
+}
+
+// LaunchQuery selects the launches from one launchpad whose UTC date falls
+// in [From, To).
+type LaunchQuery struct {
+	LaunchpadID string
+	From        time.Time
+	To          time.Time
+}
+
+// queryPageSize is how many launches are requested per page.
+const queryPageSize = 100
+
+// launchPage is one page of a query response.
+type launchPage struct {
+	Docs        []Launch `json:"docs"`
+	HasNextPage bool     `json:"hasNextPage"`
+	NextPage    *int     `json:"nextPage"`
+}
+
+// QueryLaunches returns the launches matching q, using the query endpoint
+// so that only matching launches are transferred. All pages are fetched.
+// Results are served from the cache while they are fresh.
+func (c *Client) QueryLaunches(ctx context.Context, q LaunchQuery) ([]Launch, error) {
+	key := fmt.Sprintf("%s|%s|%s", q.LaunchpadID, q.From.UTC().Format(time.RFC3339), q.To.UTC().Format(time.RFC3339))
+	if launches, ok := c.cache.get(key); ok {
+		return launches, nil
+	}
+
+	var launches []Launch
+	for page := 1; ; {
+		body, err := json.Marshal(map[string]any{
+			"query": map[string]any{
+				"launchpad": q.LaunchpadID,
+				"date_utc": map[string]string{
+					"$gte": q.From.UTC().Format(time.RFC3339),
+					"$lt":  q.To.UTC().Format(time.RFC3339),
+				},
+			},
+			"options": map[string]any{
+				"page":   page,
+				"limit":  queryPageSize,
+				"sort":   map[string]string{"date_utc": "asc"},
+				"select": []string{"id", "name", "launchpad", "date_utc", "date_local", "upcoming"},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var result launchPage
+		err = c.do(ctx, func(ctx context.Context) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v5/launches/query", bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			return req, nil
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+		launches = append(launches, result.Docs...)
+
+		if !result.HasNextPage || result.NextPage == nil || *result.NextPage <= page {
+			break
+		}
+		page = *result.NextPage
+	}
+
+	c.cache.put(key, launches)
+	return launches, nil
+}
+
+// get fetches path and decodes the JSON body into out.
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	return c.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	}, out)
+}
+
+// do sends the request built by newRequest through the circuit breaker,
+// retrying transient failures, and decodes the JSON body into out.
+func (c *Client) do(ctx context.Context, newRequest func(context.Context) (*http.Request, error), out any) error {
+	if !c.breaker.allow() {
+		return fmt.Errorf("%w: circuit breaker is open", ErrUnavailable)
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = c.attempt(ctx, newRequest, out)
+		if !retry || attempt >= c.retries {
+			break
+		}
+		if werr := wait(ctx, c.backoff(attempt)); werr != nil {
+			err = werr
+			break
+		}
+	}
+
+	// Only transient failures count against the API; a 404 or a bad
+	// request says nothing about its health, and neither does a caller
+	// that gave up.
+	if err != nil && ctx.Err() != nil {
+		c.breaker.release()
+		return err
+	}
+	c.breaker.record(err == nil || !errors.Is(err, ErrUnavailable))
+	return err
+}
+
+// attempt makes a single request. It reports whether a failure is worth
+// retrying; such failures wrap ErrUnavailable.
+func (c *Client) attempt(ctx context.Context, newRequest func(context.Context) (*http.Request, error), out any) (bool, error) {
+	req, err := newRequest(ctx)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, fmt.Errorf("%w: %v", ErrUnavailable, ctx.Err())
+		}
+		return true, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		io.Copy(io.Discard, resp.Body)
+		return true, fmt.Errorf("%w: %w", ErrUnavailable, &StatusError{Code: resp.StatusCode, URL: req.URL.String()})
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return false, &StatusError{Code: resp.StatusCode, URL: req.URL.String()}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("spacex: decode %s: %w", req.URL, err)
+	}
+	return false, nil
+}
+
+// backoff returns the delay before retry number attempt+1.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.minBackoff << attempt
+	if d > c.maxBackoff || d <= 0 {
+		d = c.maxBackoff
+	}
+	return d
+}
+
+// wait sleeps for d unless ctx is done first.
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrUnavailable, ctx.Err())
+	}
+}
@@ -0,0 +1,220 @@
+package spacex
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLaunchesRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v5/launches", r.URL.Path)
+		if calls.Add(1) < 3 {
+			http.Error(w, "<html>oops</html>", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"id": "launch_1", "launchpad": "pad_1", "date_local": "2049-12-25T10:00:00-05:00"},
+		})
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithBackoff(time.Millisecond, 5*time.Millisecond))
+	launches, err := c.Launches(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load(), "Expected two retries")
+	require.Len(t, launches, 1)
+
+	date, err := launches[0].LocalDate()
+	require.NoError(t, err)
+	assert.Equal(t, "2049-12-25", date.Format("2006-01-02"))
+}
+
+func TestQueryLaunchesFollowsPages(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		assert.Equal(t, "/v5/launches/query", r.URL.Path)
+
+		var body struct {
+			Options struct {
+				Page int `json:"page"`
+			} `json:"options"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch body.Options.Page {
+		case 1:
+			json.NewEncoder(w).Encode(map[string]any{
+				"docs":        []map[string]any{{"id": "launch_1"}},
+				"hasNextPage": true,
+				"nextPage":    2,
+			})
+		case 2:
+			json.NewEncoder(w).Encode(map[string]any{
+				"docs":        []map[string]any{{"id": "launch_2"}},
+				"hasNextPage": false,
+				"nextPage":    nil,
+			})
+		default:
+			t.Errorf("unexpected page %d", body.Options.Page)
+		}
+	}))
+	defer server.Close()
+
+	clk := clock.NewFake(time.Date(2049, time.December, 1, 0, 0, 0, 0, time.UTC))
+	c := New(server.URL, WithCache(time.Minute), WithClock(clk))
+	q := LaunchQuery{
+		LaunchpadID: "pad_1",
+		From:        time.Date(2049, time.December, 24, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2049, time.December, 27, 0, 0, 0, 0, time.UTC),
+	}
+
+	launches, err := c.QueryLaunches(context.Background(), q)
+	require.NoError(t, err)
+	require.Len(t, launches, 2)
+	assert.Equal(t, "launch_2", launches[1].ID)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// A repeated query is answered from the cache until it expires
+	_, err = c.QueryLaunches(context.Background(), q)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+
+	clk.Advance(time.Minute)
+	_, err = c.QueryLaunches(context.Background(), q)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestLaunchesGivesUpAfterRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetries(1), WithBackoff(time.Millisecond, time.Millisecond))
+	_, err := c.Launches(context.Background())
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.Code)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestLaunchpadDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithBackoff(time.Millisecond, time.Millisecond))
+	_, err := c.Launchpad(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestTimeoutIsUnavailable(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := New(server.URL, WithTimeout(20*time.Millisecond), WithRetries(0))
+	_, err := c.Launchpads(context.Background())
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	clk := clock.NewFake(time.Date(2049, time.December, 1, 0, 0, 0, 0, time.UTC))
+	c := New(server.URL, WithRetries(0), WithBreaker(2, time.Minute), WithClock(clk))
+	ctx := context.Background()
+
+	// Two failures in a row open the breaker
+	for i := 0; i < 2; i++ {
+		_, err := c.Launches(ctx)
+		assert.ErrorIs(t, err, ErrUnavailable)
+	}
+	_, err := c.Launches(ctx)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, int32(2), calls.Load(), "Expected the open breaker to skip the API")
+
+	// After the cooldown a successful trial closes it again
+	healthy.Store(true)
+	clk.Advance(time.Minute)
+	_, err = c.Launches(ctx)
+	require.NoError(t, err)
+	_, err = c.Launches(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestCircuitBreakerIgnoresCancelledCalls(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			// Hold the call until the caller gives up.
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	clk := clock.NewFake(time.Date(2049, time.December, 1, 0, 0, 0, 0, time.UTC))
+	c := New(server.URL, WithRetries(0), WithBreaker(1, time.Minute), WithClock(clk))
+	cancelled := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := c.Launches(ctx)
+		return err
+	}
+
+	assert.Error(t, cancelled())
+	healthy.Store(true)
+	_, err := c.Launches(context.Background())
+	require.NoError(t, err, "Expected a cancelled call to leave the breaker closed")
+	assert.Equal(t, int32(2), calls.Load())
+
+	// A cancelled trial lets the next call try again
+	c.breaker.record(false)
+	clk.Advance(time.Minute)
+	healthy.Store(false)
+	assert.Error(t, cancelled())
+	healthy.Store(true)
+	_, err = c.Launches(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), calls.Load())
+}
@@ -0,0 +1,32 @@
+package spacex
+
+import "time"
+
+// Launch is a launch as returned by the SpaceX API. Only the fields the
+// booking rules need are decoded.
+type Launch struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Launchpad string    `json:"launchpad"`
+	DateUTC   time.Time `json:"date_utc"`
+	DateLocal string    `json:"date_local"`
+	Upcoming  bool      `json:"upcoming"`
+}
+
+// LocalDate parses DateLocal, which keeps the launch site's UTC offset.
+// The calendar day of the result is the day the launchpad is occupied.
+func (l Launch) LocalDate() (time.Time, error) {
+	return time.Parse(time.RFC3339, l.DateLocal)
+}
+
+// Launchpad is a launchpad as returned by the SpaceX API.
+type Launchpad struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	FullName string   `json:"full_name"`
+	Locality string   `json:"locality"`
+	Region   string   `json:"region"`
+	Timezone string   `json:"timezone"`
+	Status   string   `json:"status"`
+	Launches []string `json:"launches"`
+}
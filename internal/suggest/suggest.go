@@ -0,0 +1,182 @@
+package suggest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/database"
+	"space-booking/internal/spacex"
+)
+
+// Store is the part of the database the finder reads the destination
+// rotation from.
+type Store interface {
+	GetDestinationIDs(ctx context.Context) ([]int64, error)
+}
+
+// Launchpads lists the launchpads that alternatives are searched on, such
+// as the SpaceX API client.
+type Launchpads interface {
+	Launchpads(ctx context.Context) ([]spacex.Launchpad, error)
+}
+
+// Flight is a launchpad and day on which a destination can be booked.
+type Flight struct {
+	LaunchpadID   string    `json:"launchpad_id"`
+	DestinationID int64     `json:"destination_id"`
+	LaunchDate    time.Time `json:"launch_date"`
+}
+
+// Query describes the flights to look for.
+type Query struct {
+	LaunchpadID   string
+	DestinationID int64
+	// From and To bound the launch days searched, both included.
+	From time.Time
+	To   time.Time
+	// Limit is how many flights to return for the launchpad, and how many
+	// alternatives on other launchpads.
+	Limit int
+}
+
+// Suggestions are the flights found for a Query.
+type Suggestions struct {
+	// Flights are the next bookable days on the requested launchpad.
+	Flights []Flight `json:"flights"`
+	// Alternatives are the earliest bookable day on each other active
+	// launchpad, soonest first.
+	Alternatives []Flight `json:"alternatives"`
+}
+
+// window is how many days of conflicts are fetched per query.
+const window = 31
+
+// Finder searches forward through the destination rotation and the
+// launchpad conflicts for days a destination can be booked.
+type Finder struct {
+	store      Store
+	conflicts  conflict.Provider
+	launchpads Launchpads
+	logger     *log.Logger
+}
+
+// New returns a Finder. Without launchpads no alternatives are suggested.
+func New(store Store, conflicts conflict.Provider, launchpads Launchpads, logger *log.Logger) *Finder {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Finder{store: store, conflicts: conflicts, launchpads: launchpads, logger: logger}
+}
+
+// Find returns the next flights on the requested launchpad and the
+// alternatives on the others. It fails when the requested launchpad cannot
+// be checked; other launchpads that cannot be checked are left out.
+func (f *Finder) Find(ctx context.Context, q Query) (*Suggestions, error) {
+	destinationIDs, err := f.store.GetDestinationIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list destinations: %w", err)
+	}
+	if !slices.Contains(destinationIDs, q.DestinationID) {
+		return nil, &UnknownDestinationError{ID: q.DestinationID}
+	}
+
+	flights, err := f.search(ctx, q.LaunchpadID, q, destinationIDs, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	alternatives, err := f.alternatives(ctx, q, destinationIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &Suggestions{Flights: flights, Alternatives: alternatives}, nil
+}
+
+// alternatives returns the earliest flight on every other active launchpad.
+func (f *Finder) alternatives(ctx context.Context, q Query, destinationIDs []int64) ([]Flight, error) {
+	alternatives := []Flight{}
+	if f.launchpads == nil {
+		return alternatives, nil
+	}
+	pads, err := f.launchpads.Launchpads(ctx)
+	if err != nil {
+		f.logger.Printf("Suggestions without alternative launchpads: %v", err)
+		return alternatives, nil
+	}
+
+	for _, pad := range pads {
+		if pad.ID == q.LaunchpadID || pad.Status != "active" {
+			continue
+		}
+		flights, err := f.search(ctx, pad.ID, q, destinationIDs, 1)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			f.logger.Printf("Suggestions skipped launchpad %s: %v", pad.ID, err)
+			continue
+		}
+		alternatives = append(alternatives, flights...)
+	}
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		return alternatives[i].LaunchDate.Before(alternatives[j].LaunchDate)
+	})
+	if len(alternatives) > q.Limit {
+		alternatives = alternatives[:q.Limit]
+	}
+	return alternatives, nil
+}
+
+// search returns up to limit days from q.From to q.To on which the
+// destination flies and the launchpad is free. Conflicts are fetched a
+// window at a time so that a near result needs a single query.
+func (f *Finder) search(ctx context.Context, launchpadID string, q Query, destinationIDs []int64, limit int) ([]Flight, error) {
+	flights := []Flight{}
+	from, to := clock.Day(q.From), clock.Day(q.To)
+	for start := from; !start.After(to) && len(flights) < limit; start = start.AddDate(0, 0, window) {
+		end := start.AddDate(0, 0, window-1)
+		if end.After(to) {
+			end = to
+		}
+
+		conflicts, err := f.conflicts.Conflicts(ctx, launchpadID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		blocked := make(map[time.Time]bool, len(conflicts))
+		for _, c := range conflicts {
+			blocked[c.Date] = true
+		}
+
+		for day := start; !day.After(end) && len(flights) < limit; day = day.AddDate(0, 0, 1) {
+			expected, err := database.ExpectedDestination(destinationIDs, day)
+			if err != nil {
+				return nil, err
+			}
+			if expected != q.DestinationID || blocked[day] {
+				continue
+			}
+			flights = append(flights, Flight{
+				LaunchpadID:   launchpadID,
+				DestinationID: q.DestinationID,
+				LaunchDate:    day,
+			})
+		}
+	}
+	return flights, nil
+}
+
+// UnknownDestinationError is returned for a destination that is not in the
+// rotation.
+type UnknownDestinationError struct {
+	ID int64
+}
+
+func (e *UnknownDestinationError) Error() string {
+	return fmt.Sprintf("destination %d does not exist", e.ID)
+}
@@ -0,0 +1,102 @@
+package suggest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"space-booking/internal/conflict"
+	"space-booking/internal/spacex"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct{}
+
+func (fakeStore) GetDestinationIDs(context.Context) ([]int64, error) {
+	return []int64{1, 2, 3, 4, 5, 6, 7}, nil
+}
+
+// fakeProvider reports fixed conflicts and records the queried ranges.
+type fakeProvider struct {
+	conflicts map[string][]conflict.Conflict
+	failing   map[string]bool
+	queries   map[string]int
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Conflicts(_ context.Context, launchpadID string, from, to time.Time) ([]conflict.Conflict, error) {
+	p.queries[launchpadID]++
+	if p.failing[launchpadID] {
+		return nil, errors.New("unreachable")
+	}
+	var out []conflict.Conflict
+	for _, c := range p.conflicts[launchpadID] {
+		if !c.Date.Before(from) && !c.Date.After(to) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+type fakeLaunchpads []spacex.Launchpad
+
+func (l fakeLaunchpads) Launchpads(context.Context) ([]spacex.Launchpad, error) {
+	return l, nil
+}
+
+func day(d int) time.Time {
+	return time.Date(2049, time.December, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestFind(t *testing.T) {
+	// December 20, 2049 is a Monday, so destination 2 flies on Tuesdays: the 21st, 28th, ...
+	provider := &fakeProvider{
+		conflicts: map[string][]conflict.Conflict{
+			"pad_a": {{Provider: "spacex", LaunchpadID: "pad_a", Date: day(21), Reason: "Starlink"}},
+			"pad_b": {{Provider: "spacex", LaunchpadID: "pad_b", Date: day(21), Reason: "Starlink"}},
+		},
+		failing: map[string]bool{"pad_d": true},
+		queries: make(map[string]int),
+	}
+	pads := fakeLaunchpads{
+		{ID: "pad_a", Status: "active"},
+		{ID: "pad_b", Status: "active"},
+		{ID: "pad_c", Status: "active"},
+		{ID: "pad_d", Status: "active"},
+		{ID: "pad_e", Status: "retired"},
+	}
+	f := New(fakeStore{}, provider, pads, nil)
+
+	got, err := f.Find(context.Background(), Query{
+		LaunchpadID:   "pad_a",
+		DestinationID: 2,
+		From:          day(20),
+		To:            day(20).AddDate(0, 1, 0),
+		Limit:         2,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []Flight{
+		{LaunchpadID: "pad_a", DestinationID: 2, LaunchDate: day(28)},
+		{LaunchpadID: "pad_a", DestinationID: 2, LaunchDate: time.Date(2050, time.January, 4, 0, 0, 0, 0, time.UTC)},
+	}, got.Flights)
+	assert.Equal(t, []Flight{
+		{LaunchpadID: "pad_c", DestinationID: 2, LaunchDate: day(21)},
+		{LaunchpadID: "pad_b", DestinationID: 2, LaunchDate: day(28)},
+	}, got.Alternatives, "Expected the retired and the failing launchpads to be left out")
+	assert.Equal(t, 0, provider.queries["pad_e"])
+	assert.Equal(t, 1, provider.queries["pad_a"], "Expected one conflict query per window")
+}
+
+func TestFindUnknownDestination(t *testing.T) {
+	f := New(fakeStore{}, &fakeProvider{queries: make(map[string]int)}, nil, nil)
+
+	_, err := f.Find(context.Background(), Query{LaunchpadID: "pad_a", DestinationID: 9, From: day(20), To: day(31), Limit: 1})
+	var uerr *UnknownDestinationError
+	require.ErrorAs(t, err, &uerr)
+	assert.Equal(t, int64(9), uerr.ID)
+}
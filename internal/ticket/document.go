@@ -0,0 +1,80 @@
+package ticket
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"space-booking/internal/models"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+// Ticket is what a ticket document shows.
+type Ticket struct {
+	Booking         models.Booking
+	DestinationName string
+	// Token is the signed token encoded in the QR code.
+	Token string
+}
+
+// NewClaims returns the claims of a booking's ticket.
+func NewClaims(booking models.Booking) Claims {
+	return Claims{
+		Code:          booking.Code,
+		Passenger:     booking.FirstName + " " + booking.LastName,
+		LaunchpadID:   booking.LaunchpadID,
+		DestinationID: booking.DestinationID,
+		LaunchDate:    booking.LaunchDate.Format("2006-01-02"),
+	}
+}
+
+// QRCode returns the token as a PNG QR code of size by size pixels.
+func QRCode(token string, size int) ([]byte, error) {
+	return qrcode.Encode(token, qrcode.Medium, size)
+}
+
+// WritePDF writes the ticket as a one page PDF.
+func WritePDF(w io.Writer, t Ticket) error {
+	qr, err := QRCode(t.Token, 512)
+	if err != nil {
+		return err
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle("Boarding pass "+t.Booking.Code, true)
+	pdf.SetCreator("SpaceTrouble", true)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 22)
+	pdf.CellFormat(0, 14, "SpaceTrouble boarding pass", "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	destination := t.DestinationName
+	if destination == "" {
+		destination = fmt.Sprintf("Destination %d", t.Booking.DestinationID)
+	}
+	rows := [][2]string{
+		{"Booking", t.Booking.Code},
+		{"Passenger", t.Booking.FirstName + " " + t.Booking.LastName},
+		{"Launchpad", t.Booking.LaunchpadID},
+		{"Destination", destination},
+		{"Launch date", t.Booking.LaunchDate.Format("Monday, January 2, 2006")},
+	}
+	for _, row := range rows {
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.CellFormat(40, 9, row[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.CellFormat(0, 9, tr(row[1]), "", 1, "L", false, 0, "")
+	}
+
+	pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	pdf.ImageOptions("qr", 10, pdf.GetY()+10, 70, 70, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	pdf.SetY(pdf.GetY() + 85)
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.MultiCell(0, 5, "Show this code at the gate. It is signed, so it can be checked without a connection.", "", "L", false)
+
+	return pdf.Output(w)
+}
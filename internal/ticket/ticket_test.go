@@ -0,0 +1,78 @@
+package ticket
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testBooking = models.Booking{
+	ID:            7,
+	Code:          "K7QX2MWP9D",
+	FirstName:     "Zoë",
+	LastName:      "User",
+	LaunchpadID:   "pad_a",
+	DestinationID: 1,
+	LaunchDate:    time.Date(2049, time.December, 20, 0, 0, 0, 0, time.UTC),
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer, err := NewSigner(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	require.NoError(t, err)
+
+	token, err := signer.Sign(NewClaims(testBooking))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "ST1."))
+
+	claims, err := Verify(signer.PublicKey(), token)
+	require.NoError(t, err)
+	assert.Equal(t, Claims{
+		Code:          "K7QX2MWP9D",
+		Passenger:     "Zoë User",
+		LaunchpadID:   "pad_a",
+		DestinationID: 1,
+		LaunchDate:    "2049-12-20",
+	}, claims)
+
+	// Any change to the payload breaks the signature
+	parts := strings.Split(token, ".")
+	forged, err := signer.Sign(Claims{Code: "K7QX2MWP9D", Passenger: "Someone Else"})
+	require.NoError(t, err)
+	parts[1] = strings.Split(forged, ".")[1]
+	_, err = Verify(signer.PublicKey(), strings.Join(parts, "."))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Tokens of another key are rejected
+	other, err := GenerateSigner()
+	require.NoError(t, err)
+	_, err = Verify(other.PublicKey(), token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = Verify(signer.PublicKey(), "not a token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestNewSignerRejectsShortKey(t *testing.T) {
+	_, err := NewSigner(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+}
+
+func TestDocuments(t *testing.T) {
+	qr, err := QRCode("ST1.payload.signature", 256)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(qr))
+	require.NoError(t, err)
+	assert.Equal(t, 256, img.Bounds().Dx())
+
+	var pdf bytes.Buffer
+	require.NoError(t, WritePDF(&pdf, Ticket{Booking: testBooking, DestinationName: "Mars", Token: "ST1.payload.signature"}))
+	assert.True(t, bytes.HasPrefix(pdf.Bytes(), []byte("%PDF-")))
+}
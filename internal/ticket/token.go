@@ -0,0 +1,96 @@
+package ticket
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// tokenPrefix starts every token and names its version.
+const tokenPrefix = "ST1"
+
+// ErrInvalidToken is returned for a token that is malformed or not signed
+// with the expected key.
+var ErrInvalidToken = errors.New("ticket: invalid token")
+
+// Claims are what a ticket token vouches for. They carry everything gate
+// staff check, so a token can be verified without reaching the API.
+type Claims struct {
+	Code          string `json:"code"`
+	Passenger     string `json:"passenger"`
+	LaunchpadID   string `json:"launchpad_id"`
+	DestinationID int64  `json:"destination_id"`
+	// LaunchDate is the launch day as YYYY-MM-DD.
+	LaunchDate string `json:"launch_date"`
+}
+
+// Signer signs ticket tokens with an Ed25519 key.
+type Signer struct {
+	key ed25519.PrivateKey
+}
+
+// NewSigner returns a Signer for the base64 encoded 32 byte seed of an
+// Ed25519 key.
+func NewSigner(seed string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil || len(raw) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ticket: signing key must be %d bytes in base64", ed25519.SeedSize)
+	}
+	return &Signer{key: ed25519.NewKeyFromSeed(raw)}, nil
+}
+
+// GenerateSigner returns a Signer with a new random key.
+func GenerateSigner() (*Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{key: key}, nil
+}
+
+// PublicKey returns the key tokens are verified with.
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign returns the token for claims: "ST1.", the base64url encoded JSON
+// claims, "." and the base64url encoded signature of everything before it.
+func (s *Signer) Sign(claims Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := tokenPrefix + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sig := ed25519.Sign(s.key, []byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Verify checks the token against the public key and returns its claims.
+func Verify(key ed25519.PublicKey, token string) (Claims, error) {
+	var claims Claims
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return claims, ErrInvalidToken
+	}
+	signed, encodedSig := token[:i], token[i+1:]
+	prefix, encodedPayload, ok := strings.Cut(signed, ".")
+	if !ok || prefix != tokenPrefix {
+		return claims, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !ed25519.Verify(key, []byte(signed), sig) {
+		return claims, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,141 @@
+// Package waitlist offers the seats that free up to the passengers waiting
+// for them.
+package waitlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"space-booking/internal/actor"
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/database"
+	"space-booking/internal/models"
+	"space-booking/internal/pricing"
+)
+
+// Store is the part of the database the promoter works on.
+type Store interface {
+	GetWaitlistFlights(ctx context.Context, from time.Time) ([]models.WaitlistFlight, error)
+	ExpireWaitlist(ctx context.Context, before time.Time) (int, error)
+	CheckDestinationSchedule(ctx context.Context, destinationID int64, launchpadID string, launchDate time.Time) (bool, error)
+	GetDestination(ctx context.Context, id int64) (*models.Destination, error)
+	PromoteWaitlist(ctx context.Context, flight models.WaitlistFlight, seats int, holdUntil time.Time, price func(booking *models.Booking, sold int) error) ([]models.WaitlistEntry, error)
+}
+
+// Promoter offers the free seats of the flights passengers are waiting
+// for, in the order they joined. A seat frees up when a booking is
+// cancelled or expires, and a blocked flight opens when its blackout or
+// SpaceX launch goes away; both are found on the next run. The promoted
+// passenger gets a pending booking at the current fare, held for them
+// until they pay for it or the hold ends, which frees the seat again.
+type Promoter struct {
+	store     Store
+	conflicts conflict.Checker
+	rules     pricing.Rules
+	hold      time.Duration
+	clock     clock.Clock
+	logger    *log.Logger
+}
+
+// New returns a Promoter offering seats priced by rules and held for hold.
+func New(store Store, conflicts conflict.Checker, rules pricing.Rules, hold time.Duration, clk clock.Clock, logger *log.Logger) *Promoter {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Promoter{store: store, conflicts: conflicts, rules: rules, hold: hold, clock: clk, logger: logger}
+}
+
+// RunOnce closes the entries of flights that launched and promotes the
+// waiting entries of the others, and returns how many it promoted. A
+// flight whose conflicts cannot be checked is skipped until the next run
+// rather than failing the others.
+func (p *Promoter) RunOnce(ctx context.Context) (int, error) {
+	ctx = actor.With(ctx, actor.System("waitlist"))
+	today := clock.Day(p.clock.Now())
+	if n, err := p.store.ExpireWaitlist(ctx, today); err != nil {
+		return 0, fmt.Errorf("expire waitlist: %w", err)
+	} else if n > 0 {
+		p.logger.Printf("Closed %d waitlist entries of flights that launched", n)
+	}
+
+	flights, err := p.store.GetWaitlistFlights(ctx, today)
+	if err != nil {
+		return 0, fmt.Errorf("list waitlisted flights: %w", err)
+	}
+
+	promoted := 0
+	for _, flight := range flights {
+		if err := ctx.Err(); err != nil {
+			return promoted, err
+		}
+		open, err := p.open(ctx, flight)
+		if err != nil {
+			p.logger.Printf("Waitlist skipped launchpad %s on %s: %v",
+				flight.LaunchpadID, flight.LaunchDate.Format(time.DateOnly), err)
+			continue
+		}
+		if !open {
+			continue
+		}
+
+		entries, err := p.promote(ctx, flight)
+		if err != nil {
+			return promoted, err
+		}
+		for _, e := range entries {
+			p.logger.Printf("Waitlist entry %d offered booking %d until %s",
+				e.ID, *e.BookingID, e.HoldUntil.Format(time.RFC3339))
+		}
+		promoted += len(entries)
+	}
+	return promoted, nil
+}
+
+// open reports whether the flight can be booked, seats aside.
+func (p *Promoter) open(ctx context.Context, flight models.WaitlistFlight) (bool, error) {
+	c, err := p.conflicts.Check(ctx, flight.LaunchpadID, flight.LaunchDate)
+	if err != nil || c != nil {
+		return false, err
+	}
+	return p.store.CheckDestinationSchedule(ctx, flight.DestinationID, flight.LaunchpadID, flight.LaunchDate)
+}
+
+// promote offers the free seats of the flight. A destination that is not
+// for sale promotes nobody.
+func (p *Promoter) promote(ctx context.Context, flight models.WaitlistFlight) ([]models.WaitlistEntry, error) {
+	destination, err := p.store.GetDestination(ctx, flight.DestinationID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get destination %d: %w", flight.DestinationID, err)
+	}
+	if destination.BasePrice <= 0 {
+		return nil, nil
+	}
+
+	now := p.clock.Now()
+	entries, err := p.store.PromoteWaitlist(ctx, flight, p.rules.Seats, now.Add(p.hold), func(b *models.Booking, sold int) error {
+		price, _, err := p.rules.Price(pricing.Input{
+			Destination: *destination,
+			LaunchDate:  b.LaunchDate,
+			Birthday:    b.Birthday,
+			SeatsSold:   sold,
+			Now:         now,
+		})
+		if err != nil {
+			return err
+		}
+		b.Price, b.Currency = price, p.rules.Currency
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("promote waitlist of launchpad %s on %s: %w",
+			flight.LaunchpadID, flight.LaunchDate.Format(time.DateOnly), err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,131 @@
+package waitlist
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"space-booking/internal/actor"
+	"space-booking/internal/clock"
+	"space-booking/internal/conflict"
+	"space-booking/internal/models"
+	"space-booking/internal/pricing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2049, time.December, 1, 9, 30, 0, 0, time.UTC)
+
+func day(d int) time.Time {
+	return time.Date(2049, time.December, d, 0, 0, 0, 0, time.UTC)
+}
+
+// fakeStore serves fixed flights, each with some seats sold and entries
+// waiting, and keeps the bookings it promoted them to.
+type fakeStore struct {
+	flights  []models.WaitlistFlight
+	sold     map[string]int
+	waiting  map[string][]models.WaitlistEntry
+	expired  time.Time
+	bookings map[int]*models.Booking
+	actors   map[string]bool
+}
+
+func (s *fakeStore) GetWaitlistFlights(context.Context, time.Time) ([]models.WaitlistFlight, error) {
+	return s.flights, nil
+}
+
+func (s *fakeStore) ExpireWaitlist(_ context.Context, before time.Time) (int, error) {
+	s.expired = before
+	return 0, nil
+}
+
+func (s *fakeStore) CheckDestinationSchedule(_ context.Context, destinationID int64, _ string, _ time.Time) (bool, error) {
+	return destinationID == 1, nil
+}
+
+func (s *fakeStore) GetDestination(_ context.Context, id int64) (*models.Destination, error) {
+	return &models.Destination{ID: id, Name: "Mars", BasePrice: 100000000}, nil
+}
+
+func (s *fakeStore) PromoteWaitlist(ctx context.Context, flight models.WaitlistFlight, seats int, holdUntil time.Time, price func(*models.Booking, int) error) ([]models.WaitlistEntry, error) {
+	s.actors[actor.From(ctx).ID] = true
+	sold := s.sold[flight.LaunchpadID]
+	var promoted []models.WaitlistEntry
+	for _, e := range s.waiting[flight.LaunchpadID] {
+		if sold >= seats {
+			break
+		}
+		b := e.Booking(holdUntil)
+		if err := price(b, sold); err != nil {
+			return nil, err
+		}
+		b.ID = 100 + e.ID
+		s.bookings[e.ID] = b
+		e.Status, e.BookingID, e.HoldUntil = models.WaitlistPromoted, &b.ID, b.HoldUntil
+		promoted = append(promoted, e)
+		sold++
+	}
+	return promoted, nil
+}
+
+// fakeChecker blocks launchpads and fails to check others.
+type fakeChecker struct {
+	blocked map[string]bool
+	failing map[string]bool
+}
+
+func (c *fakeChecker) Check(_ context.Context, launchpadID string, date time.Time) (*conflict.Conflict, error) {
+	if c.failing[launchpadID] {
+		return nil, errors.New("unreachable")
+	}
+	if c.blocked[launchpadID] {
+		return &conflict.Conflict{LaunchpadID: launchpadID, Date: date, Reason: "Starlink", Provider: "spacex"}, nil
+	}
+	return nil, nil
+}
+
+func TestRunOnce(t *testing.T) {
+	entry := func(id int, pad string) models.WaitlistEntry {
+		return models.WaitlistEntry{ID: id, FirstName: "Test", LaunchpadID: pad, DestinationID: 1, LaunchDate: day(25),
+			Birthday: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Status: models.WaitlistWaiting}
+	}
+	store := &fakeStore{
+		flights: []models.WaitlistFlight{
+			{LaunchpadID: "pad_a", DestinationID: 1, LaunchDate: day(25)}, // one seat left
+			{LaunchpadID: "pad_b", DestinationID: 1, LaunchDate: day(25)}, // SpaceX launch
+			{LaunchpadID: "pad_c", DestinationID: 1, LaunchDate: day(25)}, // provider down
+			{LaunchpadID: "pad_d", DestinationID: 2, LaunchDate: day(25)}, // not flown that day
+		},
+		sold: map[string]int{"pad_a": 3},
+		waiting: map[string][]models.WaitlistEntry{
+			"pad_a": {entry(1, "pad_a"), entry(2, "pad_a")},
+			"pad_b": {entry(3, "pad_b")},
+			"pad_c": {entry(4, "pad_c")},
+			"pad_d": {entry(5, "pad_d")},
+		},
+		bookings: make(map[int]*models.Booking),
+		actors:   make(map[string]bool),
+	}
+	checker := &fakeChecker{blocked: map[string]bool{"pad_b": true}, failing: map[string]bool{"pad_c": true}}
+	rules := pricing.Rules{Currency: "USD", Seats: 4, Advance: []pricing.Tier{{From: 0, Factor: 1}},
+		AgeBands: []pricing.Tier{{From: 0, Factor: 1}}}
+	p := New(store, checker, rules, 24*time.Hour, clock.NewFake(now), nil)
+
+	n, err := p.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, day(1), store.expired, "Expected the entries of past flights to be closed")
+
+	require.Contains(t, store.bookings, 1, "Expected the first entry to get the last seat")
+	b := store.bookings[1]
+	assert.Equal(t, models.StatusPending, b.Status)
+	assert.Equal(t, now.Add(24*time.Hour), *b.HoldUntil)
+	assert.Equal(t, int64(100000000), b.Price)
+	assert.Equal(t, "USD", b.Currency)
+	assert.NotContains(t, store.bookings, 2, "Expected the second entry to keep waiting")
+	assert.Len(t, store.bookings, 1, "Expected blocked flights to promote nobody")
+	assert.Equal(t, map[string]bool{"system:waitlist": true}, store.actors)
+}
@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+)
+
+// Store is the part of the database the dispatcher works on.
+type Store interface {
+	FanOutOutbox(ctx context.Context, limit int) (int, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+// Envelope is the JSON body of a delivery.
+type Envelope struct {
+	// ID identifies the message; it is the same for every attempt and
+	// every subscription, so receivers can drop duplicates.
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher publishes the outbox to the webhook subscriptions. Every run
+// fans new outbox messages out to deliveries and attempts the deliveries
+// that are due. Failed attempts are retried with exponential backoff until
+// the attempts run out.
+type Dispatcher struct {
+	store       Store
+	client      *http.Client
+	clock       clock.Clock
+	logger      *log.Logger
+	batch       int
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// Option configures a Dispatcher built by New.
+type Option func(*Dispatcher)
+
+// WithHTTPClient sets the client deliveries are sent with. Its Timeout
+// bounds every attempt.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) { d.client = client }
+}
+
+// WithMaxAttempts sets how many attempts a delivery gets before it fails.
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) { d.maxAttempts = n }
+}
+
+// WithBackoff sets the delay before the second attempt and the cap it
+// doubles up to.
+func WithBackoff(min, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.minBackoff = min
+		d.maxBackoff = max
+	}
+}
+
+// New returns a Dispatcher.
+func New(store Store, clk clock.Clock, logger *log.Logger, opts ...Option) *Dispatcher {
+	if logger == nil {
+		logger = log.Default()
+	}
+	d := &Dispatcher{
+		store:       store,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		clock:       clk,
+		logger:      logger,
+		batch:       20,
+		maxAttempts: 8,
+		minBackoff:  30 * time.Second,
+		maxBackoff:  time.Hour,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// RunOnce publishes the new outbox messages and attempts one batch of due
+// deliveries, concurrently. It returns how many were delivered.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	if _, err := d.store.FanOutOutbox(ctx, d.batch*5); err != nil {
+		return 0, fmt.Errorf("fan out outbox: %w", err)
+	}
+
+	// The batch is attempted concurrently, so it takes about one timeout;
+	// the lease keeps other replicas off it for twice that.
+	lease := 2 * d.client.Timeout
+	if lease <= 0 {
+		lease = time.Minute
+	}
+	due, err := d.store.ClaimWebhookDeliveries(ctx, d.batch, lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+		firstErr  error
+	)
+	for _, job := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := d.deliver(ctx, job)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				delivered++
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}()
+	}
+	wg.Wait()
+	return delivered, firstErr
+}
+
+// deliver makes one attempt and records its outcome. It reports whether
+// the receiver accepted the delivery; the error is about recording it.
+func (d *Dispatcher) deliver(ctx context.Context, job models.DueDelivery) (bool, error) {
+	delivery := job.Delivery
+	code, err := d.send(ctx, job)
+	now := d.clock.Now()
+
+	delivery.Attempts++
+	delivery.LastStatusCode = nil
+	delivery.LastError = ""
+	if code != 0 {
+		delivery.LastStatusCode = &code
+	}
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+		d.logger.Printf("Webhook delivery %d to %s failed for good: %v", delivery.ID, job.URL, err)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	if err := d.store.RecordWebhookAttempt(ctx, &delivery); err != nil {
+		return false, fmt.Errorf("record delivery %d: %w", delivery.ID, err)
+	}
+	return delivery.Status == models.DeliveryDelivered, nil
+}
+
+// send posts the signed message and returns the response status code, if
+// any, and an error unless the receiver answered with a 2xx status.
+func (d *Dispatcher) send(ctx context.Context, job models.DueDelivery) (int, error) {
+	id := strconv.FormatInt(job.Message.ID, 10)
+	body, err := json.Marshal(Envelope{
+		ID:        id,
+		Type:      job.Message.Type,
+		CreatedAt: job.Message.CreatedAt,
+		Data:      job.Message.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(d.clock.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "space-booking-webhooks")
+	req.Header.Set(HeaderID, id)
+	req.Header.Set(HeaderEvent, job.Message.Type)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(job.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.minBackoff << (attempts - 1)
+	if delay > d.maxBackoff || delay <= 0 {
+		delay = d.maxBackoff
+	}
+	return delay
+}
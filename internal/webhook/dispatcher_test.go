@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"space-booking/internal/clock"
+	"space-booking/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore serves fixed deliveries and keeps the recorded attempts.
+type fakeStore struct {
+	mu       sync.Mutex
+	due      []models.DueDelivery
+	recorded map[int64]models.WebhookDelivery
+}
+
+func (s *fakeStore) FanOutOutbox(context.Context, int) (int, error) {
+	return 0, nil
+}
+
+func (s *fakeStore) ClaimWebhookDeliveries(context.Context, int, time.Duration) ([]models.DueDelivery, error) {
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *fakeStore) RecordWebhookAttempt(_ context.Context, delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recorded[delivery.ID] = *delivery
+	return nil
+}
+
+func dueDelivery(id int64, url string, attempts int) models.DueDelivery {
+	return models.DueDelivery{
+		Delivery: models.WebhookDelivery{ID: id, OutboxID: 10 + id, EventType: "booking.cancelled", Status: models.DeliveryPending, Attempts: attempts},
+		URL:      url,
+		Secret:   "whsec_test",
+		Message: models.OutboxMessage{
+			ID:        10 + id,
+			Type:      "booking.cancelled",
+			BookingID: 7,
+			Payload:   json.RawMessage(`{"id":7,"status":"cancelled"}`),
+			CreatedAt: time.Date(2049, time.December, 1, 9, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	var received []Envelope
+	var mu sync.Mutex
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		if !Verify("whsec_test", r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature)) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/down" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		var e Envelope
+		assert.NoError(t, json.Unmarshal(body, &e))
+		mu.Lock()
+		received = append(received, e)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	now := time.Date(2049, time.December, 1, 9, 30, 0, 0, time.UTC)
+	store := &fakeStore{
+		due: []models.DueDelivery{
+			dueDelivery(1, receiver.URL+"/hooks", 0),
+			dueDelivery(2, receiver.URL+"/down", 1),
+			dueDelivery(3, receiver.URL+"/down", 2),
+		},
+		recorded: make(map[int64]models.WebhookDelivery),
+	}
+	d := New(store, clock.NewFake(now), nil, WithMaxAttempts(3), WithBackoff(time.Minute, time.Hour))
+
+	n, err := d.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Len(t, received, 1)
+	assert.Equal(t, "11", received[0].ID)
+	assert.Equal(t, "booking.cancelled", received[0].Type)
+	assert.JSONEq(t, `{"id":7,"status":"cancelled"}`, string(received[0].Data))
+
+	delivered := store.recorded[1]
+	assert.Equal(t, models.DeliveryDelivered, delivered.Status)
+	assert.Equal(t, 1, delivered.Attempts)
+	assert.Equal(t, http.StatusNoContent, *delivered.LastStatusCode)
+
+	retried := store.recorded[2]
+	assert.Equal(t, models.DeliveryPending, retried.Status)
+	assert.Equal(t, 2, retried.Attempts)
+	assert.Equal(t, now.Add(2*time.Minute), retried.NextAttemptAt, "Expected the backoff to double")
+	assert.Contains(t, retried.LastError, "503")
+
+	failed := store.recorded[3]
+	assert.Equal(t, models.DeliveryFailed, failed.Status, "Expected the last attempt to fail the delivery")
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	signature := Sign("secret", "2524608000", body)
+
+	assert.True(t, Verify("secret", "2524608000", body, signature))
+	assert.False(t, Verify("other", "2524608000", body, signature))
+	assert.False(t, Verify("secret", "2524608001", body, signature), "Expected the timestamp to be signed")
+	assert.False(t, Verify("secret", "2524608000", []byte(`{"id":"2"}`), signature))
+}
@@ -0,0 +1,3 @@
+-- Drop the booking creation timestamp
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,3 @@
+-- Record when each booking was made
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
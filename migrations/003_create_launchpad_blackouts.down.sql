@@ -0,0 +1,2 @@
+-- Drop the launchpad blackouts table
+DROP TABLE IF EXISTS launchpad_blackouts;
@@ -0,0 +1,12 @@
+-- Create the launchpad blackouts table for manual closures
+CREATE TABLE IF NOT EXISTS launchpad_blackouts (
+    id SERIAL PRIMARY KEY,
+    launchpad_id VARCHAR(50) NOT NULL,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    reason TEXT NOT NULL,
+    CHECK (ends_on >= starts_on)
+);
+
+CREATE INDEX IF NOT EXISTS launchpad_blackouts_launchpad_idx
+    ON launchpad_blackouts (launchpad_id, starts_on, ends_on);
@@ -0,0 +1,6 @@
+-- Drop the booking disruption columns
+DROP INDEX IF EXISTS bookings_launch_date_idx;
+
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS disruption_reason,
+    DROP COLUMN IF EXISTS disrupted_at;
@@ -0,0 +1,7 @@
+-- Record bookings that can no longer fly as booked, and why
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS disrupted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS disruption_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS bookings_launch_date_idx
+    ON bookings (launch_date);
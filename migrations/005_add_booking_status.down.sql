@@ -0,0 +1,9 @@
+-- Drop the booking status columns
+DROP INDEX IF EXISTS bookings_status_idx;
+
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS flown_at,
+    DROP COLUMN IF EXISTS rebooked_at,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS confirmed_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,14 @@
+-- Track each booking through its lifecycle
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'confirmed'
+        CHECK (status IN ('pending', 'confirmed', 'cancelled', 'disrupted', 'rebooked', 'flown')),
+    ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS rebooked_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS flown_at TIMESTAMPTZ;
+
+-- Existing bookings were confirmed when they were made
+UPDATE bookings SET confirmed_at = created_at WHERE confirmed_at IS NULL;
+UPDATE bookings SET status = 'disrupted' WHERE disrupted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS bookings_status_idx ON bookings (status);
@@ -0,0 +1,5 @@
+-- Drop the rebooking link
+DROP INDEX IF EXISTS bookings_rebooked_from_idx;
+
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS rebooked_from;
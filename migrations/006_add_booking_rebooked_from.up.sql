@@ -0,0 +1,5 @@
+-- Link a rebooking to the booking it replaces
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS rebooked_from INTEGER REFERENCES bookings (id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS bookings_rebooked_from_idx ON bookings (rebooked_from);
@@ -0,0 +1,3 @@
+-- Drop the booking audit log
+DROP TABLE IF EXISTS booking_events;
+DROP FUNCTION IF EXISTS booking_events_append_only();
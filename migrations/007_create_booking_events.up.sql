@@ -0,0 +1,25 @@
+-- Append-only audit log of booking changes
+CREATE TABLE IF NOT EXISTS booking_events (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL REFERENCES bookings (id),
+    type VARCHAR(30) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255),
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS booking_events_booking_idx ON booking_events (booking_id, id);
+
+-- Reject any attempt to rewrite history
+CREATE OR REPLACE FUNCTION booking_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'booking_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS booking_events_append_only ON booking_events;
+CREATE TRIGGER booking_events_append_only
+    BEFORE UPDATE OR DELETE ON booking_events
+    FOR EACH ROW EXECUTE FUNCTION booking_events_append_only();
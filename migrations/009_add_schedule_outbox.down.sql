@@ -0,0 +1,8 @@
+-- Drop the schedule changes from the outbox
+DELETE FROM webhook_deliveries
+WHERE outbox_id IN (SELECT id FROM outbox WHERE booking_id IS NULL);
+
+DELETE FROM outbox WHERE booking_id IS NULL;
+
+ALTER TABLE outbox
+    ALTER COLUMN booking_id SET NOT NULL;
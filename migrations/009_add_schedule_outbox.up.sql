@@ -0,0 +1,3 @@
+-- Let the outbox carry schedule changes, which are about no single booking
+ALTER TABLE outbox
+    ALTER COLUMN booking_id DROP NOT NULL;
@@ -0,0 +1,5 @@
+-- Drop the notification queue and the contact address
+DROP TABLE IF EXISTS notification_jobs;
+
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS email;
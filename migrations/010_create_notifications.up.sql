@@ -0,0 +1,21 @@
+-- Contact address of the passenger, required for new bookings
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS email VARCHAR(254);
+
+-- Emails waiting to be sent, queued in the same transaction as the booking
+-- change they are about
+CREATE TABLE IF NOT EXISTS notification_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL REFERENCES bookings (id),
+    kind VARCHAR(50) NOT NULL,
+    recipient VARCHAR(254) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    last_error TEXT,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS notification_jobs_due_idx ON notification_jobs (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,5 @@
+-- Drop the public booking reference
+DROP INDEX IF EXISTS bookings_code_idx;
+
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS code;
@@ -0,0 +1,12 @@
+-- Public, non-guessable reference of a booking
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS code VARCHAR(16);
+
+UPDATE bookings
+SET code = upper(substr(md5(random()::text || clock_timestamp()::text || id::text), 1, 10))
+WHERE code IS NULL;
+
+ALTER TABLE bookings
+    ALTER COLUMN code SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS bookings_code_idx ON bookings (code);
@@ -0,0 +1,9 @@
+-- Drop check-in and boarding; checked in and boarded bookings stay confirmed
+UPDATE bookings SET status = 'confirmed' WHERE status IN ('checked_in', 'boarded');
+
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS boarded_at,
+    DROP COLUMN IF EXISTS checked_in_at,
+    DROP CONSTRAINT IF EXISTS bookings_status_check,
+    ADD CONSTRAINT bookings_status_check
+        CHECK (status IN ('pending', 'confirmed', 'cancelled', 'disrupted', 'rebooked', 'flown'));
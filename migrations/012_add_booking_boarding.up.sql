@@ -0,0 +1,7 @@
+-- Check-in and boarding at the gate
+ALTER TABLE bookings
+    DROP CONSTRAINT IF EXISTS bookings_status_check,
+    ADD CONSTRAINT bookings_status_check
+        CHECK (status IN ('pending', 'confirmed', 'checked_in', 'boarded', 'cancelled', 'disrupted', 'rebooked', 'flown')),
+    ADD COLUMN IF NOT EXISTS checked_in_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS boarded_at TIMESTAMPTZ;
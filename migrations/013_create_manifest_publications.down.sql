@@ -0,0 +1,2 @@
+-- Drop the manifest publications
+DROP TABLE IF EXISTS manifest_publications;
@@ -0,0 +1,7 @@
+-- Flights whose manifest was handed to range control
+CREATE TABLE IF NOT EXISTS manifest_publications (
+    launchpad_id VARCHAR(50) NOT NULL,
+    launch_date DATE NOT NULL,
+    published_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (launchpad_id, launch_date)
+);
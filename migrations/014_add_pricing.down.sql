@@ -0,0 +1,7 @@
+-- Drop the fares
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS price_cents,
+    DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE destinations
+    DROP COLUMN IF EXISTS base_price_cents;
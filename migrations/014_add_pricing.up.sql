@@ -0,0 +1,19 @@
+-- Fares: a base price per destination and the price paid per booking, in
+-- cents
+ALTER TABLE destinations
+    ADD COLUMN IF NOT EXISTS base_price_cents BIGINT NOT NULL DEFAULT 0 CHECK (base_price_cents >= 0);
+
+UPDATE destinations SET base_price_cents = CASE name
+    WHEN 'Moon' THEN 25000000
+    WHEN 'Mars' THEN 120000000
+    WHEN 'Asteroid Belt' THEN 180000000
+    WHEN 'Europa' THEN 240000000
+    WHEN 'Ganymede' THEN 250000000
+    WHEN 'Titan' THEN 320000000
+    WHEN 'Pluto' THEN 450000000
+    ELSE base_price_cents
+END;
+
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS price_cents BIGINT CHECK (price_cents >= 0),
+    ADD COLUMN IF NOT EXISTS currency CHAR(3);
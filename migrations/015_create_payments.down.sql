@@ -0,0 +1,13 @@
+-- Drop the payments; expired bookings stay cancelled
+DROP TABLE IF EXISTS refunds;
+DROP TABLE IF EXISTS payments;
+
+DROP INDEX IF EXISTS bookings_pending_idx;
+
+UPDATE bookings SET status = 'cancelled', cancelled_at = expired_at WHERE status = 'expired';
+
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS expired_at,
+    DROP CONSTRAINT IF EXISTS bookings_status_check,
+    ADD CONSTRAINT bookings_status_check
+        CHECK (status IN ('pending', 'confirmed', 'checked_in', 'boarded', 'cancelled', 'disrupted', 'rebooked', 'flown'));
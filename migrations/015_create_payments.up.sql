@@ -0,0 +1,47 @@
+-- Bookings wait in pending for their payment and expire when it does not
+-- come in time
+ALTER TABLE bookings
+    DROP CONSTRAINT IF EXISTS bookings_status_check,
+    ADD CONSTRAINT bookings_status_check
+        CHECK (status IN ('pending', 'confirmed', 'checked_in', 'boarded', 'cancelled', 'disrupted', 'rebooked', 'flown', 'expired')),
+    ADD COLUMN IF NOT EXISTS expired_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS bookings_pending_idx ON bookings (created_at) WHERE status = 'pending';
+
+-- Payments of the fares, one per attempt to pay a booking
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL REFERENCES bookings (id),
+    provider VARCHAR(50) NOT NULL,
+    provider_ref VARCHAR(255),
+    amount_cents BIGINT NOT NULL CHECK (amount_cents > 0),
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'captured', 'failed')),
+    failure_reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    captured_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS payments_booking_idx ON payments (booking_id);
+
+-- Refunds waiting to be, or already, issued through the provider, queued in
+-- the same transaction as the cancellation they are for
+CREATE TABLE IF NOT EXISTS refunds (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id BIGINT NOT NULL REFERENCES payments (id),
+    amount_cents BIGINT NOT NULL CHECK (amount_cents > 0),
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'succeeded', 'failed')),
+    provider_ref VARCHAR(255),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    last_error TEXT,
+    refunded_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS refunds_payment_idx ON refunds (payment_id);
+CREATE INDEX IF NOT EXISTS refunds_due_idx ON refunds (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,8 @@
+-- Drop the disruption sources
+DROP INDEX IF EXISTS refunds_disrupted_by_idx;
+
+ALTER TABLE refunds
+    DROP COLUMN IF EXISTS disrupted_by;
+
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS disrupted_by;
@@ -0,0 +1,9 @@
+-- Cancellation refunds: the source of a booking's disruption, e.g. spacex,
+-- and the refunds owed to one
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS disrupted_by TEXT;
+
+ALTER TABLE refunds
+    ADD COLUMN IF NOT EXISTS disrupted_by TEXT;
+
+CREATE INDEX IF NOT EXISTS refunds_disrupted_by_idx ON refunds (disrupted_by) WHERE disrupted_by IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Drop the promo codes
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS promo_code,
+    DROP COLUMN IF EXISTS discount_cents;
+
+DROP TABLE IF EXISTS promo_redemptions;
+DROP TABLE IF EXISTS promo_codes;
@@ -0,0 +1,38 @@
+-- Promo codes: discounts on fares, and the bookings they were redeemed for
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    value BIGINT NOT NULL CHECK (value > 0),
+    currency CHAR(3),
+    valid_from TIMESTAMPTZ,
+    valid_until TIMESTAMPTZ,
+    destination_ids BIGINT[] NOT NULL DEFAULT '{}',
+    max_redemptions INTEGER NOT NULL DEFAULT 0 CHECK (max_redemptions >= 0),
+    redemptions INTEGER NOT NULL DEFAULT 0 CHECK (redemptions >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL,
+    CHECK (kind <> 'percent' OR value <= 100),
+    CHECK (kind <> 'fixed' OR currency IS NOT NULL)
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    promo_code_id INTEGER NOT NULL REFERENCES promo_codes (id),
+    booking_id INTEGER NOT NULL REFERENCES bookings (id),
+    -- The passenger's name in lower case and birthday, see models.PassengerKey
+    passenger TEXT NOT NULL,
+    discount_cents BIGINT NOT NULL CHECK (discount_cents >= 0),
+    currency CHAR(3) NOT NULL,
+    redeemed_at TIMESTAMPTZ NOT NULL,
+    -- Set when the booking expired or was cancelled before it was paid
+    released_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS promo_redemptions_passenger_idx
+    ON promo_redemptions (promo_code_id, passenger) WHERE released_at IS NULL;
+CREATE INDEX IF NOT EXISTS promo_redemptions_booking_idx ON promo_redemptions (booking_id);
+
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS promo_code VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS discount_cents BIGINT CHECK (discount_cents >= 0);
@@ -0,0 +1,4 @@
+-- Drop the waitlist
+ALTER TABLE bookings DROP COLUMN IF EXISTS hold_until;
+
+DROP TABLE IF EXISTS waitlist_entries;
@@ -0,0 +1,30 @@
+-- Passengers waiting for a seat on a sold out or blocked flight, in the
+-- order they joined
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id SERIAL PRIMARY KEY,
+    first_name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    gender VARCHAR(10),
+    birthday DATE NOT NULL,
+    email VARCHAR(254) NOT NULL,
+    launchpad_id VARCHAR(50) NOT NULL,
+    destination_id INTEGER NOT NULL REFERENCES destinations (id),
+    launch_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'promoted', 'left', 'expired')),
+    -- The pending booking holding the seat offered to the passenger
+    booking_id INTEGER REFERENCES bookings (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    promoted_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS waitlist_entries_waiting_idx
+    ON waitlist_entries (launchpad_id, launch_date, id) WHERE status = 'waiting';
+CREATE UNIQUE INDEX IF NOT EXISTS waitlist_entries_passenger_idx
+    ON waitlist_entries (launchpad_id, launch_date, lower(first_name), lower(last_name), birthday)
+    WHERE status = 'waiting';
+
+-- A seat offered from the waitlist is held until hold_until rather than for
+-- the payment TTL
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS hold_until TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- Drop the seat holds
+ALTER TABLE bookings DROP COLUMN IF EXISTS hold_id;
+
+DROP TABLE IF EXISTS seat_holds;
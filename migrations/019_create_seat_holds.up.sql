@@ -0,0 +1,20 @@
+-- Seats reserved on a flight while a customer checks out
+CREATE TABLE IF NOT EXISTS seat_holds (
+    id SERIAL PRIMARY KEY,
+    launchpad_id VARCHAR(50) NOT NULL,
+    destination_id INTEGER NOT NULL REFERENCES destinations (id),
+    launch_date DATE NOT NULL,
+    seats INTEGER NOT NULL CHECK (seats > 0),
+    booked INTEGER NOT NULL DEFAULT 0 CHECK (booked >= 0 AND booked <= seats),
+    status VARCHAR(20) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'converted', 'released', 'expired')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS seat_holds_active_idx
+    ON seat_holds (launchpad_id, launch_date) WHERE status = 'active';
+
+-- The hold a booking took its seat from
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS hold_id INTEGER REFERENCES seat_holds (id);
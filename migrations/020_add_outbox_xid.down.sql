@@ -0,0 +1,4 @@
+-- Drop the transaction of the outbox messages
+DROP INDEX IF EXISTS outbox_position_idx;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS xid;
@@ -0,0 +1,8 @@
+-- Record the transaction that wrote each outbox message. IDs are taken
+-- before commit, so a stream resuming by ID alone skips the messages of a
+-- transaction that commits after a later one; streams follow the
+-- transactions instead, see GetOutboxMessages.
+ALTER TABLE outbox
+    ADD COLUMN IF NOT EXISTS xid XID8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX IF NOT EXISTS outbox_position_idx ON outbox (xid, id);
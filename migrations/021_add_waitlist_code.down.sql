@@ -0,0 +1,5 @@
+-- Drop the public waitlist entry reference
+DROP INDEX IF EXISTS waitlist_entries_code_idx;
+
+ALTER TABLE waitlist_entries
+    DROP COLUMN IF EXISTS code;
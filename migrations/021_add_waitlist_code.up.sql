@@ -0,0 +1,12 @@
+-- Public, non-guessable reference of a waitlist entry
+ALTER TABLE waitlist_entries
+    ADD COLUMN IF NOT EXISTS code VARCHAR(16);
+
+UPDATE waitlist_entries
+SET code = upper(substr(md5(random()::text || clock_timestamp()::text || id::text), 1, 10))
+WHERE code IS NULL;
+
+ALTER TABLE waitlist_entries
+    ALTER COLUMN code SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS waitlist_entries_code_idx ON waitlist_entries (code);
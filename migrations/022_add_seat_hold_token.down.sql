@@ -0,0 +1,5 @@
+-- Drop the secret of the seat holds
+DROP INDEX IF EXISTS seat_holds_token_idx;
+
+ALTER TABLE seat_holds
+    DROP COLUMN IF EXISTS token;
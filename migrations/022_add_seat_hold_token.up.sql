@@ -0,0 +1,12 @@
+-- Secret of a seat hold, which its customer needs to read, release or book it
+ALTER TABLE seat_holds
+    ADD COLUMN IF NOT EXISTS token VARCHAR(16);
+
+UPDATE seat_holds
+SET token = upper(substr(md5(random()::text || clock_timestamp()::text || id::text), 1, 10))
+WHERE token IS NULL;
+
+ALTER TABLE seat_holds
+    ALTER COLUMN token SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS seat_holds_token_idx ON seat_holds (token);
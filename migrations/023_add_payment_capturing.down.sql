@@ -0,0 +1,13 @@
+-- Drop the capturing payments' retries; their captures are given up
+DROP INDEX IF EXISTS payments_capturing_idx;
+
+UPDATE payments
+SET status = 'failed', failure_reason = 'Capture was not recorded.'
+WHERE status = 'capturing';
+
+ALTER TABLE payments
+    DROP CONSTRAINT IF EXISTS payments_status_check,
+    ADD CONSTRAINT payments_status_check
+        CHECK (status IN ('pending', 'captured', 'failed')),
+    DROP COLUMN IF EXISTS capture_attempts,
+    DROP COLUMN IF EXISTS next_capture_at;
@@ -0,0 +1,11 @@
+-- A payment is capturing from before its capture is sent to the provider
+-- until the outcome is recorded; the capture job retries the ones whose
+-- outcome was lost
+ALTER TABLE payments
+    DROP CONSTRAINT IF EXISTS payments_status_check,
+    ADD CONSTRAINT payments_status_check
+        CHECK (status IN ('pending', 'capturing', 'captured', 'failed')),
+    ADD COLUMN IF NOT EXISTS capture_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS next_capture_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS payments_capturing_idx ON payments (next_capture_at) WHERE status = 'capturing';